@@ -0,0 +1,149 @@
+package client
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balancer 客户端负载均衡接口，instances 非空
+type Balancer interface {
+	Select(instances []*Instance) *Instance
+}
+
+// RoundRobinBalancer 轮询负载均衡器
+type RoundRobinBalancer struct {
+	next uint64
+}
+
+func NewRoundRobinBalancer() *RoundRobinBalancer {
+	return &RoundRobinBalancer{}
+}
+
+func (b *RoundRobinBalancer) Select(instances []*Instance) *Instance {
+	n := atomic.AddUint64(&b.next, 1)
+	return instances[(n-1)%uint64(len(instances))]
+}
+
+// WeightedRandomBalancer 按实例权重随机选择，未设置权重的实例按 1 计算
+type WeightedRandomBalancer struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func NewWeightedRandomBalancer() *WeightedRandomBalancer {
+	return &WeightedRandomBalancer{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (b *WeightedRandomBalancer) Select(instances []*Instance) *Instance {
+	total := 0
+	for _, instance := range instances {
+		total += instanceWeight(instance)
+	}
+
+	b.mutex.Lock()
+	n := b.rand.Intn(total)
+	b.mutex.Unlock()
+
+	for _, instance := range instances {
+		if n -= instanceWeight(instance); n < 0 {
+			return instance
+		}
+	}
+	return instances[len(instances)-1]
+}
+
+func instanceWeight(instance *Instance) int {
+	if instance.Weight <= 0 {
+		return 1
+	}
+	return instance.Weight
+}
+
+// P2CBalancer 两次随机选择（power of two choices）负载均衡器。
+// 随机挑选两个实例，选择负载代价较低的一个，代价为 EWMA 耗时 ×（正在处理的请求数 + 1）÷ 权重。
+// 负载由 Transport 在客户端观察得到，没有请求记录的实例按其他实例的平均耗时计算
+type P2CBalancer struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+	loads map[string]*loadStats // 实例地址 -> 负载
+}
+
+func NewP2CBalancer() *P2CBalancer {
+	return &P2CBalancer{
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		loads: make(map[string]*loadStats),
+	}
+}
+
+func (b *P2CBalancer) Select(instances []*Instance) *Instance {
+	if len(instances) == 1 {
+		return instances[0]
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	i := b.rand.Intn(len(instances))
+	j := b.rand.Intn(len(instances) - 1)
+	if j >= i {
+		j++
+	}
+
+	total, count := 0.0, 0
+	for _, instance := range instances {
+		if load, ok := b.loads[instance.Address()]; ok && load.latency > 0 {
+			total += load.latency
+			count++
+		}
+	}
+	defaultLatency := 1.0
+	if count > 0 {
+		defaultLatency = total / float64(count)
+	}
+
+	a, c := instances[i], instances[j]
+	if b.costLocked(c, defaultLatency) < b.costLocked(a, defaultLatency) {
+		return c
+	}
+	return a
+}
+
+func (b *P2CBalancer) costLocked(instance *Instance, defaultLatency float64) float64 {
+	latency, inFlight := defaultLatency, 0
+	if load, ok := b.loads[instance.Address()]; ok {
+		inFlight = load.inFlight
+		if load.latency > 0 {
+			latency = load.latency
+		}
+	}
+	return latency * float64(inFlight+1) / float64(instanceWeight(instance))
+}
+
+// Begin 实现 LoadObserver，记录发往实例的请求
+func (b *P2CBalancer) Begin(instance *Instance) func() {
+	address := instance.Address()
+	start := time.Now()
+
+	b.mutex.Lock()
+	load, ok := b.loads[address]
+	if !ok {
+		load = &loadStats{}
+		b.loads[address] = load
+	}
+	load.inFlight++
+	b.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			load.inFlight--
+			load.observe(time.Since(start))
+			b.mutex.Unlock()
+		})
+	}
+}
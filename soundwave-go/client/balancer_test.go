@@ -0,0 +1,114 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func testInstances(weights ...int) []*Instance {
+	instances := make([]*Instance, 0, len(weights))
+	for i, weight := range weights {
+		instances = append(instances, &Instance{
+			Name:   "api",
+			ID:     fmt.Sprintf("api-%d", i),
+			IP:     "10.0.0.1",
+			Port:   8000 + i,
+			Weight: weight,
+		})
+	}
+	return instances
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	instances := testInstances(1, 1, 1)
+	b := NewRoundRobinBalancer()
+	for i := 0; i < 7; i++ {
+		if got, want := b.Select(instances), instances[i%len(instances)]; got != want {
+			t.Fatalf("第 %d 次选中 %s, 期望 %s", i+1, got.ID, want.ID)
+		}
+	}
+}
+
+func TestWeightedRandomBalancer(t *testing.T) {
+	const rounds = 20000
+
+	tests := []struct {
+		name    string
+		weights []int
+		want    []float64
+	}{
+		{name: "权重相同", weights: []int{1, 1}, want: []float64{0.5, 0.5}},
+		{name: "按权重比例", weights: []int{1, 3}, want: []float64{0.25, 0.75}},
+		{name: "未设置权重按1计算", weights: []int{0, -1, 2}, want: []float64{0.25, 0.25, 0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewWeightedRandomBalancer()
+			b.rand = rand.New(rand.NewSource(1))
+			instances := testInstances(tt.weights...)
+
+			counts := make(map[*Instance]int)
+			for i := 0; i < rounds; i++ {
+				counts[b.Select(instances)]++
+			}
+			for i, instance := range instances {
+				if got := float64(counts[instance]) / rounds; math.Abs(got-tt.want[i]) > 0.02 {
+					t.Errorf("%s 的比例 = %.3f, 期望 %.3f", instance.ID, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestP2CBalancer(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		loads   map[int]loadStats // 实例下标 -> 负载
+		want    int
+	}{
+		{name: "选择耗时较低的实例", weights: []int{1, 1}, loads: map[int]loadStats{0: {latency: 50}, 1: {latency: 10}}, want: 1},
+		{name: "选择正在处理请求较少的实例", weights: []int{1, 1}, loads: map[int]loadStats{0: {latency: 10, inFlight: 4}, 1: {latency: 10}}, want: 1},
+		{name: "权重较高的实例代价较低", weights: []int{1, 4}, loads: map[int]loadStats{0: {latency: 10}, 1: {latency: 20}}, want: 1},
+		{name: "没有记录的实例按平均耗时计算", weights: []int{1, 1}, loads: map[int]loadStats{0: {latency: 10, inFlight: 2}}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewP2CBalancer()
+			b.rand = rand.New(rand.NewSource(1))
+			instances := testInstances(tt.weights...)
+			for i, load := range tt.loads {
+				load := load
+				b.loads[instances[i].Address()] = &load
+			}
+
+			// 只有两个实例时每次都比较这两个实例
+			for i := 0; i < 10; i++ {
+				if got := b.Select(instances); got != instances[tt.want] {
+					t.Fatalf("选中 %s, 期望 %s", got.ID, instances[tt.want].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestP2CBalancerObservesLoad(t *testing.T) {
+	b := NewP2CBalancer()
+	b.rand = rand.New(rand.NewSource(1))
+	instances := testInstances(1, 1)
+
+	// 实例0 有未完成的请求，应选择实例1；请求完成后两个实例的代价相同
+	done := b.Begin(instances[0])
+	if got := b.Select(instances); got != instances[1] {
+		t.Fatalf("选中 %s, 期望 %s", got.ID, instances[1].ID)
+	}
+	done()
+	done()
+	if inFlight := b.loads[instances[0].Address()].inFlight; inFlight != 0 {
+		t.Errorf("重复结束后 inFlight = %d, 期望 0", inFlight)
+	}
+}
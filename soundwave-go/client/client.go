@@ -4,20 +4,70 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"soundwave-go/internal/logger"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ConnectionState 客户端与服务中心的连接状态
+type ConnectionState string
+
+const (
+	// StateConnecting 正在向服务中心注册
+	StateConnecting ConnectionState = "CONNECTING"
+	// StateRegistered 已注册且心跳正常
+	StateRegistered ConnectionState = "REGISTERED"
+	// StateDisconnected 无法连接服务中心，稍后自动重试
+	StateDisconnected ConnectionState = "DISCONNECTED"
+)
+
+// errInstanceNotFound 服务中心中不存在当前实例，通常是服务中心重启或实例心跳超时被剔除
+var errInstanceNotFound = errors.New("服务实例不存在")
+
+// statusError 服务中心返回的非成功响应
+type statusError struct {
+	action     string
+	statusCode int
+	body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s，状态码: %d, 响应: %s", e.action, e.statusCode, e.body)
+}
+
 // Client 服务注册客户端
 type Client struct {
-	config *ClientConfig
-	ctx    context.Context
-	cancel context.CancelFunc
+	config     *ClientConfig
+	ctx        context.Context
+	cancel     context.CancelFunc
+	httpClient *http.Client
+	urls       []string
+
+	// configMutex 保护 UpdateMetadata 会修改的元数据、权重和版本，以及注册时获得的实例令牌
+	configMutex   sync.Mutex
+	instanceToken string
+
+	stateMutex sync.Mutex
+	state      ConnectionState
+	current    int
+
+	balancer   Balancer
+	load       *LoadTracker
+	cacheMutex sync.Mutex
+	cache      map[string]*cacheEntry
+
+	reportOnce  sync.Once
+	reportMutex sync.Mutex
+	outcomes    map[outcomeKey]*outcomeCounts
 }
 
 // NewClient 创建新的客户端实例
@@ -37,81 +87,232 @@ func NewClient(config *ClientConfig) (*Client, error) {
 		config.ServiceID = fmt.Sprintf("%s-%s", config.ServiceName, hostname)
 	}
 
+	defaults := DefaultConfig()
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = defaults.HeartbeatInterval
+	}
+	if config.DiscoveryInterval <= 0 {
+		config.DiscoveryInterval = defaults.DiscoveryInterval
+	}
+	if config.ReportInterval <= 0 {
+		config.ReportInterval = defaults.ReportInterval
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = defaults.RequestTimeout
+	}
+	if config.RetryMinBackoff <= 0 {
+		config.RetryMinBackoff = defaults.RetryMinBackoff
+	}
+	if config.RetryMaxBackoff < config.RetryMinBackoff {
+		config.RetryMaxBackoff = defaults.RetryMaxBackoff
+		if config.RetryMaxBackoff < config.RetryMinBackoff {
+			config.RetryMaxBackoff = config.RetryMinBackoff
+		}
+	}
+
+	urls := make([]string, 0, len(config.RegistryURLs)+1)
+	for _, registryURL := range config.RegistryURLs {
+		if registryURL != "" {
+			urls = append(urls, strings.TrimSuffix(registryURL, "/"))
+		}
+	}
+	if len(urls) == 0 {
+		if config.RegistryURL == "" {
+			return nil, fmt.Errorf("服务中心地址不能为空")
+		}
+		urls = append(urls, strings.TrimSuffix(config.RegistryURL, "/"))
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: config.RequestTimeout}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Client{
-		config: config,
-		ctx:    ctx,
-		cancel: cancel,
+		config:     config,
+		ctx:        ctx,
+		cancel:     cancel,
+		httpClient: httpClient,
+		urls:       urls,
+		state:      StateDisconnected,
+		balancer:   NewRoundRobinBalancer(),
+		load:       &LoadTracker{},
+		cache:      make(map[string]*cacheEntry),
 	}, nil
 }
 
-// Start 启动服务注册和心跳
+// Start 启动服务注册和心跳。
+// 服务中心暂不可用时不返回错误，在后台按指数退避重试注册；服务中心拒绝注册信息时返回错误
 func (c *Client) Start() error {
+	// 验证必要字段
+	if c.config.ServiceName == "" {
+		return fmt.Errorf("服务名称不能为空")
+	}
+	if c.config.Port <= 0 || c.config.Port > 65535 {
+		return fmt.Errorf("无效的端口号: %d", c.config.Port)
+	}
+
+	c.setState(StateConnecting, nil)
+
 	// 注册服务
-	if err := c.register(); err != nil {
+	err := c.register()
+	var rejected *statusError
+	if errors.As(err, &rejected) && rejected.statusCode < http.StatusInternalServerError {
 		return fmt.Errorf("服务注册失败: %v", err)
 	}
 
-	// 启动心跳
-	go c.heartbeat()
+	registered := err == nil
+	if registered {
+		c.setState(StateRegistered, nil)
+	} else {
+		logger.WarnLogger.Printf("服务注册失败，将在后台重试: %v", err)
+		c.setState(StateDisconnected, err)
+	}
+
+	go c.run(registered)
 
 	return nil
 }
 
-// Stop 停止服务
+// Stop 停止心跳和服务发现缓存的刷新，关闭所有 Watch 通道
 func (c *Client) Stop() {
 	if c.cancel != nil {
 		c.cancel()
 	}
 }
 
-func (c *Client) register() error {
-	// 验证必要字段
-	if c.config.ServiceName == "" {
-		return fmt.Errorf("服务名称不能为空")
+// Load 返回当前实例的负载统计，使用 Load().Middleware 或 Load().Start 统计的负载会随心跳上报到服务中心
+func (c *Client) Load() *LoadTracker {
+	return c.load
+}
+
+// State 返回当前与服务中心的连接状态
+func (c *Client) State() ConnectionState {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+	return c.state
+}
+
+func (c *Client) setState(state ConnectionState, err error) {
+	c.stateMutex.Lock()
+	if c.state == state {
+		c.stateMutex.Unlock()
+		return
 	}
-	if c.config.Port <= 0 || c.config.Port > 65535 {
-		return fmt.Errorf("无效的端口号: %d", c.config.Port)
+	c.state = state
+	c.stateMutex.Unlock()
+
+	if c.config.OnStateChange != nil {
+		c.config.OnStateChange(state, err)
+	}
+}
+
+func (c *Client) run(registered bool) {
+	if !registered && !c.registerWithRetry() {
+		return
+	}
+	c.heartbeat()
+}
+
+// registerWithRetry 注册失败时按指数退避重试，直到成功或客户端停止
+func (c *Client) registerWithRetry() bool {
+	for attempt := 0; ; attempt++ {
+		err := c.register()
+		if err == nil {
+			c.setState(StateRegistered, nil)
+			return true
+		}
+
+		delay := c.backoff(attempt)
+		logger.WarnLogger.Printf("服务注册失败，%v 后重试: %v", delay, err)
+		c.setState(StateDisconnected, err)
+
+		select {
+		case <-c.ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff 返回第 attempt 次重试前的等待时间，按指数增长并加入随机抖动，避免大量实例同时重试
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.config.RetryMaxBackoff
+	if attempt < 32 {
+		if d := c.config.RetryMinBackoff << attempt; d > 0 && d < delay {
+			delay = d
+		}
 	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
 
+func (c *Client) register() error {
 	hostname := getHostname()
-	data := map[string]interface{}{
-		"name":     c.config.ServiceName,
-		"id":       c.config.ServiceID,
-		"hostname": hostname,
-		"ip":       c.config.IP,
-		"port":     c.config.Port,
-		"version":  c.config.Version,
-		"metadata": c.config.Metadata,
+	var status string
+	if c.config.HealthCheck != nil {
+		ctx, cancel := context.WithTimeout(c.ctx, c.config.RequestTimeout)
+		status = c.config.HealthCheck(ctx).Status
+		cancel()
 	}
 
+	c.configMutex.Lock()
+	data := map[string]interface{}{
+		"namespace": c.config.Namespace,
+		"name":      c.config.ServiceName,
+		"id":        c.config.ServiceID,
+		"hostname":  hostname,
+		"ip":        c.config.IP,
+		"port":      c.config.Port,
+		"version":   c.config.Version,
+		"weight":    c.config.Weight,
+		"metadata":  c.config.Metadata,
+		"tags":      c.config.Tags,
+		"region":    c.config.Region,
+		"zone":      c.config.Zone,
+		"status":    status,
+	}
 	jsonData, err := json.Marshal(data)
+	c.configMutex.Unlock()
 	if err != nil {
 		return fmt.Errorf("JSON编码失败: %v", err)
 	}
 
 	log.Printf("发送注册请求: %s", string(jsonData))
 
-	resp, err := http.Post(
-		fmt.Sprintf("%s/services", c.config.RegistryURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.do(c.ctx, http.MethodPost, "/services", jsonData)
 	if err != nil {
 		return fmt.Errorf("请求失败: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("服务注册失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))
+	if resp.statusCode != http.StatusOK {
+		return &statusError{action: "服务注册失败", statusCode: resp.statusCode, body: string(resp.body)}
 	}
 
+	// 保存实例令牌，更新实例信息时用于证明请求来自实例自身
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	c.configMutex.Lock()
+	c.instanceToken = result.Token
+	c.configMutex.Unlock()
+
 	logger.InfoLogger.Printf("服务 %s 注册成功", c.config.ServiceName)
 	return nil
 }
 
+// namespaceQuery 返回请求服务中心时附加的命名空间查询参数，未配置命名空间时为空
+func (c *Client) namespaceQuery() string {
+	if c.config.Namespace == "" {
+		return ""
+	}
+	return "?namespace=" + url.QueryEscape(c.config.Namespace)
+}
+
 func (c *Client) heartbeat() {
 	ticker := time.NewTicker(c.config.HeartbeatInterval)
 	defer ticker.Stop()
@@ -121,36 +322,156 @@ func (c *Client) heartbeat() {
 		case <-c.ctx.Done():
 			return
 		case <-ticker.C:
-			if err := c.sendHeartbeat(); err != nil {
+			err := c.sendHeartbeat()
+			switch {
+			case err == nil:
+				c.setState(StateRegistered, nil)
+			case errors.Is(err, errInstanceNotFound):
+				logger.WarnLogger.Printf("服务实例 %s 已被服务中心移除，重新注册", c.config.ServiceID)
+				c.setState(StateConnecting, err)
+				if !c.registerWithRetry() {
+					return
+				}
+			default:
 				logger.ErrorLogger.Printf("发送心跳失败: %v", err)
+				c.setState(StateDisconnected, err)
 			}
 		}
 	}
 }
 
 func (c *Client) sendHeartbeat() error {
-	url := fmt.Sprintf("%s/services/%s/%s/heartbeat",
-		c.config.RegistryURL,
-		c.config.ServiceName,
-		c.config.ServiceID,
-	)
+	path := fmt.Sprintf("/services/%s/%s/heartbeat",
+		url.PathEscape(c.config.ServiceName),
+		url.PathEscape(c.config.ServiceID),
+	) + c.namespaceQuery()
+
+	var req heartbeatRequest
+	if load, ok := c.load.Snapshot(); ok {
+		req.LoadReport = &load
+	}
+	if c.config.HealthCheck != nil {
+		ctx, cancel := context.WithTimeout(c.ctx, c.config.RequestTimeout)
+		req.HealthReport = c.config.HealthCheck(ctx)
+		cancel()
+	}
+
+	var body []byte
+	if req.LoadReport != nil || req.Status != "" || len(req.Checks) > 0 {
+		var err error
+		if body, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
 
-	req, err := http.NewRequest(http.MethodPut, url, nil)
+	resp, err := c.do(c.ctx, http.MethodPut, path, body)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	switch resp.statusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return errInstanceNotFound
+	default:
+		return &statusError{action: "心跳请求失败", statusCode: resp.statusCode, body: string(resp.body)}
+	}
+}
+
+// heartbeatRequest 心跳请求体，负载和健康状态均可省略
+type heartbeatRequest struct {
+	*LoadReport
+	HealthReport
+}
+
+// registryResponse 服务中心的响应，code 为错误响应中的错误码
+type registryResponse struct {
+	statusCode int
+	code       string
+	body       []byte
+}
+
+// do 向服务中心发送请求，连接失败或服务中心内部错误时依次切换到下一个地址，
+// 成功的地址会作为后续请求的首选地址
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*registryResponse, error) {
+	return c.doWithHeader(ctx, method, path, body, nil)
+}
+
+// doWithHeader 与 do 相同，请求时附加 header 中的请求头
+func (c *Client) doWithHeader(ctx context.Context, method, path string, body []byte, header http.Header) (*registryResponse, error) {
+	c.stateMutex.Lock()
+	start := c.current
+	c.stateMutex.Unlock()
+
+	var lastErr error
+	for i := range c.urls {
+		index := (start + i) % len(c.urls)
+		resp, err := c.doOnce(ctx, method, c.urls[index]+path, body, header)
+		if err == nil && !isRegistryFailure(resp) {
+			if index != start {
+				c.stateMutex.Lock()
+				c.current = index
+				c.stateMutex.Unlock()
+				logger.InfoLogger.Printf("切换到服务中心: %s", c.urls[index])
+			}
+			return resp, nil
+		}
+
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %v", c.urls[index], err)
+		} else {
+			lastErr = fmt.Errorf("%s: 状态码: %d, 响应: %s", c.urls[index], resp.statusCode, string(resp.body))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) doOnce(ctx context.Context, method, target string, body []byte, header http.Header) (*registryResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &registryResponse{statusCode: resp.StatusCode, body: data}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("心跳请求失败，状态码: %d", resp.StatusCode)
+		var errResp struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(data, &errResp)
+		result.code = errResp.Code
 	}
+	return result, nil
+}
 
-	return nil
+// isRegistryFailure 服务中心自身故障时需要切换地址，没有可用实例属于正常的业务响应
+func isRegistryFailure(resp *registryResponse) bool {
+	return resp.statusCode >= http.StatusInternalServerError && resp.code != "NO_AVAILABLE_INSTANCE"
 }
 
 func getHostname() string {
@@ -1,17 +1,42 @@
 package client
 
-import "time"
+import (
+	"context"
+	"net/http"
+	"time"
+)
 
 // ClientConfig 客户端配置
 type ClientConfig struct {
+	Namespace         string            // 命名空间，为空时使用默认命名空间
 	ServiceName       string            // 服务名称
 	ServiceID         string            // 服务ID
 	IP                string            // 服务IP
 	Port              int               // 服务端口
 	Version           string            // 服务版本
+	Weight            int               // 服务权重，0 表示使用默认权重
 	Metadata          map[string]string // 服务元数据
+	Tags              []string          // 服务标签，可用于服务发现时筛选实例
+	Region            string            // 实例所在的地域
+	Zone              string            // 实例所在的可用区，注册中心据此就近选择实例
 	RegistryURL       string            // 服务中心地址
+	RegistryURLs      []string          // 多个服务中心地址，请求失败时依次切换，为空时使用 RegistryURL
 	HeartbeatInterval time.Duration     // 心跳间隔
+	DiscoveryInterval time.Duration     // 服务发现缓存刷新间隔
+	ReportInterval    time.Duration     // 调用结果的上报间隔
+	CacheDir          string            // 服务发现缓存的持久化目录，为空时不持久化
+
+	HTTPClient      *http.Client  // 请求服务中心使用的 HTTP 客户端，为空时按 RequestTimeout 创建
+	RequestTimeout  time.Duration // 单次请求服务中心的超时时间
+	RetryMinBackoff time.Duration // 注册失败后的最小重试间隔
+	RetryMaxBackoff time.Duration // 注册失败后的最大重试间隔
+
+	// OnStateChange 与服务中心的连接状态变化时回调，err 为导致状态变化的错误
+	OnStateChange func(state ConnectionState, err error)
+
+	// HealthCheck 每次发送心跳前回调，返回的状态和依赖健康情况随心跳上报，ctx 在 RequestTimeout 后取消。
+	// 为空时只上报实例存活，不修改实例状态
+	HealthCheck func(ctx context.Context) HealthReport
 }
 
 // DefaultConfig 返回默认配置
@@ -22,6 +47,11 @@ func DefaultConfig() *ClientConfig {
 		Version:           "1.0.0",
 		RegistryURL:       "http://localhost:7777",
 		HeartbeatInterval: 10 * time.Second,
+		DiscoveryInterval: 10 * time.Second,
+		ReportInterval:    5 * time.Second,
+		RequestTimeout:    5 * time.Second,
+		RetryMinBackoff:   time.Second,
+		RetryMaxBackoff:   30 * time.Second,
 		Metadata:          make(map[string]string),
 	}
 }
@@ -0,0 +1,344 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"soundwave-go/internal/logger"
+	"soundwave-go/pkg/routing"
+)
+
+// ServiceInstances 服务发现结果
+type ServiceInstances struct {
+	Name      string      `json:"name"`
+	Instances []*Instance `json:"instances"`
+	// UpdatedAt 最近一次从注册中心获取成功的时间
+	UpdatedAt time.Time `json:"updated_at"`
+	// Stale 注册中心暂不可用，Instances 为本地缓存的数据
+	Stale bool `json:"stale"`
+	// Rules 服务的路由规则，Select 和 SelectRoute 会按规则选择实例
+	Rules []TrafficRule `json:"rules,omitempty"`
+}
+
+// Discover 获取服务的健康实例。首次调用时查询注册中心，之后由后台按 DiscoveryInterval 刷新本地缓存；
+// 注册中心不可用时返回缓存数据并将 Stale 置为 true，没有任何缓存时返回错误
+func (c *Client) Discover(name string) (*ServiceInstances, error) {
+	entry, err := c.cacheEntry(name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.snapshot(), nil
+}
+
+// Select 按指定策略从服务的健康实例中选择一个，strategy 为 nil 时使用轮询。
+// 服务配置了路由规则时只匹配不要求请求头和路由键的规则，需要按请求匹配时使用 SelectRoute
+func (c *Client) Select(name string, strategy Balancer) (*Instance, error) {
+	return c.SelectRoute(name, RouteContext{}, strategy)
+}
+
+// SelectRoute 按服务的路由规则确定目标实例组，再按指定策略从中选择一个实例
+func (c *Client) SelectRoute(name string, route RouteContext, strategy Balancer) (*Instance, error) {
+	entry, err := c.cacheEntry(name)
+	if err != nil {
+		return nil, err
+	}
+	result, policy := entry.snapshotWithPolicy()
+	if len(result.Instances) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoAvailableInstance, name)
+	}
+
+	if strategy == nil {
+		strategy = c.balancer
+	}
+	return selectInstance(strategy, routeInstances(policy, result.Instances, route), route), nil
+}
+
+// Watch 订阅服务实例变化，通道中先推送当前结果，之后在实例列表或 Stale 状态变化时推送。
+// 处理过慢时只保留最新结果；调用返回的取消函数或 Stop 后通道关闭
+func (c *Client) Watch(name string) (<-chan *ServiceInstances, func(), error) {
+	entry, err := c.cacheEntry(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan *ServiceInstances, 1)
+	entry.mutex.Lock()
+	if entry.closed {
+		close(ch)
+	} else {
+		entry.watchers[ch] = struct{}{}
+		ch <- entry.snapshotLocked()
+	}
+	entry.mutex.Unlock()
+
+	cancel := func() {
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+		if _, ok := entry.watchers[ch]; ok {
+			delete(entry.watchers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel, nil
+}
+
+// cacheEntry 获取服务的缓存，首次访问时查询注册中心，失败时尝试读取磁盘缓存
+func (c *Client) cacheEntry(name string) (*cacheEntry, error) {
+	if name == "" {
+		return nil, fmt.Errorf("服务名称不能为空")
+	}
+
+	c.cacheMutex.Lock()
+	entry, ok := c.cache[name]
+	c.cacheMutex.Unlock()
+	if ok {
+		return entry, nil
+	}
+
+	result, err := c.fetchInstances(name)
+	if err != nil {
+		cached, loadErr := c.loadCache(name)
+		if loadErr != nil {
+			return nil, fmt.Errorf("获取服务 %s 实例失败: %v", name, err)
+		}
+		logger.WarnLogger.Printf("注册中心不可用，使用服务 %s 的磁盘缓存: %v", name, err)
+		result = cached
+	} else {
+		c.saveCache(result)
+	}
+
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	if existing, ok := c.cache[name]; ok {
+		return existing, nil
+	}
+	entry = newCacheEntry(result)
+	c.cache[name] = entry
+	go c.refresh(name, entry)
+
+	return entry, nil
+}
+
+// refresh 定期刷新服务缓存直到客户端停止
+func (c *Client) refresh(name string, entry *cacheEntry) {
+	defer entry.close()
+
+	ticker := time.NewTicker(c.config.DiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := c.fetchInstances(name)
+			if err != nil {
+				if entry.markStale() {
+					logger.WarnLogger.Printf("刷新服务 %s 实例失败，使用本地缓存: %v", name, err)
+				}
+				continue
+			}
+			if entry.update(result) {
+				c.saveCache(result)
+			}
+		}
+	}
+}
+
+// fetchInstances 从注册中心查询服务的健康实例，服务不存在或没有健康实例时返回空列表
+func (c *Client) fetchInstances(name string) (*ServiceInstances, error) {
+	resp, err := c.do(c.ctx, http.MethodGet, "/services/"+url.PathEscape(name)+c.namespaceQuery(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ServiceInstances{
+		Name:      name,
+		Instances: make([]*Instance, 0),
+		UpdatedAt: time.Now(),
+	}
+
+	if resp.statusCode != http.StatusOK {
+		if resp.code == "SERVICE_NOT_FOUND" || resp.code == "NO_AVAILABLE_INSTANCE" {
+			return result, nil
+		}
+		return nil, &statusError{action: "查询服务失败", statusCode: resp.statusCode, body: string(resp.body)}
+	}
+
+	var data struct {
+		Services []*Instance   `json:"services"`
+		Rules    []TrafficRule `json:"rules"`
+	}
+	if err := json.Unmarshal(resp.body, &data); err != nil {
+		return nil, fmt.Errorf("JSON解码失败: %v", err)
+	}
+	if data.Services != nil {
+		result.Instances = data.Services
+	}
+	result.Rules = data.Rules
+	sort.Slice(result.Instances, func(i, j int) bool {
+		return result.Instances[i].ID < result.Instances[j].ID
+	})
+
+	return result, nil
+}
+
+func (c *Client) cachePath(name string) string {
+	return filepath.Join(c.config.CacheDir, url.PathEscape(name)+".json")
+}
+
+// saveCache 将服务实例写入磁盘缓存，先写临时文件再重命名，避免进程退出时留下不完整的文件
+func (c *Client) saveCache(result *ServiceInstances) {
+	if c.config.CacheDir == "" {
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		logger.ErrorLogger.Printf("编码服务 %s 缓存失败: %v", result.Name, err)
+		return
+	}
+
+	if err := os.MkdirAll(c.config.CacheDir, 0o755); err != nil {
+		logger.ErrorLogger.Printf("创建缓存目录失败: %v", err)
+		return
+	}
+	path := c.cachePath(result.Name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		logger.ErrorLogger.Printf("写入服务 %s 缓存失败: %v", result.Name, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		logger.ErrorLogger.Printf("写入服务 %s 缓存失败: %v", result.Name, err)
+	}
+}
+
+// loadCache 读取磁盘缓存，读取到的数据均视为过期
+func (c *Client) loadCache(name string) (*ServiceInstances, error) {
+	if c.config.CacheDir == "" {
+		return nil, fmt.Errorf("未启用磁盘缓存")
+	}
+
+	data, err := os.ReadFile(c.cachePath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var result ServiceInstances
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	result.Stale = true
+	return &result, nil
+}
+
+// cacheEntry 单个服务的发现缓存
+type cacheEntry struct {
+	mutex    sync.Mutex
+	result   *ServiceInstances
+	policy   *routing.TrafficPolicy // 由 result.Rules 编译得到
+	watchers map[chan *ServiceInstances]struct{}
+	closed   bool
+}
+
+func newCacheEntry(result *ServiceInstances) *cacheEntry {
+	entry := &cacheEntry{
+		result:   result,
+		watchers: make(map[chan *ServiceInstances]struct{}),
+	}
+	entry.compileLocked()
+	return entry
+}
+
+func (e *cacheEntry) snapshot() *ServiceInstances {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.snapshotLocked()
+}
+
+// snapshotWithPolicy 返回结果的副本和编译后的路由规则
+func (e *cacheEntry) snapshotWithPolicy() (*ServiceInstances, *routing.TrafficPolicy) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.snapshotLocked(), e.policy
+}
+
+// compileLocked 编译路由规则，规则无效时忽略并记录日志
+func (e *cacheEntry) compileLocked() {
+	policy, err := routing.CompileTrafficRules(e.result.Rules)
+	if err != nil {
+		logger.WarnLogger.Printf("忽略服务 %s 的无效路由规则: %v", e.result.Name, err)
+	}
+	e.policy = policy
+}
+
+// snapshotLocked 返回结果的副本，调用方修改返回值不影响缓存
+func (e *cacheEntry) snapshotLocked() *ServiceInstances {
+	result := *e.result
+	result.Instances = make([]*Instance, len(e.result.Instances))
+	for i, instance := range e.result.Instances {
+		copied := *instance
+		result.Instances[i] = &copied
+	}
+	return &result
+}
+
+// update 更新缓存，实例列表或 Stale 状态发生变化时通知订阅者并返回 true
+func (e *cacheEntry) update(result *ServiceInstances) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	changed := e.result.Stale || !reflect.DeepEqual(e.result.Instances, result.Instances) || !reflect.DeepEqual(e.result.Rules, result.Rules)
+	e.result = result
+	if changed {
+		e.compileLocked()
+		e.notifyLocked()
+	}
+	return changed
+}
+
+// markStale 标记缓存已过期，首次标记时通知订阅者并返回 true
+func (e *cacheEntry) markStale() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.result.Stale {
+		return false
+	}
+	result := *e.result
+	result.Stale = true
+	e.result = &result
+	e.notifyLocked()
+	return true
+}
+
+// notifyLocked 向订阅者推送最新结果，订阅者尚未读取的旧结果会被替换
+func (e *cacheEntry) notifyLocked() {
+	for ch := range e.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- e.snapshotLocked()
+	}
+}
+
+func (e *cacheEntry) close() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.closed = true
+	for ch := range e.watchers {
+		delete(e.watchers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 实例和依赖的状态，实例可上报 UP、DEGRADED、STARTING，依赖可上报 UP、DEGRADED、DOWN
+const (
+	StatusUP       = "UP"
+	StatusDegraded = "DEGRADED"
+	StatusStarting = "STARTING"
+	StatusDown     = "DOWN"
+)
+
+// HealthReport 实例通过心跳上报的健康状态，STARTING 状态的实例不参与服务发现
+type HealthReport struct {
+	Status string                      `json:"status,omitempty"` // 为空时保持实例当前状态，注册时视为 UP
+	Checks map[string]DependencyHealth `json:"checks,omitempty"` // 键为依赖名称，如 db、cache
+}
+
+// DependencyHealth 依赖的健康检查结果
+type DependencyHealth struct {
+	Status    string  `json:"status"`
+	Message   string  `json:"message,omitempty"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+}
+
+// HealthChecker 检查单个依赖，返回 nil 表示依赖正常
+type HealthChecker func(ctx context.Context) error
+
+// CheckDependencies 并发检查各依赖并汇总为 HealthReport，可在 ClientConfig.HealthCheck 中使用。
+// 依赖全部正常时状态为 UP，否则为 DEGRADED
+func CheckDependencies(ctx context.Context, checkers map[string]HealthChecker) HealthReport {
+	report := HealthReport{Status: StatusUP, Checks: make(map[string]DependencyHealth, len(checkers))}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checkers {
+		wg.Add(1)
+		go func(name string, check HealthChecker) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			result := DependencyHealth{
+				Status:    StatusUP,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusDown
+				result.Message = err.Error()
+			}
+			mutex.Lock()
+			report.Checks[name] = result
+			mutex.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusUP {
+			report.Status = StatusDegraded
+			break
+		}
+	}
+	return report
+}
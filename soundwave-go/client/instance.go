@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net"
+	"strconv"
+
+	"soundwave-go/api/registrypb"
+)
+
+// Instance 从注册中心获取的服务实例
+type Instance struct {
+	Name     string            `json:"name"`
+	ID       string            `json:"id"`
+	Hostname string            `json:"hostname"`
+	IP       string            `json:"ip"`
+	Port     int               `json:"port"`
+	Metadata map[string]string `json:"metadata"`
+	Status   string            `json:"status"`
+	Weight   int               `json:"weight"`
+	Version  string            `json:"version"`
+	Tags     []string          `json:"tags,omitempty"`
+	Region   string            `json:"region,omitempty"`
+	Zone     string            `json:"zone,omitempty"`
+	// Revision 实例最近一次变化时注册表的版本号
+	Revision uint64 `json:"revision"`
+}
+
+// Address 返回实例的 host:port 地址
+func (i *Instance) Address() string {
+	return net.JoinHostPort(i.IP, strconv.Itoa(i.Port))
+}
+
+func fromProto(instances []*registrypb.Instance) []*Instance {
+	result := make([]*Instance, 0, len(instances))
+	for _, instance := range instances {
+		result = append(result, &Instance{
+			Name:     instance.Name,
+			ID:       instance.Id,
+			Hostname: instance.Hostname,
+			IP:       instance.Ip,
+			Port:     int(instance.Port),
+			Metadata: instance.Metadata,
+			Status:   instance.Status,
+			Weight:   int(instance.Weight),
+			Version:  instance.Version,
+			Tags:     instance.Tags,
+			Region:   instance.Region,
+			Zone:     instance.Zone,
+			Revision: instance.Revision,
+		})
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// latencyDecay 耗时指数加权移动平均的衰减系数，越大越偏向最近的请求
+const latencyDecay = 0.3
+
+// LoadReport 实例的负载，随心跳上报到注册中心
+type LoadReport struct {
+	InFlight  int     `json:"in_flight"`  // 正在处理的请求数
+	LatencyMs float64 `json:"latency_ms"` // 请求耗时的指数加权移动平均，单位毫秒
+}
+
+// loadStats 正在处理的请求数和耗时的指数加权移动平均，由调用方加锁
+type loadStats struct {
+	inFlight int
+	latency  float64 // 毫秒，0 表示还没有完成的请求
+}
+
+func (s *loadStats) observe(elapsed time.Duration) {
+	ms := float64(elapsed) / float64(time.Millisecond)
+	if s.latency == 0 {
+		s.latency = ms
+		return
+	}
+	s.latency = latencyDecay*ms + (1-latencyDecay)*s.latency
+}
+
+// LoadTracker 统计当前服务实例的负载，Client 在心跳中上报给注册中心
+type LoadTracker struct {
+	mutex  sync.Mutex
+	stats  loadStats
+	active bool
+}
+
+// Start 记录一个请求开始处理，返回的函数在请求处理完成时调用
+func (t *LoadTracker) Start() func() {
+	start := time.Now()
+	t.mutex.Lock()
+	t.stats.inFlight++
+	t.active = true
+	t.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mutex.Lock()
+			t.stats.inFlight--
+			t.stats.observe(time.Since(start))
+			t.mutex.Unlock()
+		})
+	}
+}
+
+// Middleware 返回统计请求负载的 HTTP 中间件
+func (t *LoadTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done := t.Start()
+		defer done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Snapshot 返回当前负载，没有统计过任何请求时 ok 为 false
+func (t *LoadTracker) Snapshot() (report LoadReport, ok bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return LoadReport{InFlight: t.stats.inFlight, LatencyMs: t.stats.latency}, t.active
+}
+
+// LoadObserver 需要观察请求负载的负载均衡器，Transport 在向实例发送请求前调用 Begin，请求完成后调用返回的函数
+type LoadObserver interface {
+	Begin(instance *Instance) func()
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"soundwave-go/internal/logger"
+	"time"
+)
+
+// OutcomeReporter 接收实例的调用结果，Transport 每次请求完成后调用。
+// Client 实现了该接口，汇总后定期上报给服务中心用于异常实例检测
+type OutcomeReporter interface {
+	ReportOutcome(instance *Instance, failed bool)
+}
+
+type outcomeKey struct {
+	name string
+	id   string
+}
+
+type outcomeCounts struct {
+	Successes int `json:"successes"`
+	Failures  int `json:"failures"`
+}
+
+// outcomeRequest 上报请求体，服务中心只接受已注册实例的上报，调用方标识与实例令牌一并校验
+type outcomeRequest struct {
+	CallerService string `json:"caller_service"`
+	CallerID      string `json:"caller_id"`
+	*outcomeCounts
+}
+
+// ReportOutcome 记录一次对实例的调用结果，按 ReportInterval 汇总上报给服务中心。
+// 实例需与客户端位于同一命名空间，上报时携带客户端自身的实例令牌，客户端注册成功前的结果会被丢弃
+func (c *Client) ReportOutcome(instance *Instance, failed bool) {
+	key := outcomeKey{name: instance.Name, id: instance.ID}
+
+	c.reportMutex.Lock()
+	if c.outcomes == nil {
+		c.outcomes = make(map[outcomeKey]*outcomeCounts)
+	}
+	counts, ok := c.outcomes[key]
+	if !ok {
+		counts = &outcomeCounts{}
+		c.outcomes[key] = counts
+	}
+	if failed {
+		counts.Failures++
+	} else {
+		counts.Successes++
+	}
+	c.reportMutex.Unlock()
+
+	c.reportOnce.Do(func() {
+		go c.reportLoop()
+	})
+}
+
+// reportLoop 定期上报汇总的调用结果，客户端停止时丢弃未上报的结果
+func (c *Client) reportLoop() {
+	ticker := time.NewTicker(c.config.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.flushOutcomes()
+		}
+	}
+}
+
+func (c *Client) flushOutcomes() {
+	c.reportMutex.Lock()
+	outcomes := c.outcomes
+	c.outcomes = nil
+	c.reportMutex.Unlock()
+
+	for key, counts := range outcomes {
+		if err := c.sendOutcome(key, counts); err != nil {
+			logger.WarnLogger.Printf("上报服务实例 %s/%s 的调用结果失败: %v", key.name, key.id, err)
+		}
+	}
+}
+
+func (c *Client) sendOutcome(key outcomeKey, counts *outcomeCounts) error {
+	path := fmt.Sprintf("/services/%s/%s/report",
+		url.PathEscape(key.name),
+		url.PathEscape(key.id),
+	) + c.namespaceQuery()
+
+	c.configMutex.Lock()
+	token := c.instanceToken
+	c.configMutex.Unlock()
+	if token == "" {
+		return errors.New("当前实例尚未注册")
+	}
+
+	body, err := json.Marshal(outcomeRequest{
+		CallerService: c.config.ServiceName,
+		CallerID:      c.config.ServiceID,
+		outcomeCounts: counts,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doWithHeader(c.ctx, http.MethodPost, path, body, http.Header{instanceTokenHeader: {token}})
+	if err != nil {
+		return err
+	}
+	switch resp.statusCode {
+	case http.StatusOK, http.StatusNotFound:
+		// 实例已下线时忽略
+		return nil
+	default:
+		return &statusError{action: "上报调用结果失败", statusCode: resp.statusCode, body: string(resp.body)}
+	}
+}
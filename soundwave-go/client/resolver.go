@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"soundwave-go/api/registrypb"
+	"soundwave-go/internal/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/weightedroundrobin"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+)
+
+// ResolverScheme gRPC 目标地址使用的 scheme，如 soundwave:///order-service
+// 或 soundwave://registry:7778/order-service（指定注册中心地址），
+// 非默认命名空间的服务在服务名称前加命名空间，如 soundwave:///staging/order-service
+const ResolverScheme = "soundwave"
+
+// RegisterResolver 向 gRPC 全局注册 soundwave 解析器，registryAddr 为注册中心的 gRPC 地址，
+// 需在 grpc.Dial 之前调用。未传入连接选项时使用明文连接
+func RegisterResolver(registryAddr string, opts ...grpc.DialOption) {
+	resolver.Register(NewResolverBuilder(registryAddr, opts...))
+}
+
+// NewResolverBuilder 创建 soundwave 解析器，可通过 grpc.WithResolvers 按连接使用
+func NewResolverBuilder(registryAddr string, opts ...grpc.DialOption) resolver.Builder {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	return &resolverBuilder{
+		registryAddr: registryAddr,
+		dialOptions:  opts,
+	}
+}
+
+type resolverBuilder struct {
+	registryAddr string
+	dialOptions  []grpc.DialOption
+}
+
+func (b *resolverBuilder) Scheme() string {
+	return ResolverScheme
+}
+
+func (b *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	namespace, serviceName, ok := strings.Cut(strings.TrimPrefix(target.Endpoint(), "/"), "/")
+	if !ok {
+		namespace, serviceName = "", namespace
+	}
+	if serviceName == "" {
+		return nil, fmt.Errorf("服务名称不能为空: %s", target.String())
+	}
+
+	registryAddr := b.registryAddr
+	if target.URL.Host != "" {
+		registryAddr = target.URL.Host
+	}
+
+	conn, err := grpc.NewClient(registryAddr, b.dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("连接注册中心失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &registryResolver{
+		namespace:   namespace,
+		serviceName: serviceName,
+		cc:          cc,
+		conn:        conn,
+		client:      registrypb.NewRegistryClient(conn),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go r.watch()
+
+	return r, nil
+}
+
+// registryResolver 通过注册中心的 Watch 接口持续更新服务地址
+type registryResolver struct {
+	namespace   string
+	serviceName string
+	cc          resolver.ClientConn
+	conn        *grpc.ClientConn
+	client      registrypb.RegistryClient
+	ctx         context.Context
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// ResolveNow 地址由 Watch 推送，无需主动解析
+func (r *registryResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *registryResolver) Close() {
+	r.cancel()
+	<-r.done
+	r.conn.Close()
+}
+
+// watch 订阅服务实例变化。重连期间保留已推送的地址，注册中心短暂不可用不影响已有连接
+func (r *registryResolver) watch() {
+	defer close(r.done)
+
+	resolved := false
+	watchService(r.ctx, r.client, r.namespace, r.serviceName, func(instances []*registrypb.Instance) {
+		resolved = true
+		if err := r.cc.UpdateState(resolver.State{Addresses: toAddresses(instances)}); err != nil {
+			logger.WarnLogger.Printf("更新服务 %s 地址失败: %v", r.serviceName, err)
+		}
+	}, func(err error) {
+		logger.WarnLogger.Printf("订阅服务 %s 失败: %v", r.serviceName, err)
+		if !resolved {
+			r.cc.ReportError(err)
+		}
+	})
+}
+
+// toAddresses 转换为 gRPC 地址，实例权重供 weighted_round_robin 负载均衡使用
+func toAddresses(instances []*registrypb.Instance) []resolver.Address {
+	addresses := make([]resolver.Address, 0, len(instances))
+	for _, instance := range instances {
+		weight := instance.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		address := resolver.Address{
+			Addr: net.JoinHostPort(instance.Ip, strconv.Itoa(int(instance.Port))),
+		}
+		addresses = append(addresses, weightedroundrobin.SetAddrInfo(address, weightedroundrobin.AddrInfo{Weight: uint32(weight)}))
+	}
+	return addresses
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"sync"
+
+	"soundwave-go/pkg/routing"
+)
+
+// RouteKeyHeader 携带路由键的请求头，注册中心和 Transport 都会读取
+const RouteKeyHeader = "X-Soundwave-Route-Key"
+
+type (
+	// TrafficRule 注册中心下发的路由规则
+	TrafficRule = routing.TrafficRule
+	// TrafficMatch 路由规则的匹配条件
+	TrafficMatch = routing.TrafficMatch
+	// TrafficDestination 路由规则的目标实例组
+	TrafficDestination = routing.TrafficDestination
+	// RouteContext 匹配路由规则使用的请求信息
+	RouteContext = routing.RouteContext
+)
+
+// RouteBalancer 可按请求信息匹配路由规则的负载均衡器，Transport 会传入当前请求的路由键和请求头
+type RouteBalancer interface {
+	Balancer
+	SelectRoute(instances []*Instance, route RouteContext) *Instance
+}
+
+// RoutingBalancer 先按路由规则确定目标实例组，再由 next 从组内选择实例。
+// 目标实例组没有实例时从全部实例中选择，与注册中心的行为一致
+type RoutingBalancer struct {
+	next   Balancer
+	mutex  sync.RWMutex
+	policy *routing.TrafficPolicy
+}
+
+// NewRoutingBalancer 创建按路由规则选择实例的负载均衡器，next 为 nil 时使用轮询
+func NewRoutingBalancer(next Balancer) *RoutingBalancer {
+	if next == nil {
+		next = NewRoundRobinBalancer()
+	}
+	return &RoutingBalancer{next: next}
+}
+
+// SetRules 更新路由规则，规则无效时返回错误并保留原有规则
+func (b *RoutingBalancer) SetRules(rules []TrafficRule) error {
+	policy, err := routing.CompileTrafficRules(rules)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.policy = policy
+	return nil
+}
+
+// Select 只匹配不要求请求头和路由键的规则
+func (b *RoutingBalancer) Select(instances []*Instance) *Instance {
+	return b.SelectRoute(instances, RouteContext{})
+}
+
+// SelectRoute 按请求信息匹配路由规则后选择实例
+func (b *RoutingBalancer) SelectRoute(instances []*Instance, route RouteContext) *Instance {
+	b.mutex.RLock()
+	policy := b.policy
+	b.mutex.RUnlock()
+
+	return b.next.Select(routeInstances(policy, instances, route))
+}
+
+// Begin 实现 LoadObserver，next 观察请求负载时转发给 next
+func (b *RoutingBalancer) Begin(instance *Instance) func() {
+	if observer, ok := b.next.(LoadObserver); ok {
+		return observer.Begin(instance)
+	}
+	return func() {}
+}
+
+// routeInstances 返回路由规则选中的目标实例组，未命中规则或目标实例组为空时返回全部实例
+func routeInstances(policy *routing.TrafficPolicy, instances []*Instance, route RouteContext) []*Instance {
+	traffic := policy.Route(route)
+	if traffic == nil {
+		return instances
+	}
+
+	filtered := make([]*Instance, 0, len(instances))
+	for _, instance := range instances {
+		if traffic.Matches(instance.Version, instance.Metadata) {
+			filtered = append(filtered, instance)
+		}
+	}
+	if len(filtered) == 0 {
+		return instances
+	}
+	return filtered
+}
+
+// selectInstance 负载均衡器支持路由规则时传入请求信息
+func selectInstance(balancer Balancer, instances []*Instance, route RouteContext) *Instance {
+	if rb, ok := balancer.(RouteBalancer); ok {
+		return rb.SelectRoute(instances, route)
+	}
+	return balancer.Select(instances)
+}
@@ -0,0 +1,449 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"soundwave-go/api/registrypb"
+	"soundwave-go/internal/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ErrNoAvailableInstance 服务没有可用的实例
+var ErrNoAvailableInstance = errors.New("没有可用的服务实例")
+
+// TransportConfig 服务发现传输层配置
+type TransportConfig struct {
+	RegistryAddr     string            // 注册中心 gRPC 地址
+	Namespace        string            // 服务所在的命名空间，为空时使用默认命名空间
+	DialOptions      []grpc.DialOption // 连接注册中心的选项，默认明文连接
+	Base             http.RoundTripper // 实际发送请求的传输层，默认 http.DefaultTransport
+	Balancer         Balancer          // 负载均衡器，默认轮询；使用 RoutingBalancer 时按请求头匹配路由规则，使用 P2CBalancer 时按观察到的实例负载选择
+	MaxRetries       int               // 幂等请求失败后更换实例重试的次数
+	FailureThreshold int               // 实例连续失败多少次后被摘除
+	EjectionDuration time.Duration     // 实例被摘除的时长
+	ResolveTimeout   time.Duration     // 首次获取服务实例列表的等待时间
+	Reporter         OutcomeReporter   // 接收每次请求的结果，设置为 Client 时上报给注册中心用于异常实例检测
+}
+
+// DefaultTransportConfig 返回默认配置
+func DefaultTransportConfig() *TransportConfig {
+	return &TransportConfig{
+		RegistryAddr:     "localhost:7778",
+		MaxRetries:       2,
+		FailureThreshold: 3,
+		EjectionDuration: 30 * time.Second,
+		ResolveTimeout:   5 * time.Second,
+	}
+}
+
+// Transport 按服务名称发送 HTTP 请求的 http.RoundTripper。
+//
+// 请求地址的主机名不带端口、不是 IP 且不含点号时视为服务名称，如 http://order-service/orders，
+// 由负载均衡器从本地缓存的实例列表中选择实例并改写请求地址；其余请求直接交给 Base 发送。
+// 实例列表通过注册中心的 Watch 接口持续更新，注册中心不可用时沿用最近一次的列表
+type Transport struct {
+	config *TransportConfig
+	conn   *grpc.ClientConn
+	client registrypb.RegistryClient
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex    sync.Mutex
+	services map[string]*serviceEntry
+}
+
+// NewTransport 创建服务发现传输层，使用完毕后需调用 Close 停止订阅
+func NewTransport(config *TransportConfig) (*Transport, error) {
+	if config == nil {
+		config = DefaultTransportConfig()
+	}
+	defaults := DefaultTransportConfig()
+	if config.Base == nil {
+		config.Base = http.DefaultTransport
+	}
+	if config.Balancer == nil {
+		config.Balancer = NewRoundRobinBalancer()
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaults.FailureThreshold
+	}
+	if config.EjectionDuration <= 0 {
+		config.EjectionDuration = defaults.EjectionDuration
+	}
+	if config.ResolveTimeout <= 0 {
+		config.ResolveTimeout = defaults.ResolveTimeout
+	}
+	dialOptions := config.DialOptions
+	if len(dialOptions) == 0 {
+		dialOptions = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	conn, err := grpc.NewClient(config.RegistryAddr, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("连接注册中心失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Transport{
+		config:   config,
+		conn:     conn,
+		client:   registrypb.NewRegistryClient(conn),
+		ctx:      ctx,
+		cancel:   cancel,
+		services: make(map[string]*serviceEntry),
+	}, nil
+}
+
+// Close 停止所有订阅并断开与注册中心的连接
+func (t *Transport) Close() error {
+	t.cancel()
+	return t.conn.Close()
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, ok := serviceNameOf(req.URL)
+	if !ok {
+		return t.config.Base.RoundTrip(req)
+	}
+
+	entry := t.service(name)
+	if err := entry.wait(req.Context(), t.config.ResolveTimeout); err != nil {
+		return nil, err
+	}
+
+	route := RouteContext{Key: req.Header.Get(RouteKeyHeader), Headers: req.Header}
+	instance := entry.pick(t.config.Balancer, route, nil)
+	if instance == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoAvailableInstance, name)
+	}
+
+	retries := 0
+	if canRetry(req) {
+		retries = t.config.MaxRetries
+	}
+
+	tried := make(map[string]bool)
+	for attempt := 0; ; attempt++ {
+		tried[instance.ID] = true
+
+		resp, err := t.observe(req, instance, attempt)
+		if req.Context().Err() != nil {
+			// 调用方取消的请求不计入实例失败
+			return resp, err
+		}
+
+		failed := err != nil || isFailureStatus(resp.StatusCode)
+		entry.record(instance, failed, attempt > 0, t.config)
+		if t.config.Reporter != nil {
+			t.config.Reporter.ReportOutcome(instance, failed)
+		}
+		if !failed || attempt >= retries {
+			return resp, err
+		}
+
+		next := entry.pick(t.config.Balancer, route, tried)
+		if next == nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		instance = next
+	}
+}
+
+// observe 发送请求，负载均衡器实现 LoadObserver 时记录实例的负载
+func (t *Transport) observe(req *http.Request, instance *Instance, attempt int) (*http.Response, error) {
+	observer, ok := t.config.Balancer.(LoadObserver)
+	if !ok {
+		return t.send(req, instance, attempt)
+	}
+	done := observer.Begin(instance)
+	defer done()
+	return t.send(req, instance, attempt)
+}
+
+// send 将请求改写到指定实例后发送，重试时重新获取请求体
+func (t *Transport) send(req *http.Request, instance *Instance, attempt int) (*http.Response, error) {
+	outReq := req.Clone(req.Context())
+	outReq.URL.Host = instance.Address()
+	outReq.Host = ""
+
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		outReq.Body = body
+	}
+
+	return t.config.Base.RoundTrip(outReq)
+}
+
+// service 获取服务缓存，首次访问时开始订阅
+func (t *Transport) service(name string) *serviceEntry {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entry, ok := t.services[name]
+	if !ok {
+		entry = newServiceEntry(name)
+		t.services[name] = entry
+		go watchService(t.ctx, t.client, t.config.Namespace, name, entry.update, func(err error) {
+			logger.WarnLogger.Printf("订阅服务 %s 失败: %v", name, err)
+			entry.setError(err)
+		})
+	}
+	return entry
+}
+
+// Stats 返回各服务的请求统计，按服务名称排序
+func (t *Transport) Stats() []ServiceStats {
+	t.mutex.Lock()
+	entries := make([]*serviceEntry, 0, len(t.services))
+	for _, entry := range t.services {
+		entries = append(entries, entry)
+	}
+	t.mutex.Unlock()
+
+	stats := make([]ServiceStats, 0, len(entries))
+	for _, entry := range entries {
+		stats = append(stats, entry.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// ServiceStats 服务的请求统计
+type ServiceStats struct {
+	Name      string          `json:"name"`
+	Requests  uint64          `json:"requests"`
+	Failures  uint64          `json:"failures"`
+	Retries   uint64          `json:"retries"`
+	Instances []InstanceStats `json:"instances"`
+}
+
+// InstanceStats 实例的请求统计
+type InstanceStats struct {
+	ID                  string    `json:"id"`
+	Address             string    `json:"address"`
+	Requests            uint64    `json:"requests"`
+	Failures            uint64    `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Ejections           uint64    `json:"ejections"`
+	Ejected             bool      `json:"ejected"`
+	EjectedUntil        time.Time `json:"ejected_until,omitempty"`
+}
+
+// serviceEntry 单个服务的实例缓存和统计
+type serviceEntry struct {
+	name      string
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	mutex     sync.Mutex
+	instances []*Instance
+	err       error
+	endpoints map[string]*endpointState
+	requests  uint64
+	failures  uint64
+	retries   uint64
+}
+
+// endpointState 实例的被动健康状态
+type endpointState struct {
+	requests            uint64
+	failures            uint64
+	consecutiveFailures int
+	ejections           uint64
+	ejectedUntil        time.Time
+}
+
+func newServiceEntry(name string) *serviceEntry {
+	return &serviceEntry{
+		name:      name,
+		ready:     make(chan struct{}),
+		endpoints: make(map[string]*endpointState),
+	}
+}
+
+func (e *serviceEntry) update(instances []*registrypb.Instance) {
+	e.mutex.Lock()
+	e.instances = fromProto(instances)
+	e.err = nil
+
+	// 清理已下线实例的状态，保留仍在线实例的摘除状态
+	current := make(map[string]bool, len(e.instances))
+	for _, instance := range e.instances {
+		current[instance.ID] = true
+	}
+	for id := range e.endpoints {
+		if !current[id] {
+			delete(e.endpoints, id)
+		}
+	}
+	e.mutex.Unlock()
+
+	e.readyOnce.Do(func() { close(e.ready) })
+}
+
+func (e *serviceEntry) setError(err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.err = err
+}
+
+// wait 等待首次获取实例列表
+func (e *serviceEntry) wait(ctx context.Context, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-e.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		if e.err != nil {
+			return fmt.Errorf("获取服务 %s 实例失败: %v", e.name, e.err)
+		}
+		return fmt.Errorf("获取服务 %s 实例超时", e.name)
+	}
+}
+
+// pick 从未尝试过的实例中选择，优先选择未被摘除的实例；全部被摘除时仍从中选择，避免服务整体不可用
+func (e *serviceEntry) pick(balancer Balancer, route RouteContext, tried map[string]bool) *Instance {
+	e.mutex.Lock()
+	now := time.Now()
+	healthy := make([]*Instance, 0, len(e.instances))
+	ejected := make([]*Instance, 0)
+	for _, instance := range e.instances {
+		if tried[instance.ID] {
+			continue
+		}
+		if state, ok := e.endpoints[instance.ID]; ok && now.Before(state.ejectedUntil) {
+			ejected = append(ejected, instance)
+		} else {
+			healthy = append(healthy, instance)
+		}
+	}
+	e.mutex.Unlock()
+
+	if len(healthy) > 0 {
+		return selectInstance(balancer, healthy, route)
+	}
+	if len(ejected) > 0 {
+		return selectInstance(balancer, ejected, route)
+	}
+	return nil
+}
+
+// record 记录一次请求结果，连续失败达到阈值时摘除实例
+func (e *serviceEntry) record(instance *Instance, failed, retry bool, config *TransportConfig) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	state, ok := e.endpoints[instance.ID]
+	if !ok {
+		state = &endpointState{}
+		e.endpoints[instance.ID] = state
+	}
+
+	if retry {
+		e.retries++
+	} else {
+		e.requests++
+	}
+	state.requests++
+
+	if !failed {
+		state.consecutiveFailures = 0
+		return
+	}
+
+	e.failures++
+	state.failures++
+	state.consecutiveFailures++
+	if state.consecutiveFailures >= config.FailureThreshold {
+		state.consecutiveFailures = 0
+		state.ejections++
+		state.ejectedUntil = time.Now().Add(config.EjectionDuration)
+		logger.WarnLogger.Printf("服务 %s 实例 %s 连续失败 %d 次，摘除 %v", e.name, instance.ID, config.FailureThreshold, config.EjectionDuration)
+	}
+}
+
+func (e *serviceEntry) stats() ServiceStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := time.Now()
+	stats := ServiceStats{
+		Name:      e.name,
+		Requests:  e.requests,
+		Failures:  e.failures,
+		Retries:   e.retries,
+		Instances: make([]InstanceStats, 0, len(e.instances)),
+	}
+	for _, instance := range e.instances {
+		instanceStats := InstanceStats{
+			ID:      instance.ID,
+			Address: instance.Address(),
+		}
+		if state, ok := e.endpoints[instance.ID]; ok {
+			instanceStats.Requests = state.requests
+			instanceStats.Failures = state.failures
+			instanceStats.ConsecutiveFailures = state.consecutiveFailures
+			instanceStats.Ejections = state.ejections
+			if now.Before(state.ejectedUntil) {
+				instanceStats.Ejected = true
+				instanceStats.EjectedUntil = state.ejectedUntil
+			}
+		}
+		stats.Instances = append(stats.Instances, instanceStats)
+	}
+	return stats
+}
+
+// serviceNameOf 主机名不带端口、不是 IP 且不含点号时视为服务名称
+func serviceNameOf(u *url.URL) (string, bool) {
+	host := u.Hostname()
+	if host == "" || u.Port() != "" || host == "localhost" || strings.Contains(host, ".") || net.ParseIP(host) != nil {
+		return "", false
+	}
+	return host, true
+}
+
+// canRetry 幂等请求且请求体可以重新获取时才允许重试
+func canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// isFailureStatus 网关错误和服务不可用视为实例故障，其余状态码由调用方处理
+func isFailureStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"soundwave-go/api/registrypb"
+)
+
+// newTestTransport 创建不连接注册中心的 Transport，服务 api 的实例为给定的地址
+func newTestTransport(t *testing.T, config *TransportConfig, addrs ...string) *Transport {
+	t.Helper()
+	instances := make([]*registrypb.Instance, 0, len(addrs))
+	for i, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			t.Fatalf("解析地址 %s 失败: %v", addr, err)
+		}
+		p, _ := strconv.Atoi(port)
+		instances = append(instances, &registrypb.Instance{Name: "api", Id: "api-" + strconv.Itoa(i), Ip: host, Port: int32(p)})
+	}
+
+	entry := newServiceEntry("api")
+	entry.update(instances)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if config.Base == nil {
+		config.Base = http.DefaultTransport
+	}
+	if config.Balancer == nil {
+		config.Balancer = NewRoundRobinBalancer()
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.EjectionDuration <= 0 {
+		config.EjectionDuration = time.Minute
+	}
+	if config.ResolveTimeout <= 0 {
+		config.ResolveTimeout = time.Second
+	}
+	return &Transport{
+		config:   config,
+		ctx:      ctx,
+		cancel:   cancel,
+		services: map[string]*serviceEntry{"api": entry},
+	}
+}
+
+// statusServer 返回固定状态码的实例，响应体为实例名称
+func statusServer(t *testing.T, name string, status int) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(server.Close)
+	return server.Listener.Addr().String()
+}
+
+// closedAddr 返回没有监听的地址，连接会被拒绝
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func TestTransportFailover(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		header      map[string]string
+		maxRetries  int
+		first       func(t *testing.T) string // 轮询时首先选中的实例
+		wantStatus  int
+		wantBody    string
+		wantErr     bool
+		wantRetries uint64
+	}{
+		{
+			name: "幂等请求遇到503时更换实例", method: http.MethodGet, maxRetries: 2,
+			first:      func(t *testing.T) string { return statusServer(t, "bad", http.StatusServiceUnavailable) },
+			wantStatus: http.StatusOK, wantBody: "good", wantRetries: 1,
+		},
+		{
+			name: "连接失败时更换实例", method: http.MethodGet, maxRetries: 2,
+			first:      closedAddr,
+			wantStatus: http.StatusOK, wantBody: "good", wantRetries: 1,
+		},
+		{
+			name: "非幂等请求不重试", method: http.MethodPost, maxRetries: 2,
+			first:      func(t *testing.T) string { return statusServer(t, "bad", http.StatusServiceUnavailable) },
+			wantStatus: http.StatusServiceUnavailable, wantBody: "bad",
+		},
+		{
+			name: "带Idempotency-Key的POST请求重试", method: http.MethodPost, header: map[string]string{"Idempotency-Key": "k1"}, maxRetries: 2,
+			first:      func(t *testing.T) string { return statusServer(t, "bad", http.StatusBadGateway) },
+			wantStatus: http.StatusOK, wantBody: "good", wantRetries: 1,
+		},
+		{
+			name: "未开启重试", method: http.MethodGet,
+			first:   closedAddr,
+			wantErr: true,
+		},
+		{
+			name: "其他错误状态码由调用方处理", method: http.MethodGet, maxRetries: 2,
+			first:      func(t *testing.T) string { return statusServer(t, "bad", http.StatusInternalServerError) },
+			wantStatus: http.StatusInternalServerError, wantBody: "bad",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newTestTransport(t, &TransportConfig{MaxRetries: tt.maxRetries}, tt.first(t), statusServer(t, "good", http.StatusOK))
+
+			req, _ := http.NewRequest(tt.method, "http://api/orders", strings.NewReader("body"))
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			resp, err := transport.RoundTrip(req)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("期望返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body := make([]byte, 16)
+			n, _ := resp.Body.Read(body)
+			if resp.StatusCode != tt.wantStatus || string(body[:n]) != tt.wantBody {
+				t.Errorf("响应 = %d %s, 期望 %d %s", resp.StatusCode, body[:n], tt.wantStatus, tt.wantBody)
+			}
+			if stats := transport.Stats()[0]; stats.Retries != tt.wantRetries {
+				t.Errorf("Retries = %d, 期望 %d", stats.Retries, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestTransportEjectsFailingInstance(t *testing.T) {
+	transport := newTestTransport(t, &TransportConfig{FailureThreshold: 2},
+		statusServer(t, "bad", http.StatusServiceUnavailable), statusServer(t, "good", http.StatusOK))
+
+	status := make([]int, 0)
+	for i := 0; i < 6; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://api/orders", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		resp.Body.Close()
+		status = append(status, resp.StatusCode)
+	}
+
+	// 轮询交替选择两个实例，bad 连续失败 2 次后被摘除，之后的请求都发往 good
+	want := []int{503, 200, 503, 200, 200, 200}
+	for i := range want {
+		if status[i] != want[i] {
+			t.Fatalf("状态码 = %v, 期望 %v", status, want)
+		}
+	}
+	stats := transport.Stats()[0]
+	if !stats.Instances[0].Ejected || stats.Instances[0].Ejections != 1 {
+		t.Errorf("实例 %s Ejected = %v, Ejections = %d, 期望被摘除一次", stats.Instances[0].ID, stats.Instances[0].Ejected, stats.Instances[0].Ejections)
+	}
+}
+
+func TestTransportAllInstancesEjected(t *testing.T) {
+	transport := newTestTransport(t, &TransportConfig{FailureThreshold: 1},
+		statusServer(t, "bad", http.StatusServiceUnavailable))
+
+	// 唯一的实例被摘除后仍向其发送请求，避免服务整体不可用
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://api/orders", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("第 %d 次请求失败: %v", i+1, err)
+		}
+		resp.Body.Close()
+	}
+	if stats := transport.Stats()[0]; stats.Requests != 2 || stats.Instances[0].Ejections != 2 {
+		t.Errorf("Requests = %d, Ejections = %d, 期望 2, 2", stats.Requests, stats.Instances[0].Ejections)
+	}
+}
+
+func TestServiceNameOf(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://order-service/orders", want: "order-service"},
+		{url: "http://order-service:8080/orders"},
+		{url: "http://example.com/"},
+		{url: "http://localhost/"},
+		{url: "http://10.0.0.1/"},
+		{url: "http://[fd00::1]/"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		name, ok := serviceNameOf(req.URL)
+		if name != tt.want || ok != (tt.want != "") {
+			t.Errorf("serviceNameOf(%s) = %q, %v, 期望 %q", tt.url, name, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// instanceTokenHeader 携带注册时获得的实例令牌的请求头
+const instanceTokenHeader = "X-Instance-Token"
+
+var (
+	// ErrRevisionConflict 更新实例时实例已被其他请求修改，需重新获取实例后再更新
+	ErrRevisionConflict = errors.New("服务实例已被修改")
+	// ErrInvalidInstanceToken 实例令牌无效，通常是实例已被其他进程以相同 ID 重新注册
+	ErrInvalidInstanceToken = errors.New("实例令牌无效")
+)
+
+// MetadataUpdate 当前实例的部分更新，为 nil 的字段保持不变
+type MetadataUpdate struct {
+	// Metadata 需要修改的元数据，值为 nil 时删除该键
+	Metadata map[string]*string `json:"metadata,omitempty"`
+	Weight   *int               `json:"weight,omitempty"`
+	Version  *string            `json:"version,omitempty"`
+	// Revision 读取到的实例版本号，不为 0 且实例已被修改时返回 ErrRevisionConflict
+	Revision uint64 `json:"revision,omitempty"`
+}
+
+// UpdateMetadata 更新当前实例的元数据、权重和版本，无需重新注册。
+// 更新成功后同步修改客户端配置，之后重新注册时使用更新后的值
+func (c *Client) UpdateMetadata(ctx context.Context, update MetadataUpdate) (*Instance, error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/services/%s/%s",
+		url.PathEscape(c.config.ServiceName),
+		url.PathEscape(c.config.ServiceID),
+	) + c.namespaceQuery()
+	c.configMutex.Lock()
+	header := http.Header{instanceTokenHeader: {c.instanceToken}}
+	c.configMutex.Unlock()
+
+	resp, err := c.doWithHeader(ctx, http.MethodPatch, path, body, header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.statusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInstanceToken, string(resp.body))
+	case http.StatusConflict:
+		return nil, fmt.Errorf("%w: %s", ErrRevisionConflict, string(resp.body))
+	case http.StatusNotFound:
+		return nil, errInstanceNotFound
+	default:
+		return nil, &statusError{action: "更新服务实例失败", statusCode: resp.statusCode, body: string(resp.body)}
+	}
+
+	var result struct {
+		Service *Instance `json:"service"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	c.configMutex.Lock()
+	c.config.Metadata = result.Service.Metadata
+	c.config.Weight = result.Service.Weight
+	c.config.Version = result.Service.Version
+	c.configMutex.Unlock()
+	return result.Service, nil
+}
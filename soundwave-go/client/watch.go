@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"soundwave-go/api/registrypb"
+)
+
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = 30 * time.Second
+)
+
+// watchService 订阅服务实例变化直到 ctx 取消，断开后按指数退避重连。
+// 每次收到实例列表时调用 onUpdate，连接失败或中断时调用 onError
+func watchService(ctx context.Context, client registrypb.RegistryClient, namespace, name string, onUpdate func([]*registrypb.Instance), onError func(error)) {
+	backoff := watchMinBackoff
+	for {
+		err := watchOnce(ctx, client, namespace, name, func(instances []*registrypb.Instance) {
+			backoff = watchMinBackoff
+			onUpdate(instances)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		onError(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > watchMaxBackoff {
+			backoff = watchMaxBackoff
+		}
+	}
+}
+
+func watchOnce(ctx context.Context, client registrypb.RegistryClient, namespace, name string, onUpdate func([]*registrypb.Instance)) error {
+	stream, err := client.Watch(ctx, &registrypb.WatchRequest{Namespace: namespace, Name: name})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		onUpdate(resp.Instances)
+	}
+}
@@ -8,30 +8,26 @@ import (
 	"soundwave-go/internal/logger"
 	"soundwave-go/internal/models"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func InitializeData(db *mongo.Database, initData *config.InitData) error {
+// InitializeData 写入预设数据，只创建缺失的记录，不修改或删除已有数据
+func InitializeData(db *mongo.Database, cfg *config.Config, initData *config.InitData) error {
 	logger.InfoLogger.Println("开始初始化数据...")
 
-	// 清空现有数据
-	if err := clearCollections(db); err != nil {
-		logger.ErrorLogger.Printf("清空集合失败: %v", err)
-		return err
-	}
-	logger.InfoLogger.Println("清空集合完成")
-
 	// 初始化用户数据
-	if err := initUsers(db, initData.Users); err != nil {
+	if err := initUsers(db.Collection(cfg.MongoDB.Collections.Users), initData.Users); err != nil {
 		logger.ErrorLogger.Printf("初始化用户数据失败: %v", err)
 		return err
 	}
 	logger.InfoLogger.Println("初始化用户数据完成")
 
 	// 初始化菜单数据
-	if err := initMenus(db, initData.Menus); err != nil {
+	if err := initMenus(db.Collection(cfg.MongoDB.Collections.Menus), initData.Menus); err != nil {
 		logger.ErrorLogger.Printf("初始化菜单数据失败: %v", err)
 		return err
 	}
@@ -40,66 +36,81 @@ func InitializeData(db *mongo.Database, initData *config.InitData) error {
 	return nil
 }
 
-func clearCollections(db *mongo.Database) error {
-	collections := []string{"users", "menus"}
-	for _, name := range collections {
-		if err := db.Collection(name).Drop(context.Background()); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func initUsers(db *mongo.Database, users []config.UserConfig) error {
+func initUsers(coll *mongo.Collection, users []config.UserConfig) error {
 	if len(users) == 0 {
 		logger.WarnLogger.Println("没有用户数据需要初始化")
 		return nil
 	}
 
-	var documents []interface{}
+	ctx := context.Background()
+	created := 0
 	for _, user := range users {
+		// 已存在的用户保持不变，避免重启时覆盖用户修改过的密码和权限
+		count, err := coll.CountDocuments(ctx, bson.M{"username": user.Username})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return err
 		}
 
+		now := time.Now()
 		doc := models.User{
-			ID:          primitive.NewObjectID(),
-			Username:    user.Username,
-			Password:    string(hashedPassword),
-			Role:        user.Role,
-			Permissions: user.Permissions,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			ID:                primitive.NewObjectID(),
+			Username:          user.Username,
+			Password:          string(hashedPassword),
+			Role:              user.Role,
+			Permissions:       user.Permissions,
+			PasswordChangedAt: now,
+			CreatedAt:         now,
+			UpdatedAt:         now,
 		}
-		documents = append(documents, doc)
-		logger.InfoLogger.Printf("准备创建用户: %s, 角色: %s", user.Username, user.Role)
-	}
 
-	result, err := db.Collection("users").InsertMany(context.Background(), documents)
-	if err != nil {
-		return err
+		// 依赖 username 唯一索引，多实例同时启动时只有一个会插入成功
+		if _, err := coll.InsertOne(ctx, doc); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return err
+		}
+		created++
+		logger.InfoLogger.Printf("创建预设用户: %s, 角色: %s", user.Username, user.Role)
 	}
-	logger.InfoLogger.Printf("成功创建 %d 个用户", len(result.InsertedIDs))
+
+	logger.InfoLogger.Printf("成功创建 %d 个用户，跳过 %d 个已存在用户", created, len(users)-created)
 	return nil
 }
 
-func initMenus(db *mongo.Database, menus []config.MenuConfig) error {
+func initMenus(coll *mongo.Collection, menus []config.MenuConfig) error {
 	if len(menus) == 0 {
 		return nil
 	}
 
-	var documents []interface{}
+	ctx := context.Background()
 	for _, menu := range menus {
-		documents = append(documents, models.Menu{
+		doc := models.Menu{
 			Name:       menu.Name,
 			Path:       menu.Path,
 			Icon:       menu.Icon,
 			Permission: menu.Permission,
 			Sort:       menu.Sort,
-		})
+		}
+
+		// 按路径匹配，仅在菜单不存在时插入
+		_, err := coll.UpdateOne(ctx,
+			bson.M{"path": menu.Path},
+			bson.M{"$setOnInsert": doc},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
 	}
 
-	_, err := db.Collection("menus").InsertMany(context.Background(), documents)
-	return err
+	return nil
 }
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// migrationsCollection 记录已执行迁移的集合
+	migrationsCollection = "schema_migrations"
+	// migrationLockID 迁移锁文档ID，防止多个实例同时执行迁移
+	migrationLockID = "lock"
+	// migrationLockTTL 超过该时间的锁视为持有者已异常退出
+	migrationLockTTL = 10 * time.Minute
+	// migrationLockWait 等待其他实例释放迁移锁的最长时间，超过 TTL 后过期的锁可以被直接获取
+	migrationLockWait = migrationLockTTL
+	// migrationLockMinBackoff、migrationLockMaxBackoff 重试获取迁移锁的间隔范围
+	migrationLockMinBackoff = 500 * time.Millisecond
+	migrationLockMaxBackoff = 10 * time.Second
+)
+
+// errMigrationLocked 迁移锁被其他实例持有
+var errMigrationLocked = errors.New("其他实例正在执行数据库迁移")
+
+// Migration 数据库迁移，Version 全局唯一且递增，已发布的迁移不能修改
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database, cfg *config.Config) error
+}
+
+// MigrationRecord 已执行的迁移记录
+type MigrationRecord struct {
+	Version     int       `bson:"_id" json:"version"`
+	Description string    `bson:"description" json:"description"`
+	AppliedAt   time.Time `bson:"applied_at" json:"applied_at"`
+	Duration    string    `bson:"duration" json:"duration"`
+}
+
+// Migrate 按版本顺序执行尚未执行的迁移
+func Migrate(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+	coll := db.Collection(migrationsCollection)
+
+	if err := waitMigrationLock(ctx, db); err != nil {
+		return err
+	}
+	defer releaseMigrationLock(db)
+
+	// 获取锁后再读取迁移记录，等待期间其他实例可能已经执行了部分迁移
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	count := 0
+	for _, m := range sorted {
+		if applied[m.Version] {
+			continue
+		}
+
+		logger.InfoLogger.Printf("执行数据库迁移 %d: %s", m.Version, m.Description)
+		start := time.Now()
+		if err := m.Up(ctx, db, cfg); err != nil {
+			return fmt.Errorf("数据库迁移 %d 执行失败: %w", m.Version, err)
+		}
+
+		record := MigrationRecord{
+			Version:     m.Version,
+			Description: m.Description,
+			AppliedAt:   time.Now(),
+			Duration:    time.Since(start).String(),
+		}
+		if _, err := coll.InsertOne(ctx, record); err != nil {
+			return fmt.Errorf("保存迁移记录 %d 失败: %w", m.Version, err)
+		}
+		count++
+	}
+
+	if count == 0 {
+		logger.InfoLogger.Println("数据库已是最新版本")
+	} else {
+		logger.InfoLogger.Printf("数据库迁移完成，共执行 %d 个迁移", count)
+	}
+	return nil
+}
+
+// AppliedMigrations 返回已执行的迁移记录
+func AppliedMigrations(ctx context.Context, db *mongo.Database) ([]MigrationRecord, error) {
+	cursor, err := db.Collection(migrationsCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := make([]MigrationRecord, 0)
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func appliedMigrations(ctx context.Context, db *mongo.Database) (map[int]bool, error) {
+	records, err := AppliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+	return applied, nil
+}
+
+// waitMigrationLock 获取迁移锁，锁被其他实例持有时按指数退避重试，
+// 多个实例滚动发布时只有一个实例执行迁移，其余实例等待其完成
+func waitMigrationLock(ctx context.Context, db *mongo.Database) error {
+	deadline := time.Now().Add(migrationLockWait)
+	backoff := migrationLockMinBackoff
+	for {
+		err := acquireMigrationLock(ctx, db)
+		if !errors.Is(err, errMigrationLocked) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待迁移锁超时（%v）: %w", migrationLockWait, err)
+		}
+		if backoff == migrationLockMinBackoff {
+			logger.InfoLogger.Println("其他实例正在执行数据库迁移，等待迁移锁释放")
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("等待迁移锁失败: %w", ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > migrationLockMaxBackoff {
+			backoff = migrationLockMaxBackoff
+		}
+	}
+}
+
+func acquireMigrationLock(ctx context.Context, db *mongo.Database) error {
+	coll := db.Collection(migrationsCollection + "_lock")
+	hostname, _ := os.Hostname()
+	now := time.Now()
+
+	// 锁不存在或已过期时获取锁，否则 upsert 会因 _id 冲突失败
+	_, err := coll.UpdateOne(ctx,
+		bson.M{"_id": migrationLockID, "locked_at": bson.M{"$lt": now.Add(-migrationLockTTL)}},
+		bson.M{"$set": bson.M{"locked_at": now, "owner": fmt.Sprintf("%s-%d", hostname, os.Getpid())}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errMigrationLocked
+		}
+		return fmt.Errorf("获取迁移锁失败: %w", err)
+	}
+	return nil
+}
+
+func releaseMigrationLock(db *mongo.Database) {
+	coll := db.Collection(migrationsCollection + "_lock")
+	if _, err := coll.DeleteOne(context.Background(), bson.M{"_id": migrationLockID}); err != nil {
+		logger.ErrorLogger.Printf("释放迁移锁失败: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"soundwave-go/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations 所有数据库迁移，新增迁移追加到末尾并使用新的版本号
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "创建用户名唯一索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.Users), mongo.IndexModel{
+				Keys:    bson.D{{Key: "username", Value: 1}},
+				Options: options.Index().SetName("uniq_username").SetUnique(true),
+			})
+		},
+	},
+	{
+		Version:     2,
+		Description: "创建菜单路径唯一索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.Menus), mongo.IndexModel{
+				Keys:    bson.D{{Key: "path", Value: 1}},
+				Options: options.Index().SetName("uniq_path").SetUnique(true),
+			})
+		},
+	},
+	{
+		Version:     3,
+		Description: "创建外部身份源用户唯一索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.Users), mongo.IndexModel{
+				Keys: bson.D{{Key: "provider", Value: 1}, {Key: "external_id", Value: 1}},
+				Options: options.Index().SetName("uniq_provider_external_id").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"external_id": bson.M{"$exists": true}}),
+			})
+		},
+	},
+	{
+		Version:     4,
+		Description: "创建登录记录查询索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.LoginAttempts),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "created_at", Value: -1}},
+					Options: options.Index().SetName("idx_created_at"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "username", Value: 1}, {Key: "created_at", Value: -1}},
+					Options: options.Index().SetName("idx_username_created_at"),
+				},
+			)
+		},
+	},
+	{
+		Version:     5,
+		Description: "创建角色策略唯一索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.RolePolicies), mongo.IndexModel{
+				Keys:    bson.D{{Key: "role", Value: 1}},
+				Options: options.Index().SetName("uniq_role").SetUnique(true),
+			})
+		},
+	},
+	{
+		Version:     6,
+		Description: "为已有用户补充密码修改时间",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			_, err := db.Collection(cfg.MongoDB.Collections.Users).UpdateMany(ctx,
+				bson.M{"password_changed_at": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"password_changed_at": "$created_at"}}}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "创建路由规则唯一索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.TrafficRules), mongo.IndexModel{
+				Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "service", Value: 1}},
+				Options: options.Index().SetName("uniq_namespace_service").SetUnique(true),
+			})
+		},
+	},
+	{
+		Version:     8,
+		Description: "创建实例事件查询索引和TTL索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.ServiceEvents),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "time", Value: 1}},
+					Options: options.Index().SetName("ttl_time").SetExpireAfterSeconds(int32(cfg.Events.Retention / time.Second)),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "service", Value: 1}, {Key: "time", Value: -1}},
+					Options: options.Index().SetName("idx_namespace_service_time"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "service", Value: 1}, {Key: "instance_id", Value: 1}, {Key: "time", Value: -1}},
+					Options: options.Index().SetName("idx_namespace_service_instance_time"),
+				},
+			)
+		},
+	},
+	{
+		Version:     9,
+		Description: "创建服务历史数据查询索引和TTL索引",
+		Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+			return createIndexes(ctx, db.Collection(cfg.MongoDB.Collections.ServiceHistory),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "time", Value: 1}},
+					Options: options.Index().SetName("ttl_time").SetExpireAfterSeconds(int32(cfg.History.Retention / time.Second)),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "service", Value: 1}, {Key: "time", Value: 1}},
+					Options: options.Index().SetName("idx_namespace_service_time"),
+				},
+			)
+		},
+	},
+}
+
+func createIndexes(ctx context.Context, coll *mongo.Collection, indexes ...mongo.IndexModel) error {
+	_, err := coll.Indexes().CreateMany(ctx, indexes)
+	return err
+}
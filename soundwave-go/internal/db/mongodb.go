@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"soundwave-go/internal/logger"
+	"soundwave-go/internal/metrics"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -70,7 +71,7 @@ func (m *MongoDB) ListServices(result interface{}) error {
 func NewMongoDB(uri, database string) (*MongoDB, error) {
 	logger.InfoLogger.Printf("连接 MongoDB: %s, 数据库: %s", uri, database)
 
-	clientOptions := options.Client().ApplyURI(uri)
+	clientOptions := options.Client().ApplyURI(uri).SetMonitor(metrics.NewMongoMonitor())
 	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
 		logger.ErrorLogger.Printf("MongoDB 连接失败: %v", err)
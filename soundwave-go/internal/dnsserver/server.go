@@ -0,0 +1,363 @@
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/registry"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// serviceLabel 服务查询的固定标签，<service>.service[.<namespace>].<domain>
+	serviceLabel = "service"
+	// instanceLabel SRV 记录目标使用的标签，<id>.<service>.instance[.<namespace>].<domain>
+	instanceLabel = "instance"
+)
+
+// Server 内置 DNS 服务，将注册中心中健康的服务实例以 DNS 记录的形式提供
+type Server struct {
+	config   config.DNSConfig
+	domain   string
+	registry *registry.ServiceRegistry
+	servers  []*dns.Server
+	addr     string
+}
+
+func NewServer(cfg config.DNSConfig, reg *registry.ServiceRegistry) *Server {
+	return &Server{
+		config:   cfg,
+		domain:   dns.Fqdn(strings.ToLower(cfg.Domain)),
+		registry: reg,
+	}
+}
+
+// Start 同时监听 UDP 和 TCP，监听失败时返回错误
+func (s *Server) Start() error {
+	packetConn, err := net.ListenPacket("udp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("监听DNS UDP端口失败: %w", err)
+	}
+	// TCP 监听与 UDP 相同的地址，端口为 0 时两者使用同一个随机端口
+	listener, err := net.Listen("tcp", packetConn.LocalAddr().String())
+	if err != nil {
+		packetConn.Close()
+		return fmt.Errorf("监听DNS TCP端口失败: %w", err)
+	}
+
+	s.servers = []*dns.Server{
+		{PacketConn: packetConn, Handler: s},
+		{Listener: listener, Handler: s},
+	}
+	for _, server := range s.servers {
+		go func(server *dns.Server) {
+			if err := server.ActivateAndServe(); err != nil {
+				logger.ErrorLogger.Printf("DNS服务异常退出: %v", err)
+			}
+		}(server)
+	}
+
+	s.addr = packetConn.LocalAddr().String()
+	logger.InfoLogger.Printf("DNS服务启动，监听地址：%s，域名：%s", s.addr, s.domain)
+	return nil
+}
+
+// Addr 返回实际监听的地址，未启动时返回配置的地址
+func (s *Server) Addr() string {
+	if s.addr == "" {
+		return s.config.Addr
+	}
+	return s.addr
+}
+
+// Shutdown 停止 DNS 服务
+func (s *Server) Shutdown() {
+	for _, server := range s.servers {
+		if err := server.Shutdown(); err != nil {
+			logger.ErrorLogger.Printf("关闭DNS服务失败: %v", err)
+		}
+	}
+}
+
+// ServeDNS 处理 DNS 查询
+func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) > 0 {
+		s.answer(m, r.Question[0])
+	}
+
+	// UDP 应答超过客户端可接收的大小时截断，客户端会改用 TCP 重试
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		size := dns.MinMsgSize
+		if opt := r.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		m.Truncate(size)
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		logger.ErrorLogger.Printf("发送DNS应答失败: %v", err)
+	}
+}
+
+// answer 根据查询名称填充应答
+func (s *Server) answer(m *dns.Msg, q dns.Question) {
+	name := strings.ToLower(q.Name)
+	if !dns.IsSubDomain(s.domain, name) {
+		m.Rcode = dns.RcodeRefused
+		return
+	}
+
+	// 去掉域名后缀后按标签从右向左解析
+	labels := dns.SplitDomainName(strings.TrimSuffix(name, s.domain))
+	if len(labels) < 2 {
+		s.nxdomain(m)
+		return
+	}
+
+	// 固定标签之后可以带命名空间，未带时查询默认命名空间
+	namespace := registry.DefaultNamespace
+	if kind := labels[len(labels)-1]; kind != serviceLabel && kind != instanceLabel && len(labels) >= 3 {
+		namespace = kind
+		labels = labels[:len(labels)-1]
+	}
+	// DNS 查询方不需要登录，受限的命名空间视为不存在
+	if s.registry.NamespaceRestricted(namespace) {
+		s.nxdomain(m)
+		return
+	}
+
+	switch labels[len(labels)-1] {
+	case serviceLabel:
+		s.answerService(m, q, namespace, labels[:len(labels)-1])
+	case instanceLabel:
+		s.answerInstance(m, q, namespace, labels[:len(labels)-1])
+	default:
+		s.nxdomain(m)
+	}
+}
+
+// answerService 处理 [<tag或版本>.]<service>.service.<domain> 和 _<service>._tcp.service.<domain> 查询
+func (s *Server) answerService(m *dns.Msg, q dns.Question, namespace string, labels []string) {
+	var serviceName, filter string
+	if len(labels) == 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_") {
+		// RFC 2782 格式
+		serviceName = strings.TrimPrefix(labels[0], "_")
+	} else {
+		serviceName = labels[len(labels)-1]
+		filter = strings.Join(labels[:len(labels)-1], ".")
+	}
+
+	instances, err := s.instances(namespace, serviceName)
+	if err != nil {
+		if errors.Is(err, registry.ErrServiceNotFound) {
+			s.nxdomain(m)
+			return
+		}
+		// 服务存在但没有健康实例时返回空应答
+		s.nodata(m)
+		return
+	}
+
+	matched := make([]*registry.Service, 0, len(instances))
+	for _, instance := range instances {
+		if filter == "" || matchFilter(instance, filter) {
+			matched = append(matched, instance)
+		}
+	}
+	if len(matched) == 0 {
+		s.nodata(m)
+		return
+	}
+
+	for _, instance := range matched {
+		switch q.Qtype {
+		case dns.TypeA, dns.TypeAAAA, dns.TypeANY:
+			if rr := s.addressRecord(q.Name, instance, q.Qtype); rr != nil {
+				m.Answer = append(m.Answer, rr)
+			}
+		case dns.TypeSRV:
+			target := s.instanceName(instance)
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:      s.header(q.Name, dns.TypeSRV),
+				Priority: 1,
+				Weight:   srvWeight(instance),
+				Port:     uint16(instance.Port),
+				Target:   target,
+			})
+			if rr := s.addressRecord(target, instance, dns.TypeANY); rr != nil {
+				m.Extra = append(m.Extra, rr)
+			}
+		case dns.TypeTXT:
+			m.Answer = append(m.Answer, s.txtRecord(q.Name, instance))
+		}
+	}
+
+	if len(m.Answer) == 0 {
+		s.nodata(m)
+	}
+}
+
+// answerInstance 处理 SRV 记录目标 <id>.<service>.instance.<domain> 的地址查询
+func (s *Server) answerInstance(m *dns.Msg, q dns.Question, namespace string, labels []string) {
+	if len(labels) != 2 {
+		s.nxdomain(m)
+		return
+	}
+
+	instances, err := s.instances(namespace, labels[1])
+	if err != nil {
+		s.nxdomain(m)
+		return
+	}
+
+	for _, instance := range instances {
+		if strings.ToLower(instanceLabelOf(instance)) != labels[0] {
+			continue
+		}
+		switch q.Qtype {
+		case dns.TypeTXT:
+			m.Answer = append(m.Answer, s.txtRecord(q.Name, instance))
+		default:
+			if rr := s.addressRecord(q.Name, instance, q.Qtype); rr != nil {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+		if len(m.Answer) == 0 {
+			s.nodata(m)
+		}
+		return
+	}
+
+	s.nxdomain(m)
+}
+
+// instances 获取服务的健康实例，DNS 名称不区分大小写，查询名称已转为小写，
+// 需按不区分大小写的方式找到注册时使用的服务名称
+func (s *Server) instances(namespace, name string) ([]*registry.Service, error) {
+	if resolved, ok := s.registry.ResolveServiceName(namespace, name); ok {
+		name = resolved
+	}
+	return s.registry.GetService(namespace, name)
+}
+
+// addressRecord 根据实例 IP 类型返回 A 或 AAAA 记录，与查询类型不符时返回 nil
+func (s *Server) addressRecord(name string, instance *registry.Service, qtype uint16) dns.RR {
+	ip := net.ParseIP(instance.IP)
+	if ip == nil {
+		return nil
+	}
+
+	if ipv4 := ip.To4(); ipv4 != nil {
+		if qtype != dns.TypeA && qtype != dns.TypeANY {
+			return nil
+		}
+		return &dns.A{Hdr: s.header(name, dns.TypeA), A: ipv4}
+	}
+	if qtype != dns.TypeAAAA && qtype != dns.TypeANY {
+		return nil
+	}
+	return &dns.AAAA{Hdr: s.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+func (s *Server) txtRecord(name string, instance *registry.Service) dns.RR {
+	return &dns.TXT{
+		Hdr: s.header(name, dns.TypeTXT),
+		Txt: []string{"id=" + instance.ID, "version=" + instance.Version},
+	}
+}
+
+func (s *Server) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{
+		Name:   name,
+		Rrtype: rrtype,
+		Class:  dns.ClassINET,
+		Ttl:    s.ttl(),
+	}
+}
+
+func (s *Server) ttl() uint32 {
+	return uint32(s.config.TTL / time.Second)
+}
+
+// instanceName 返回实例在 SRV 记录中的目标域名，非默认命名空间的实例带命名空间标签
+func (s *Server) instanceName(instance *registry.Service) string {
+	if instance.Namespace != "" && instance.Namespace != registry.DefaultNamespace {
+		return fmt.Sprintf("%s.%s.%s.%s.%s", instanceLabelOf(instance), instance.Name, instanceLabel, instance.Namespace, s.domain)
+	}
+	return fmt.Sprintf("%s.%s.%s.%s", instanceLabelOf(instance), instance.Name, instanceLabel, s.domain)
+}
+
+// nxdomain 名称不存在
+func (s *Server) nxdomain(m *dns.Msg) {
+	m.Rcode = dns.RcodeNameError
+	m.Ns = append(m.Ns, s.soa())
+}
+
+// nodata 名称存在但没有对应类型的记录
+func (s *Server) nodata(m *dns.Msg) {
+	m.Answer = nil
+	m.Extra = nil
+	m.Ns = append(m.Ns, s.soa())
+}
+
+// soa 否定应答携带 SOA 记录，解析器据此缓存否定结果
+func (s *Server) soa() dns.RR {
+	return &dns.SOA{
+		Hdr:     s.header(s.domain, dns.TypeSOA),
+		Ns:      "ns." + s.domain,
+		Mbox:    "hostmaster." + s.domain,
+		Serial:  uint32(time.Now().Unix()),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  s.ttl(),
+	}
+}
+
+// matchFilter 子域名可以是实例版本（可带 v 前缀）或实例的标签，DNS 名称不区分大小写
+func matchFilter(instance *registry.Service, filter string) bool {
+	version := strings.TrimPrefix(strings.ToLower(instance.Version), "v")
+	if version != "" && strings.TrimPrefix(filter, "v") == version {
+		return true
+	}
+	for _, tag := range instance.Tags {
+		if strings.EqualFold(tag, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// srvWeight SRV 权重为 0 表示几乎不被选中，未设置权重的实例使用默认值 1
+func srvWeight(instance *registry.Service) uint16 {
+	if instance.Weight <= 0 {
+		return 1
+	}
+	if instance.Weight > 65535 {
+		return 65535
+	}
+	return uint16(instance.Weight)
+}
+
+// instanceLabelOf 将实例 ID 转换为合法的 DNS 标签
+func instanceLabelOf(instance *registry.Service) string {
+	var b strings.Builder
+	for _, r := range instance.ID {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,274 @@
+package dnsserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/registry"
+
+	"github.com/miekg/dns"
+)
+
+// startTestServer 在随机端口启动 DNS 服务并注册测试实例
+func startTestServer(t *testing.T, instances ...*registry.Service) *Server {
+	t.Helper()
+
+	return startRegistryServer(t, registry.NewServiceRegistry(), instances...)
+}
+
+// startRegistryServer 向 reg 注册实例并启动测试服务
+func startRegistryServer(t *testing.T, reg *registry.ServiceRegistry, instances ...*registry.Service) *Server {
+	t.Helper()
+
+	for _, instance := range instances {
+		if err := reg.RegisterService(instance); err != nil {
+			t.Fatalf("注册实例 %s 失败: %v", instance.ID, err)
+		}
+	}
+
+	s := NewServer(config.DNSConfig{Addr: "127.0.0.1:0", Domain: "Soundwave", TTL: 5 * time.Second}, reg)
+	if err := s.Start(); err != nil {
+		t.Fatalf("启动DNS服务失败: %v", err)
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+// query 向测试服务发送查询，net 为 udp 或 tcp
+func query(t *testing.T, s *Server, net, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+
+	client := &dns.Client{Net: net, Timeout: 2 * time.Second}
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	resp, _, err := client.Exchange(m, s.Addr())
+	if err != nil {
+		t.Fatalf("查询 %s %s 失败: %v", name, dns.TypeToString[qtype], err)
+	}
+	return resp
+}
+
+// answers 将应答记录格式化为便于比较的字符串，去掉 TTL 等无关字段并排序
+func answers(rrs []dns.RR) []string {
+	result := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		switch v := rr.(type) {
+		case *dns.A:
+			result = append(result, "A "+v.A.String())
+		case *dns.AAAA:
+			result = append(result, "AAAA "+v.AAAA.String())
+		case *dns.SRV:
+			result = append(result, fmt.Sprintf("SRV %d %d %s", v.Weight, v.Port, v.Target))
+		case *dns.TXT:
+			result = append(result, "TXT "+strings.Join(v.Txt, " "))
+		default:
+			result = append(result, dns.TypeToString[rr.Header().Rrtype])
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func testInstances() []*registry.Service {
+	return []*registry.Service{
+		{Name: "api", ID: "api-1", Hostname: "host-1", IP: "10.0.0.1", Port: 8080, Version: "v2.1.0", Weight: 10,
+			Tags: []string{"canary", "Blue"}},
+		{Name: "api", ID: "api-2", Hostname: "host-2", IP: "10.0.0.2", Port: 8080, Version: "1.0.0",
+			Metadata: map[string]string{"tags": "green"}},
+		{Name: "ipv6", ID: "v6_1", Hostname: "host-3", IP: "fd00::1", Port: 9090},
+		{Name: "api", ID: "staging-1", Hostname: "host-4", IP: "10.1.0.1", Port: 8081, Namespace: "staging"},
+		{Name: "PaymentGateway", ID: "pay-1", Hostname: "host-5", IP: "10.0.0.5", Port: 7000},
+		{Name: "booting", ID: "boot-1", Hostname: "host-6", IP: "10.0.0.6", Port: 7000, Status: registry.StatusStarting},
+	}
+}
+
+func TestServeRecords(t *testing.T) {
+	s := startTestServer(t, testInstances()...)
+
+	tests := []struct {
+		name  string
+		qname string
+		qtype uint16
+		want  []string
+		extra []string
+	}{
+		{name: "A记录", qname: "api.service.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.1", "A 10.0.0.2"}},
+		{name: "AAAA记录", qname: "ipv6.service.soundwave.", qtype: dns.TypeAAAA, want: []string{"AAAA fd00::1"}},
+		{
+			name:  "SRV记录及附加地址",
+			qname: "api.service.soundwave.",
+			qtype: dns.TypeSRV,
+			want: []string{
+				"SRV 1 8080 api-2.api.instance.soundwave.",
+				"SRV 10 8080 api-1.api.instance.soundwave.",
+			},
+			extra: []string{"A 10.0.0.1", "A 10.0.0.2"},
+		},
+		{
+			name:  "RFC 2782 格式的SRV查询",
+			qname: "_api._tcp.service.soundwave.",
+			qtype: dns.TypeSRV,
+			want: []string{
+				"SRV 1 8080 api-2.api.instance.soundwave.",
+				"SRV 10 8080 api-1.api.instance.soundwave.",
+			},
+			extra: []string{"A 10.0.0.1", "A 10.0.0.2"},
+		},
+		{name: "TXT记录", qname: "api.service.soundwave.", qtype: dns.TypeTXT, want: []string{"TXT id=api-1 version=v2.1.0", "TXT id=api-2 version=1.0.0"}},
+		{name: "命名空间子域名", qname: "api.service.staging.soundwave.", qtype: dns.TypeA, want: []string{"A 10.1.0.1"}},
+		{
+			name:  "非默认命名空间的SRV目标带命名空间",
+			qname: "api.service.staging.soundwave.",
+			qtype: dns.TypeSRV,
+			want:  []string{"SRV 1 8081 staging-1.api.instance.staging.soundwave."},
+			extra: []string{"A 10.1.0.1"},
+		},
+		{name: "标签子域名", qname: "canary.api.service.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.1"}},
+		{name: "标签不区分大小写", qname: "blue.api.service.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.1"}},
+		{name: "版本子域名", qname: "v1.0.0.api.service.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.2"}},
+		{name: "版本子域名不带v前缀", qname: "2.1.0.api.service.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.1"}},
+		{name: "SRV目标地址", qname: "api-1.api.instance.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.1"}},
+		{name: "SRV目标实例ID转换为合法标签", qname: "v6-1.ipv6.instance.soundwave.", qtype: dns.TypeAAAA, want: []string{"AAAA fd00::1"}},
+		{name: "命名空间中的SRV目标地址", qname: "staging-1.api.instance.staging.soundwave.", qtype: dns.TypeA, want: []string{"A 10.1.0.1"}},
+		{name: "查询名称不区分大小写", qname: "API.Service.SoundWave.", qtype: dns.TypeA, want: []string{"A 10.0.0.1", "A 10.0.0.2"}},
+		{name: "大写服务名称", qname: "paymentgateway.service.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.5"}},
+		{name: "大写服务名称的SRV目标", qname: "pay-1.PaymentGateway.instance.soundwave.", qtype: dns.TypeA, want: []string{"A 10.0.0.5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := query(t, s, "udp", tt.qname, tt.qtype)
+			if resp.Rcode != dns.RcodeSuccess {
+				t.Fatalf("Rcode = %s, 期望 NOERROR", dns.RcodeToString[resp.Rcode])
+			}
+			if !resp.Authoritative {
+				t.Error("应答应为权威应答")
+			}
+			if got := answers(resp.Answer); strings.Join(got, ";") != strings.Join(tt.want, ";") {
+				t.Errorf("Answer = %v, 期望 %v", got, tt.want)
+			}
+			if got := answers(resp.Extra); strings.Join(got, ";") != strings.Join(tt.extra, ";") {
+				t.Errorf("Extra = %v, 期望 %v", got, tt.extra)
+			}
+			for _, rr := range resp.Answer {
+				if rr.Header().Ttl != 5 {
+					t.Errorf("TTL = %d, 期望 5", rr.Header().Ttl)
+				}
+			}
+		})
+	}
+}
+
+func TestServeNegativeAnswers(t *testing.T) {
+	s := startTestServer(t, testInstances()...)
+
+	tests := []struct {
+		name  string
+		qname string
+		qtype uint16
+		rcode int
+		soa   bool
+	}{
+		{name: "服务不存在", qname: "missing.service.soundwave.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{name: "命名空间不存在", qname: "api.service.missing.soundwave.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{name: "未知的固定标签", qname: "api.unknown.soundwave.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{name: "缺少固定标签", qname: "soundwave.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{name: "实例不存在", qname: "api-9.api.instance.soundwave.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{name: "IPv4实例查询AAAA", qname: "api.service.soundwave.", qtype: dns.TypeAAAA, rcode: dns.RcodeSuccess, soa: true},
+		{name: "元数据中的tags不作为标签", qname: "green.api.service.soundwave.", qtype: dns.TypeA, rcode: dns.RcodeSuccess, soa: true},
+		{name: "没有可用实例", qname: "booting.service.soundwave.", qtype: dns.TypeA, rcode: dns.RcodeSuccess, soa: true},
+		{name: "IPv4实例地址查询AAAA", qname: "api-1.api.instance.soundwave.", qtype: dns.TypeAAAA, rcode: dns.RcodeSuccess, soa: true},
+		{name: "不属于服务域名", qname: "example.com.", qtype: dns.TypeA, rcode: dns.RcodeRefused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := query(t, s, "udp", tt.qname, tt.qtype)
+			if resp.Rcode != tt.rcode {
+				t.Fatalf("Rcode = %s, 期望 %s", dns.RcodeToString[resp.Rcode], dns.RcodeToString[tt.rcode])
+			}
+			if len(resp.Answer) != 0 || len(resp.Extra) != 0 {
+				t.Errorf("否定应答不应包含记录: Answer=%v Extra=%v", resp.Answer, resp.Extra)
+			}
+			if !tt.soa {
+				return
+			}
+			if len(resp.Ns) != 1 {
+				t.Fatalf("Ns = %v, 期望一条 SOA 记录", resp.Ns)
+			}
+			soa, ok := resp.Ns[0].(*dns.SOA)
+			if !ok {
+				t.Fatalf("Ns[0] = %v, 期望 SOA 记录", resp.Ns[0])
+			}
+			if soa.Hdr.Name != "soundwave." || soa.Minttl != 5 {
+				t.Errorf("SOA = %v", soa)
+			}
+		})
+	}
+}
+
+func TestServeHidesRestrictedNamespaces(t *testing.T) {
+	s := startRegistryServer(t, registry.NewServiceRegistry(registry.WithRestrictedNamespaces([]string{"staging"})), testInstances()...)
+
+	for _, qname := range []string{"api.service.staging.soundwave.", "staging-1.api.instance.staging.soundwave."} {
+		if resp := query(t, s, "udp", qname, dns.TypeA); resp.Rcode != dns.RcodeNameError || len(resp.Answer) != 0 {
+			t.Errorf("查询 %s 得到 Rcode = %s, Answer = %v, 期望 NXDOMAIN", qname, dns.RcodeToString[resp.Rcode], resp.Answer)
+		}
+	}
+	if resp := query(t, s, "udp", "api.service.soundwave.", dns.TypeA); len(resp.Answer) == 0 {
+		t.Error("未受限的命名空间应正常应答")
+	}
+}
+
+func TestServeTruncatesLargeUDPResponses(t *testing.T) {
+	instances := make([]*registry.Service, 0, 40)
+	for i := 0; i < 40; i++ {
+		instances = append(instances, &registry.Service{
+			Name:     "large",
+			ID:       fmt.Sprintf("large-%d", i),
+			Hostname: fmt.Sprintf("host-%d", i),
+			IP:       fmt.Sprintf("10.2.0.%d", i+1),
+			Port:     8080,
+		})
+	}
+	s := startTestServer(t, instances...)
+
+	udp := query(t, s, "udp", "large.service.soundwave.", dns.TypeA)
+	if !udp.Truncated {
+		t.Fatal("超过512字节的UDP应答应设置TC标志")
+	}
+	if len(udp.Answer) >= 40 {
+		t.Errorf("截断后的UDP应答包含 %d 条记录", len(udp.Answer))
+	}
+	// 截断时会启用名称压缩，按压缩后的大小比较
+	udp.Compress = true
+	if udp.Len() > dns.MinMsgSize {
+		t.Errorf("截断后的UDP应答大小 %d 超过 %d", udp.Len(), dns.MinMsgSize)
+	}
+
+	// 客户端收到截断应答后改用 TCP 重试
+	tcp := query(t, s, "tcp", "large.service.soundwave.", dns.TypeA)
+	if tcp.Truncated {
+		t.Error("TCP应答不应截断")
+	}
+	if len(tcp.Answer) != 40 {
+		t.Errorf("TCP应答包含 %d 条记录, 期望 40", len(tcp.Answer))
+	}
+
+	// 声明了 EDNS0 缓冲区大小的 UDP 查询按声明的大小截断
+	client := &dns.Client{Net: "udp", Timeout: 2 * time.Second}
+	m := new(dns.Msg)
+	m.SetQuestion("large.service.soundwave.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	edns, _, err := client.Exchange(m, s.Addr())
+	if err != nil {
+		t.Fatalf("EDNS0查询失败: %v", err)
+	}
+	if edns.Truncated || len(edns.Answer) != 40 {
+		t.Errorf("EDNS0应答 Truncated=%v，包含 %d 条记录, 期望 40 条且未截断", edns.Truncated, len(edns.Answer))
+	}
+}
@@ -1,18 +1,153 @@
 package errors
 
-import "fmt"
+import (
+	stderrors "errors"
+	"net/http"
+)
+
+// Kind 错误类别，决定返回的 HTTP 状态码
+type Kind int
 
+const (
+	// KindInternal 服务器内部错误
+	KindInternal Kind = iota
+	// KindValidation 请求参数校验失败
+	KindValidation
+	// KindUnauthorized 未认证或认证失败
+	KindUnauthorized
+	// KindForbidden 没有权限执行操作
+	KindForbidden
+	// KindNotFound 资源不存在
+	KindNotFound
+	// KindConflict 资源状态冲突
+	KindConflict
+	// KindLocked 资源已锁定
+	KindLocked
+	// KindUnavailable 依赖的资源暂不可用
+	KindUnavailable
+)
+
+// HTTPStatus 返回错误类别对应的 HTTP 状态码
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindLocked:
+		return http.StatusLocked
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ServiceError 业务错误，Code 为机器可读的错误码，Message 可直接展示给用户
 type ServiceError struct {
-	Code    int
+	Kind    Kind
+	Code    string
 	Message string
+	// Err 底层错误，仅用于日志，不返回给调用方
+	Err error
 }
 
 func (e *ServiceError) Error() string {
-	return fmt.Sprintf("错误码: %d, 错误信息: %s", e.Code, e.Message)
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// Is 错误码相同即视为同一错误，便于对 WithMessage、Wrap 生成的错误使用 errors.Is 判断
+func (e *ServiceError) Is(target error) bool {
+	t, ok := target.(*ServiceError)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage 返回错误码相同、提示信息不同的错误
+func (e *ServiceError) WithMessage(message string) *ServiceError {
+	err := *e
+	err.Message = message
+	return &err
+}
+
+// Wrap 返回附带底层错误的副本
+func (e *ServiceError) Wrap(cause error) *ServiceError {
+	err := *e
+	err.Err = cause
+	return &err
+}
+
+// New 创建业务错误
+func New(kind Kind, code, message string) *ServiceError {
+	return &ServiceError{Kind: kind, Code: code, Message: message}
+}
+
+// Validation 创建参数校验错误
+func Validation(code, message string) *ServiceError {
+	return New(KindValidation, code, message)
+}
+
+// Unauthorized 创建认证失败错误
+func Unauthorized(code, message string) *ServiceError {
+	return New(KindUnauthorized, code, message)
+}
+
+// Forbidden 创建权限不足错误
+func Forbidden(code, message string) *ServiceError {
+	return New(KindForbidden, code, message)
+}
+
+// NotFound 创建资源不存在错误
+func NotFound(code, message string) *ServiceError {
+	return New(KindNotFound, code, message)
+}
+
+// Conflict 创建资源冲突错误
+func Conflict(code, message string) *ServiceError {
+	return New(KindConflict, code, message)
 }
 
+// Locked 创建资源锁定错误
+func Locked(code, message string) *ServiceError {
+	return New(KindLocked, code, message)
+}
+
+// Unavailable 创建资源不可用错误
+func Unavailable(code, message string) *ServiceError {
+	return New(KindUnavailable, code, message)
+}
+
+// 通用错误
 var (
-	ErrServiceNotFound    = &ServiceError{Code: 404, Message: "服务不存在"}
-	ErrServiceUnavailable = &ServiceError{Code: 503, Message: "服务不可用"}
-	ErrInvalidRequest     = &ServiceError{Code: 400, Message: "无效的请求参数"}
+	ErrInvalidRequest = Validation("INVALID_REQUEST", "无效的请求参数")
+	ErrUnauthorized   = Unauthorized("UNAUTHORIZED", "未授权访问")
+	ErrForbidden      = Forbidden("FORBIDDEN", "没有访问权限")
+	ErrNotFound       = NotFound("NOT_FOUND", "请求的资源不存在")
+	ErrInternal       = New(KindInternal, "INTERNAL_ERROR", "服务器内部错误")
 )
+
+// InvalidRequest 请求体或查询参数解析失败
+func InvalidRequest(err error) *ServiceError {
+	return ErrInvalidRequest.WithMessage(ErrInvalidRequest.Message + ": " + err.Error())
+}
+
+// From 将任意错误转换为 ServiceError，未分类的错误视为内部错误
+func From(err error) *ServiceError {
+	var serviceErr *ServiceError
+	if stderrors.As(err, &serviceErr) {
+		return serviceErr
+	}
+	return ErrInternal.Wrap(err)
+}
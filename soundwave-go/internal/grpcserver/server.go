@@ -0,0 +1,288 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"soundwave-go/api/registrypb"
+	"soundwave-go/internal/config"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/registry"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server 注册中心的 gRPC 接口
+type Server struct {
+	registrypb.UnimplementedRegistryServer
+
+	config   config.GRPCConfig
+	registry *registry.ServiceRegistry
+	server   *grpc.Server
+}
+
+func NewServer(cfg config.GRPCConfig, reg *registry.ServiceRegistry) *Server {
+	s := &Server{
+		config:   cfg,
+		registry: reg,
+		server:   grpc.NewServer(),
+	}
+	registrypb.RegisterRegistryServer(s.server, s)
+	return s
+}
+
+// Start 监听端口并在后台提供服务，监听失败时返回错误
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("监听gRPC端口失败: %w", err)
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			logger.ErrorLogger.Printf("gRPC服务异常退出: %v", err)
+		}
+	}()
+
+	logger.InfoLogger.Printf("gRPC服务启动，监听地址：%s", s.config.Addr)
+	return nil
+}
+
+// Shutdown 关闭所有连接，Watch 等长连接无法等待其自然结束
+func (s *Server) Shutdown() {
+	s.server.Stop()
+}
+
+// Register 注册服务实例
+func (s *Server) Register(ctx context.Context, req *registrypb.RegisterRequest) (*registrypb.RegisterResponse, error) {
+	if req.Instance == nil {
+		return nil, toStatus(apperrors.ErrInvalidRequest.WithMessage("实例信息不能为空"))
+	}
+
+	service := &registry.Service{
+		Namespace: req.Instance.Namespace,
+		Name:      req.Instance.Name,
+		ID:        req.Instance.Id,
+		Hostname:  req.Instance.Hostname,
+		IP:        req.Instance.Ip,
+		Port:      int(req.Instance.Port),
+		Metadata:  req.Instance.Metadata,
+		Weight:    int(req.Instance.Weight),
+		Version:   req.Instance.Version,
+		Status:    registry.ServiceStatus(strings.ToUpper(req.Instance.Status)),
+		Tags:      req.Instance.Tags,
+		Region:    req.Instance.Region,
+		Zone:      req.Instance.Zone,
+	}
+	if err := s.registry.RegisterService(service); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &registrypb.RegisterResponse{Instance: toInstance(service)}, nil
+}
+
+// Heartbeat 处理心跳流，单次心跳失败不会中断流
+func (s *Server) Heartbeat(stream registrypb.Registry_HeartbeatServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := &registrypb.HeartbeatResponse{
+			Name:      req.Name,
+			Id:        req.Id,
+			Namespace: req.Namespace,
+			Ok:        true,
+		}
+		namespace, err := registry.NormalizeNamespace(req.Namespace)
+		if err == nil {
+			report := &registry.HeartbeatReport{Status: registry.ServiceStatus(strings.ToUpper(req.Status))}
+			if req.ReportLoad {
+				report.Load = &registry.LoadReport{InFlight: int(req.InFlight), LatencyMs: req.LatencyMs}
+			}
+			if len(req.Checks) > 0 {
+				report.Checks = make(map[string]registry.DependencyHealth, len(req.Checks))
+				for name, check := range req.Checks {
+					report.Checks[name] = registry.DependencyHealth{
+						Status:    registry.ServiceStatus(strings.ToUpper(check.Status)),
+						Message:   check.Message,
+						LatencyMs: check.LatencyMs,
+					}
+				}
+			}
+			err = s.registry.UpdateHeartbeat(namespace, req.Name, req.Id, report)
+		}
+		if err != nil {
+			serviceErr := apperrors.From(err)
+			resp.Ok = false
+			resp.Code = serviceErr.Code
+			resp.Message = serviceErr.Message
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// Deregister 注销服务实例
+func (s *Server) Deregister(ctx context.Context, req *registrypb.DeregisterRequest) (*registrypb.DeregisterResponse, error) {
+	namespace, err := registry.NormalizeNamespace(req.Namespace)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.registry.DeregisterService(namespace, req.Name, req.Id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &registrypb.DeregisterResponse{}, nil
+}
+
+// Discover 获取服务的健康实例
+func (s *Server) Discover(ctx context.Context, req *registrypb.DiscoverRequest) (*registrypb.DiscoverResponse, error) {
+	namespace, err := readableNamespace(s.registry, req.Namespace)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	query, err := registry.ParseInstanceQuery(req.Tags, req.Selector, req.Version, req.Zone)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	services, err := s.registry.FindServices(namespace, req.Name, query)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &registrypb.DiscoverResponse{Instances: toInstances(services)}, nil
+}
+
+// Watch 推送服务的健康实例列表，服务不存在或没有健康实例时推送空列表
+func (s *Server) Watch(req *registrypb.WatchRequest, stream registrypb.Registry_WatchServer) error {
+	if req.Name == "" {
+		return toStatus(apperrors.ErrInvalidRequest.WithMessage("服务名称不能为空"))
+	}
+	namespace, err := readableNamespace(s.registry, req.Namespace)
+	if err != nil {
+		return toStatus(err)
+	}
+	query, err := registry.ParseInstanceQuery(req.Tags, req.Selector, req.Version, req.Zone)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	// 先订阅再发送快照，避免两者之间的变化丢失
+	events, cancel := s.registry.Subscribe(namespace, req.Name)
+	defer cancel()
+
+	if err := s.sendSnapshot(stream, namespace, req.Name, query); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case _, ok := <-events:
+			if !ok {
+				return nil
+			}
+			// 合并已积压的事件，只发送一次最新快照
+			for drained := false; !drained; {
+				select {
+				case <-events:
+				default:
+					drained = true
+				}
+			}
+			if err := s.sendSnapshot(stream, namespace, req.Name, query); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readableNamespace 校验命名空间名称，gRPC 调用方不需要登录，不能读取受限的命名空间
+func readableNamespace(reg *registry.ServiceRegistry, name string) (string, error) {
+	namespace, err := registry.NormalizeNamespace(name)
+	if err != nil {
+		return "", err
+	}
+	if reg.NamespaceRestricted(namespace) {
+		return "", registry.ErrNamespaceRestricted
+	}
+	return namespace, nil
+}
+
+func (s *Server) sendSnapshot(stream registrypb.Registry_WatchServer, namespace, name string, query *registry.InstanceQuery) error {
+	services, err := s.registry.FindServices(namespace, name, query)
+	if err != nil && !errors.Is(err, registry.ErrServiceNotFound) && !errors.Is(err, registry.ErrNoAvailableInstance) {
+		return toStatus(err)
+	}
+	return stream.Send(&registrypb.WatchResponse{Instances: toInstances(services)})
+}
+
+func toInstances(services []*registry.Service) []*registrypb.Instance {
+	instances := make([]*registrypb.Instance, 0, len(services))
+	for _, service := range services {
+		instances = append(instances, toInstance(service))
+	}
+	return instances
+}
+
+func toInstance(service *registry.Service) *registrypb.Instance {
+	return &registrypb.Instance{
+		Namespace:     service.Namespace,
+		Name:          service.Name,
+		Id:            service.ID,
+		Hostname:      service.Hostname,
+		Ip:            service.IP,
+		Port:          int32(service.Port),
+		Metadata:      service.Metadata,
+		Status:        string(service.Status),
+		Weight:        int32(service.Weight),
+		Revision:      service.Revision,
+		Version:       service.Version,
+		Tags:          service.Tags,
+		Region:        service.Region,
+		Zone:          service.Zone,
+		LastHeartbeat: timestamppb.New(service.LastHeartbeat),
+		StartTime:     timestamppb.New(service.StartTime),
+	}
+}
+
+// toStatus 将业务错误转换为 gRPC 状态码
+func toStatus(err error) error {
+	serviceErr := apperrors.From(err)
+
+	code := codes.Internal
+	switch serviceErr.Kind {
+	case apperrors.KindValidation:
+		code = codes.InvalidArgument
+	case apperrors.KindUnauthorized:
+		code = codes.Unauthenticated
+	case apperrors.KindForbidden:
+		code = codes.PermissionDenied
+	case apperrors.KindNotFound:
+		code = codes.NotFound
+	case apperrors.KindConflict:
+		code = codes.AlreadyExists
+	case apperrors.KindLocked:
+		code = codes.FailedPrecondition
+	case apperrors.KindUnavailable:
+		code = codes.Unavailable
+	default:
+		logger.ErrorLogger.Printf("gRPC请求处理失败: %v", err)
+	}
+
+	return status.Error(code, serviceErr.Message)
+}
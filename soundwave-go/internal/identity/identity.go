@@ -0,0 +1,56 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/models"
+)
+
+var (
+	// ErrInvalidCredentials 外部身份源认证失败
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrNoRoleMapped 用户不属于任何映射的用户组且未配置默认角色
+	ErrNoRoleMapped = apperrors.Forbidden("NO_ROLE_MAPPED", "用户未被授权访问本系统")
+)
+
+// Identity 外部身份源认证通过后返回的用户身份
+type Identity struct {
+	Provider string   // 身份源名称
+	Subject  string   // 身份源内的唯一标识
+	Username string   // 用户名
+	Email    string   // 邮箱
+	Groups   []string // 所属用户组
+}
+
+// Provider 外部身份源
+type Provider interface {
+	// Name 身份源名称，用于路由和用户关联
+	Name() string
+	// DisplayName 登录页显示名称
+	DisplayName() string
+	// Type 身份源类型，如 oidc、ldap
+	Type() string
+	// MapRole 根据用户组映射角色
+	MapRole(groups []string) (models.Role, error)
+}
+
+// PasswordProvider 使用用户名密码认证的身份源，如 LDAP
+type PasswordProvider interface {
+	Provider
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+// RedirectProvider 使用浏览器跳转认证的身份源，如 OIDC 授权码模式
+type RedirectProvider interface {
+	Provider
+	AuthCodeURL(state, nonce string) string
+	Exchange(ctx context.Context, code, nonce string) (*Identity, error)
+}
+
+// ProviderInfo 登录页展示的身份源信息
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
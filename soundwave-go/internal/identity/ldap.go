@@ -0,0 +1,125 @@
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"soundwave-go/internal/config"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConn LDAP 连接中用到的操作，便于替换为进程内的模拟实现
+type LDAPConn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPDialer 建立 LDAP 连接
+type LDAPDialer func(ctx context.Context) (LDAPConn, error)
+
+// LDAPProvider LDAP 绑定认证身份源
+type LDAPProvider struct {
+	RoleMapper
+	config config.LDAPConfig
+	dial   LDAPDialer
+}
+
+// NewLDAPProvider 创建 LDAP 身份源，dialer 为空时按配置连接真实 LDAP 服务
+func NewLDAPProvider(cfg config.LDAPConfig, dialer LDAPDialer) *LDAPProvider {
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+
+	p := &LDAPProvider{
+		RoleMapper: NewRoleMapper(cfg.RoleMapping, cfg.DefaultRole),
+		config:     cfg,
+		dial:       dialer,
+	}
+	if p.dial == nil {
+		p.dial = p.dialURL
+	}
+	return p
+}
+
+func (p *LDAPProvider) Name() string        { return p.config.Name }
+func (p *LDAPProvider) DisplayName() string { return p.config.DisplayName }
+func (p *LDAPProvider) Type() string        { return "ldap" }
+
+// Authenticate 使用服务账号查找用户 DN，再以用户 DN 和密码绑定验证
+func (p *LDAPProvider) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	// 空密码会被部分 LDAP 服务视为匿名绑定而成功
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("连接LDAP失败: %w", err)
+	}
+	defer conn.Close()
+
+	if p.config.BindDN != "" {
+		if err := conn.Bind(p.config.BindDN, p.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP服务账号绑定失败: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(p.config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", p.config.UsernameAttribute, "mail", p.config.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("查询LDAP用户失败: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP用户绑定失败: %w", err)
+	}
+
+	identity := &Identity{
+		Provider: p.config.Name,
+		Subject:  entry.DN,
+		Username: entry.GetAttributeValue(p.config.UsernameAttribute),
+		Email:    entry.GetAttributeValue("mail"),
+		Groups:   entry.GetAttributeValues(p.config.GroupAttribute),
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+	return identity, nil
+}
+
+func (p *LDAPProvider) dialURL(ctx context.Context) (LDAPConn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.config.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: p.config.Timeout}
+
+	conn, err := ldap.DialURL(p.config.URL,
+		ldap.DialWithDialer(dialer),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(p.config.Timeout)
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,271 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeLDAPUser 模拟 LDAP 目录中的用户
+type fakeLDAPUser struct {
+	dn       string
+	uid      string
+	mail     string
+	password string
+	groups   []string
+}
+
+// fakeLDAPConn 进程内的模拟 LDAP 连接，按 uid 过滤条件查找用户
+type fakeLDAPConn struct {
+	bindDN       string
+	bindPassword string
+	users        []fakeLDAPUser
+	// searchErr 不为空时 Search 返回该错误
+	searchErr error
+
+	binds   []string
+	filters []string
+	closed  bool
+}
+
+func (c *fakeLDAPConn) Bind(username, password string) error {
+	c.binds = append(c.binds, username)
+	if username == c.bindDN && password == c.bindPassword {
+		return nil
+	}
+	for _, user := range c.users {
+		if user.dn == username && user.password == password {
+			return nil
+		}
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (c *fakeLDAPConn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	c.filters = append(c.filters, request.Filter)
+	if c.searchErr != nil {
+		return nil, c.searchErr
+	}
+	result := &ldap.SearchResult{}
+	for _, user := range c.users {
+		if request.Filter != "(uid="+ldap.EscapeFilter(user.uid)+")" {
+			continue
+		}
+		result.Entries = append(result.Entries, ldap.NewEntry(user.dn, map[string][]string{
+			"uid":      {user.uid},
+			"mail":     {user.mail},
+			"memberOf": user.groups,
+		}))
+	}
+	return result, nil
+}
+
+func (c *fakeLDAPConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func newTestLDAPProvider(conn *fakeLDAPConn) *LDAPProvider {
+	return NewLDAPProvider(config.LDAPConfig{
+		Name:              "corp",
+		BindDN:            "cn=service,dc=example,dc=com",
+		BindPassword:      "service-password",
+		BaseDN:            "dc=example,dc=com",
+		UserFilter:        "(uid=%s)",
+		UsernameAttribute: "uid",
+		GroupAttribute:    "memberOf",
+		RoleMapping: map[string]models.Role{
+			"cn=admins,ou=groups,dc=example,dc=com": models.RoleAdmin,
+			"CN=QA,OU=Groups,DC=example,DC=com":     models.RoleTester,
+		},
+	}, func(ctx context.Context) (LDAPConn, error) {
+		return conn, nil
+	})
+}
+
+func newFakeLDAPConn() *fakeLDAPConn {
+	return &fakeLDAPConn{
+		bindDN:       "cn=service,dc=example,dc=com",
+		bindPassword: "service-password",
+		users: []fakeLDAPUser{
+			{
+				dn:       "uid=alice,ou=people,dc=example,dc=com",
+				uid:      "alice",
+				mail:     "alice@example.com",
+				password: "alice-password",
+				groups:   []string{"cn=qa,ou=groups,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com"},
+			},
+			{
+				dn:       "uid=bob,ou=people,dc=example,dc=com",
+				uid:      "bob",
+				mail:     "bob@example.com",
+				password: "bob-password",
+				groups:   []string{"cn=qa,ou=groups,dc=example,dc=com"},
+			},
+			{
+				dn:       "uid=carol,ou=people,dc=example,dc=com",
+				uid:      "carol",
+				password: "carol-password",
+				groups:   []string{"cn=sales,ou=groups,dc=example,dc=com"},
+			},
+		},
+	}
+}
+
+func TestLDAPAuthenticateSuccess(t *testing.T) {
+	conn := newFakeLDAPConn()
+	provider := newTestLDAPProvider(conn)
+
+	ident, err := provider.Authenticate(context.Background(), "alice", "alice-password")
+	if err != nil {
+		t.Fatalf("Authenticate 失败: %v", err)
+	}
+
+	if ident.Provider != "corp" || ident.Subject != "uid=alice,ou=people,dc=example,dc=com" ||
+		ident.Username != "alice" || ident.Email != "alice@example.com" || len(ident.Groups) != 2 {
+		t.Errorf("身份 = %+v", *ident)
+	}
+	wantBinds := []string{"cn=service,dc=example,dc=com", "uid=alice,ou=people,dc=example,dc=com"}
+	if strings.Join(conn.binds, ";") != strings.Join(wantBinds, ";") {
+		t.Errorf("绑定顺序 = %v, 期望 %v", conn.binds, wantBinds)
+	}
+	if !conn.closed {
+		t.Error("认证结束后应关闭连接")
+	}
+}
+
+func TestLDAPAuthenticateFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		setup    func(p *LDAPProvider, c *fakeLDAPConn)
+		wantErr  error
+		wantMsg  string
+	}{
+		{name: "密码错误", username: "alice", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "用户不存在", username: "nobody", password: "whatever", wantErr: ErrInvalidCredentials},
+		{name: "空密码不发起绑定", username: "alice", password: "", wantErr: ErrInvalidCredentials},
+		{name: "空用户名", username: "", password: "alice-password", wantErr: ErrInvalidCredentials},
+		{
+			name:     "服务账号绑定失败",
+			username: "alice",
+			password: "alice-password",
+			setup: func(p *LDAPProvider, c *fakeLDAPConn) {
+				c.bindPassword = "rotated"
+			},
+			wantMsg: "LDAP服务账号绑定失败",
+		},
+		{
+			name:     "查询失败",
+			username: "alice",
+			password: "alice-password",
+			setup: func(p *LDAPProvider, c *fakeLDAPConn) {
+				c.searchErr = errors.New("connection reset")
+			},
+			wantMsg: "查询LDAP用户失败",
+		},
+		{
+			name:     "用户名匹配多个条目",
+			username: "alice",
+			password: "alice-password",
+			setup: func(p *LDAPProvider, c *fakeLDAPConn) {
+				c.users = append(c.users, fakeLDAPUser{dn: "uid=alice,ou=contractors,dc=example,dc=com", uid: "alice"})
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "连接失败",
+			username: "alice",
+			password: "alice-password",
+			setup: func(p *LDAPProvider, c *fakeLDAPConn) {
+				p.dial = func(ctx context.Context) (LDAPConn, error) {
+					return nil, errors.New("connection refused")
+				}
+			},
+			wantMsg: "连接LDAP失败",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newFakeLDAPConn()
+			provider := newTestLDAPProvider(conn)
+			if tt.setup != nil {
+				tt.setup(provider, conn)
+			}
+
+			ident, err := provider.Authenticate(context.Background(), tt.username, tt.password)
+			if err == nil {
+				t.Fatalf("Authenticate 应返回错误，实际返回 %+v", *ident)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("错误 = %v, 期望 %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.wantMsg)
+			}
+			if tt.password == "" && len(conn.binds) > 0 {
+				t.Errorf("空密码不应发起绑定，实际绑定 %v", conn.binds)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateEscapesFilter(t *testing.T) {
+	conn := newFakeLDAPConn()
+	provider := newTestLDAPProvider(conn)
+
+	if _, err := provider.Authenticate(context.Background(), "*)(uid=*", "alice-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("错误 = %v, 期望 %v", err, ErrInvalidCredentials)
+	}
+	if want := `(uid=\2a\29\28uid=\2a)`; len(conn.filters) != 1 || conn.filters[0] != want {
+		t.Errorf("查询条件 = %v, 期望 %s", conn.filters, want)
+	}
+}
+
+func TestLDAPGroupsMapToRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		username    string
+		defaultRole models.Role
+		want        models.Role
+		wantErr     error
+	}{
+		{name: "多个用户组取最高权限", username: "alice", want: models.RoleAdmin},
+		{name: "用户组不区分大小写", username: "bob", want: models.RoleTester},
+		{name: "未匹配时使用默认角色", username: "carol", defaultRole: models.RoleUser, want: models.RoleUser},
+		{name: "未匹配且无默认角色", username: "carol", wantErr: ErrNoRoleMapped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newFakeLDAPConn()
+			provider := newTestLDAPProvider(conn)
+			provider.RoleMapper.defaultRole = tt.defaultRole
+
+			ident, err := provider.Authenticate(context.Background(), tt.username, tt.username+"-password")
+			if err != nil {
+				t.Fatalf("Authenticate 失败: %v", err)
+			}
+			role, err := provider.MapRole(ident.Groups)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("错误 = %v, 期望 %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MapRole 失败: %v", err)
+			}
+			if role != tt.want {
+				t.Errorf("角色 = %s, 期望 %s", role, tt.want)
+			}
+		})
+	}
+}
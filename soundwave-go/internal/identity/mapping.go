@@ -0,0 +1,44 @@
+package identity
+
+import (
+	"soundwave-go/internal/models"
+	"strings"
+)
+
+// rolePriority 用户属于多个用户组时，按权限从高到低选择角色
+var rolePriority = []models.Role{models.RoleAdmin, models.RoleTester, models.RoleUser}
+
+// RoleMapper 用户组到角色的映射，用户组名称不区分大小写
+type RoleMapper struct {
+	mapping     map[string]models.Role
+	defaultRole models.Role
+}
+
+func NewRoleMapper(mapping map[string]models.Role, defaultRole models.Role) RoleMapper {
+	normalized := make(map[string]models.Role, len(mapping))
+	for group, role := range mapping {
+		normalized[strings.ToLower(group)] = role
+	}
+	return RoleMapper{mapping: normalized, defaultRole: defaultRole}
+}
+
+// MapRole 返回用户组映射到的最高权限角色，未匹配时返回默认角色
+func (m RoleMapper) MapRole(groups []string) (models.Role, error) {
+	matched := make(map[models.Role]bool)
+	for _, group := range groups {
+		if role, ok := m.mapping[strings.ToLower(group)]; ok {
+			matched[role] = true
+		}
+	}
+
+	for _, role := range rolePriority {
+		if matched[role] {
+			return role, nil
+		}
+	}
+
+	if m.defaultRole != "" {
+		return m.defaultRole, nil
+	}
+	return "", ErrNoRoleMapped
+}
@@ -0,0 +1,128 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"soundwave-go/internal/config"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider OIDC 授权码模式身份源
+type OIDCProvider struct {
+	RoleMapper
+	config   config.OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider 通过 issuer 的发现文档初始化 OIDC 身份源
+func NewOIDCProvider(ctx context.Context, cfg config.OIDCConfig) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("获取OIDC发现文档失败: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+
+	return &OIDCProvider{
+		RoleMapper: NewRoleMapper(cfg.RoleMapping, cfg.DefaultRole),
+		config:     cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+func (p *OIDCProvider) Name() string        { return p.config.Name }
+func (p *OIDCProvider) DisplayName() string { return p.config.DisplayName }
+func (p *OIDCProvider) Type() string        { return "oidc" }
+
+// AuthCodeURL 返回跳转到身份源的授权地址
+func (p *OIDCProvider) AuthCodeURL(state, nonce string) string {
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce))
+}
+
+// Exchange 使用授权码换取并校验 ID Token，解析用户身份
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("授权码换取token失败: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("响应中缺少id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id_token校验失败: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce不匹配")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("解析id_token失败: %w", err)
+	}
+
+	identity := &Identity{
+		Provider: p.config.Name,
+		Subject:  idToken.Subject,
+		Username: stringClaim(claims, p.config.UsernameClaim),
+		Email:    stringClaim(claims, "email"),
+		Groups:   stringsClaim(claims, p.config.GroupsClaim),
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		return nil, fmt.Errorf("id_token中缺少用户名字段 %s", p.config.UsernameClaim)
+	}
+
+	return identity, nil
+}
+
+func stringClaim(claims map[string]interface{}, key string) string {
+	if v, ok := claims[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func stringsClaim(claims map[string]interface{}, key string) []string {
+	switch v := claims[key].(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case string:
+		return []string{v}
+	}
+	return nil
+}
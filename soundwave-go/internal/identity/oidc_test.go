@@ -0,0 +1,376 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	testClientID  = "soundwave"
+	testValidCode = "valid-code"
+	testKeyID     = "test-key"
+)
+
+// mockOIDCProvider 提供发现文档、JWKS 和 token 接口的模拟 OIDC 身份源
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// signingKey 为空时使用 key 签名，用于模拟签名与 JWKS 不匹配
+	signingKey *rsa.PrivateKey
+	// claims 颁发的 id_token 中的声明，iss、aud、exp、iat 未设置时自动填充
+	claims map[string]interface{}
+	// omitIDToken 为 true 时 token 响应中不返回 id_token
+	omitIDToken bool
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成RSA密钥失败: %v", err)
+	}
+	m := &mockOIDCProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key:       &m.key.PublicKey,
+			KeyID:     testKeyID,
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != testValidCode {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		resp := map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		}
+		if !m.omitIDToken {
+			resp["id_token"] = m.idToken(t)
+		}
+		writeJSON(w, resp)
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// idToken 按 claims 签发 id_token
+func (m *mockOIDCProvider) idToken(t *testing.T) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": m.server.URL,
+		"aud": testClientID,
+		"exp": now.Add(time.Hour).Unix(),
+		"iat": now.Unix(),
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("序列化声明失败: %v", err)
+	}
+
+	key := m.signingKey
+	if key == nil {
+		key = m.key
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", testKeyID),
+	)
+	if err != nil {
+		t.Fatalf("创建签名器失败: %v", err)
+	}
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	token, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatalf("序列化id_token失败: %v", err)
+	}
+	return token
+}
+
+func (m *mockOIDCProvider) newProvider(t *testing.T, cfg config.OIDCConfig) *OIDCProvider {
+	t.Helper()
+
+	cfg.Name = "mock"
+	cfg.Issuer = m.server.URL
+	cfg.ClientID = testClientID
+	cfg.ClientSecret = "secret"
+	cfg.RedirectURL = "http://localhost/auth/oidc/mock/callback"
+	provider, err := NewOIDCProvider(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("初始化OIDC身份源失败: %v", err)
+	}
+	return provider
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCAuthCodeURL(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	provider := mock.newProvider(t, config.OIDCConfig{})
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state-value", "nonce-value"))
+	if err != nil {
+		t.Fatalf("解析授权地址失败: %v", err)
+	}
+	if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != mock.server.URL+"/authorize" {
+		t.Errorf("授权地址 = %s, 期望 %s", got, mock.server.URL+"/authorize")
+	}
+
+	query := authURL.Query()
+	want := map[string]string{
+		"state":         "state-value",
+		"nonce":         "nonce-value",
+		"client_id":     testClientID,
+		"response_type": "code",
+		"redirect_uri":  "http://localhost/auth/oidc/mock/callback",
+		"scope":         "openid profile email",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("参数 %s = %q, 期望 %q", key, got, value)
+		}
+	}
+}
+
+func TestOIDCExchangeMapsClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.OIDCConfig
+		claims map[string]interface{}
+		want   Identity
+	}{
+		{
+			name: "默认声明",
+			claims: map[string]interface{}{
+				"sub":                "user-1",
+				"preferred_username": "alice",
+				"email":              "alice@example.com",
+				"groups":             []string{"ops", "dev"},
+			},
+			want: Identity{Subject: "user-1", Username: "alice", Email: "alice@example.com", Groups: []string{"ops", "dev"}},
+		},
+		{
+			name:   "自定义用户名和用户组声明",
+			config: config.OIDCConfig{UsernameClaim: "login", GroupsClaim: "roles"},
+			claims: map[string]interface{}{
+				"sub":                "user-2",
+				"login":              "bob",
+				"preferred_username": "ignored",
+				"roles":              []string{"admins"},
+				"groups":             []string{"ignored"},
+			},
+			want: Identity{Subject: "user-2", Username: "bob", Groups: []string{"admins"}},
+		},
+		{
+			name: "单个字符串用户组",
+			claims: map[string]interface{}{
+				"sub":                "user-3",
+				"preferred_username": "carol",
+				"groups":             "ops",
+			},
+			want: Identity{Subject: "user-3", Username: "carol", Groups: []string{"ops"}},
+		},
+		{
+			name: "缺少用户名时使用邮箱",
+			claims: map[string]interface{}{
+				"sub":   "user-4",
+				"email": "dave@example.com",
+			},
+			want: Identity{Subject: "user-4", Username: "dave@example.com", Email: "dave@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockOIDCProvider(t)
+			provider := mock.newProvider(t, tt.config)
+			mock.claims = tt.claims
+			mock.claims["nonce"] = "nonce-value"
+
+			got, err := provider.Exchange(context.Background(), testValidCode, "nonce-value")
+			if err != nil {
+				t.Fatalf("Exchange 失败: %v", err)
+			}
+			if got.Provider != "mock" {
+				t.Errorf("Provider = %q, 期望 mock", got.Provider)
+			}
+			if got.Subject != tt.want.Subject || got.Username != tt.want.Username || got.Email != tt.want.Email {
+				t.Errorf("身份 = %+v, 期望 %+v", *got, tt.want)
+			}
+			if strings.Join(got.Groups, ",") != strings.Join(tt.want.Groups, ",") {
+				t.Errorf("Groups = %v, 期望 %v", got.Groups, tt.want.Groups)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeRejectsInvalidToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成RSA密钥失败: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		nonce   string
+		setup   func(m *mockOIDCProvider)
+		wantErr string
+	}{
+		{
+			name:    "nonce不匹配",
+			code:    testValidCode,
+			nonce:   "other-nonce",
+			wantErr: "nonce不匹配",
+		},
+		{
+			name:  "缺少nonce",
+			code:  testValidCode,
+			nonce: "nonce-value",
+			setup: func(m *mockOIDCProvider) {
+				delete(m.claims, "nonce")
+			},
+			wantErr: "nonce不匹配",
+		},
+		{
+			name:    "授权码无效",
+			code:    "invalid-code",
+			nonce:   "nonce-value",
+			wantErr: "授权码换取token失败",
+		},
+		{
+			name:  "缺少id_token",
+			code:  testValidCode,
+			nonce: "nonce-value",
+			setup: func(m *mockOIDCProvider) {
+				m.omitIDToken = true
+			},
+			wantErr: "缺少id_token",
+		},
+		{
+			name:  "签名密钥不匹配",
+			code:  testValidCode,
+			nonce: "nonce-value",
+			setup: func(m *mockOIDCProvider) {
+				m.signingKey = otherKey
+			},
+			wantErr: "id_token校验失败",
+		},
+		{
+			name:  "audience不匹配",
+			code:  testValidCode,
+			nonce: "nonce-value",
+			setup: func(m *mockOIDCProvider) {
+				m.claims["aud"] = "other-client"
+			},
+			wantErr: "id_token校验失败",
+		},
+		{
+			name:  "id_token已过期",
+			code:  testValidCode,
+			nonce: "nonce-value",
+			setup: func(m *mockOIDCProvider) {
+				m.claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			},
+			wantErr: "id_token校验失败",
+		},
+		{
+			name:  "缺少用户名和邮箱",
+			code:  testValidCode,
+			nonce: "nonce-value",
+			setup: func(m *mockOIDCProvider) {
+				delete(m.claims, "preferred_username")
+			},
+			wantErr: "缺少用户名字段",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockOIDCProvider(t)
+			provider := mock.newProvider(t, config.OIDCConfig{})
+			mock.claims = map[string]interface{}{
+				"sub":                "user-1",
+				"preferred_username": "alice",
+				"nonce":              "nonce-value",
+			}
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+
+			_, err := provider.Exchange(context.Background(), tt.code, tt.nonce)
+			if err == nil {
+				t.Fatal("Exchange 应返回错误")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderMapsGroupsToRole(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	provider := mock.newProvider(t, config.OIDCConfig{
+		RoleMapping: map[string]models.Role{"Ops": models.RoleAdmin, "qa": models.RoleTester},
+	})
+	mock.claims = map[string]interface{}{
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"nonce":              "nonce-value",
+		"groups":             []string{"QA", "ops"},
+	}
+
+	ident, err := provider.Exchange(context.Background(), testValidCode, "nonce-value")
+	if err != nil {
+		t.Fatalf("Exchange 失败: %v", err)
+	}
+	role, err := provider.MapRole(ident.Groups)
+	if err != nil {
+		t.Fatalf("MapRole 失败: %v", err)
+	}
+	if role != models.RoleAdmin {
+		t.Errorf("角色 = %s, 期望 %s", role, models.RoleAdmin)
+	}
+}
@@ -0,0 +1,73 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/logger"
+)
+
+// Registry 已启用的外部身份源
+type Registry struct {
+	providers map[string]Provider
+	order     []string
+}
+
+// NewRegistry 按配置初始化身份源，初始化失败的身份源记录日志后跳过
+func NewRegistry(ctx context.Context, cfg config.SSOConfig) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+
+	for _, oidcCfg := range cfg.OIDC {
+		provider, err := NewOIDCProvider(ctx, oidcCfg)
+		if err != nil {
+			logger.ErrorLogger.Printf("初始化OIDC身份源 %s 失败: %v", oidcCfg.Name, err)
+			continue
+		}
+		r.Add(provider)
+	}
+
+	if cfg.LDAP.Enabled {
+		r.Add(NewLDAPProvider(cfg.LDAP, nil))
+	}
+
+	return r
+}
+
+// Add 注册身份源
+func (r *Registry) Add(provider Provider) {
+	if _, exists := r.providers[provider.Name()]; !exists {
+		r.order = append(r.order, provider.Name())
+	}
+	r.providers[provider.Name()] = provider
+	logger.InfoLogger.Printf("已启用%s身份源: %s", provider.Type(), provider.Name())
+}
+
+// Get 按名称获取身份源
+func (r *Registry) Get(name string) (Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// List 返回所有身份源的展示信息
+func (r *Registry) List() []ProviderInfo {
+	infos := make([]ProviderInfo, 0, len(r.order))
+	for _, name := range r.order {
+		p := r.providers[name]
+		infos = append(infos, ProviderInfo{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+			Type:        p.Type(),
+		})
+	}
+	return infos
+}
+
+// NewState 生成 OIDC 登录使用的随机 state/nonce
+func NewState() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InstanceCounter 按命名空间、服务名和状态统计当前实例数量
+type InstanceCounter interface {
+	CountInstances() map[string]map[string]map[string]int
+}
+
+var instancesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "instances"),
+	"当前注册的服务实例数",
+	[]string{"namespace", "service", "status"}, nil,
+)
+
+// instanceCollector 在采集时读取注册中心快照，避免在每次状态变化时维护 gauge
+type instanceCollector struct {
+	counter InstanceCounter
+}
+
+// RegisterInstanceCounter 注册实例数量采集器
+func RegisterInstanceCounter(counter InstanceCounter) error {
+	return Registry.Register(&instanceCollector{counter: counter})
+}
+
+func (c *instanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- instancesDesc
+}
+
+func (c *instanceCollector) Collect(ch chan<- prometheus.Metric) {
+	for ns, services := range c.counter.CountInstances() {
+		for service, statuses := range services {
+			for status, count := range statuses {
+				ch <- prometheus.MustNewConstMetric(instancesDesc, prometheus.GaugeValue, float64(count), ns, service, status)
+			}
+		}
+	}
+}
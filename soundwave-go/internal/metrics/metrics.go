@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "soundwave"
+
+// Registry 注册中心自身的指标，与默认注册表隔离，避免第三方库注册的指标混入
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Registrations 服务注册次数
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "服务实例注册次数",
+	}, []string{"namespace", "service"})
+
+	// Deregistrations 服务主动注销次数
+	Deregistrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deregistrations_total",
+		Help:      "服务实例注销次数",
+	}, []string{"namespace", "service"})
+
+	// Expirations 因心跳超时被剔除的实例数
+	Expirations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "expirations_total",
+		Help:      "因心跳超时被剔除的服务实例数",
+	}, []string{"namespace", "service"})
+
+	// Heartbeats 收到的心跳次数，配合 rate() 计算心跳速率
+	Heartbeats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "heartbeats_total",
+		Help:      "收到的服务心跳次数",
+	}, []string{"namespace", "service"})
+
+	// HeartbeatInterval 收到心跳时距上一次心跳的时间，超过实例心跳间隔的观测值即为延迟的心跳
+	HeartbeatInterval = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "heartbeat_interval_seconds",
+		Help:      "收到心跳时距上一次心跳的时间",
+		Buckets:   []float64{1, 2.5, 5, 10, 15, 20, 25, 30, 45, 60},
+	}, []string{"namespace", "service"})
+
+	// BalancerSelections 负载均衡选中各实例的次数，实例注销或过期时删除对应的序列
+	BalancerSelections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "balancer_selections_total",
+		Help:      "负载均衡选中服务实例的次数",
+	}, []string{"namespace", "service", "instance"})
+
+	// LocalitySelections 就近负载均衡按选择范围统计的次数，scope 为 zone、region 或 any
+	LocalitySelections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "locality_selections_total",
+		Help:      "就近负载均衡按选择范围统计的次数",
+	}, []string{"namespace", "service", "scope"})
+
+	// OutlierEjections 异常实例摘除次数，result 为 ejected 或 capped（已摘除的实例比例达到上限）
+	OutlierEjections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outlier_ejections_total",
+		Help:      "异常实例的摘除次数",
+	}, []string{"namespace", "service", "source", "result"})
+
+	// WatchEventsDropped 订阅者处理过慢、缓冲区已满而丢弃的事件数
+	WatchEventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "watch_events_dropped_total",
+		Help:      "订阅者处理过慢而丢弃的实例事件数",
+	}, []string{"namespace", "service"})
+
+	// SelfPreservationActive 注册中心是否处于自我保护模式
+	SelfPreservationActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "self_preservation_active",
+		Help:      "注册中心是否处于自我保护模式，1 表示暂停剔除心跳超时的实例",
+	})
+
+	// HealthCheckDuration 一轮健康检查的耗时
+	HealthCheckDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "health_check_duration_seconds",
+		Help:      "一轮服务健康检查的耗时",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	})
+
+	// HTTPRequestDuration HTTP 请求耗时，route 为路由模板而非实际路径
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求处理耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// MongoOperationDuration MongoDB 命令耗时
+	MongoOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongodb_operation_duration_seconds",
+		Help:      "MongoDB命令执行耗时",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 8),
+	}, []string{"command", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Registrations,
+		Deregistrations,
+		Expirations,
+		Heartbeats,
+		HeartbeatInterval,
+		BalancerSelections,
+		LocalitySelections,
+		OutlierEjections,
+		WatchEventsDropped,
+		SelfPreservationActive,
+		HealthCheckDuration,
+		HTTPRequestDuration,
+		MongoOperationDuration,
+	)
+}
+
+// Handler 返回暴露指标的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
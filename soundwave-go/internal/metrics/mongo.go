@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// NewMongoMonitor 返回记录 MongoDB 命令耗时的监听器
+func NewMongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			observeMongo(e.CommandName, "success", e.Duration)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			observeMongo(e.CommandName, "failure", e.Duration)
+		},
+	}
+}
+
+func observeMongo(command, status string, duration time.Duration) {
+	MongoOperationDuration.WithLabelValues(command, status).Observe(duration.Seconds())
+}
@@ -1,8 +1,8 @@
 package middleware
 
 import (
-	"net/http"
 	"soundwave-go/internal/config"
+	apperrors "soundwave-go/internal/errors"
 	"soundwave-go/internal/models"
 	"soundwave-go/internal/utils"
 	"strings"
@@ -10,27 +10,72 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// allowPasswordChangeKey 标记当前路由允许必须修改密码的用户访问
+	allowPasswordChangeKey = "allow_password_change"
+	// allowMFASetupKey 标记当前路由允许必须绑定 MFA 的用户访问
+	allowMFASetupKey = "allow_mfa_setup"
+)
+
+var (
+	// ErrInvalidTokenFormat Authorization 头格式错误
+	ErrInvalidTokenFormat = apperrors.Unauthorized("INVALID_TOKEN_FORMAT", "无效的token格式")
+	// ErrInvalidToken token 无效或已过期
+	ErrInvalidToken = apperrors.Unauthorized("INVALID_TOKEN", "无效的token")
+	// ErrPasswordChangeRequired 密码已过期，必须先修改密码
+	ErrPasswordChangeRequired = apperrors.Forbidden("PASSWORD_CHANGE_REQUIRED", "密码已过期，请先修改密码")
+	// ErrMFASetupRequired 角色强制 MFA，必须先完成绑定
+	ErrMFASetupRequired = apperrors.Forbidden("MFA_SETUP_REQUIRED", "当前角色要求启用MFA，请先完成绑定")
+	// ErrNamespaceForbidden 没有访问该命名空间的权限
+	ErrNamespaceForbidden = apperrors.Forbidden("NAMESPACE_FORBIDDEN", "没有访问该命名空间的权限")
+)
+
+// AllowPasswordChange 允许密码已过期、必须修改密码的用户访问后续路由，需放在 AuthRequired 之前
+func AllowPasswordChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(allowPasswordChangeKey, true)
+		c.Next()
+	}
+}
+
+// AllowMFASetup 允许角色强制 MFA 但尚未绑定的用户访问后续路由，需放在 AuthRequired 之前
+func AllowMFASetup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(allowMFASetupKey, true)
+		c.Next()
+	}
+}
+
 func AuthRequired(cfg *config.Config, requiredPermission models.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
-			c.Abort()
+			AbortWithError(c, apperrors.ErrUnauthorized)
 			return
 		}
 
 		// 从 Bearer token 中提取 token
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token格式"})
-			c.Abort()
+			AbortWithError(c, ErrInvalidTokenFormat)
 			return
 		}
 
 		claims, err := utils.ParseToken(cfg, parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
-			c.Abort()
+			AbortWithError(c, ErrInvalidToken)
+			return
+		}
+
+		// 必须修改密码的用户只能访问修改密码接口
+		if claims.MustChangePassword && !c.GetBool(allowPasswordChangeKey) {
+			AbortWithError(c, ErrPasswordChangeRequired)
+			return
+		}
+
+		// 角色强制 MFA 的用户需先完成绑定
+		if claims.MFASetupRequired && !c.GetBool(allowMFASetupKey) {
+			AbortWithError(c, ErrMFASetupRequired)
 			return
 		}
 
@@ -44,8 +89,7 @@ func AuthRequired(cfg *config.Config, requiredPermission models.Permission) gin.
 		}
 
 		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{"error": "没有访问权限"})
-			c.Abort()
+			AbortWithError(c, apperrors.ErrForbidden)
 			return
 		}
 
@@ -53,3 +97,17 @@ func AuthRequired(cfg *config.Config, requiredPermission models.Permission) gin.
 		c.Next()
 	}
 }
+
+// CanAccessNamespace 判断当前登录用户能否访问服务命名空间，未经 AuthRequired 认证的请求没有权限。
+// 权限取自 token 中的声明，撤销的权限在 token 过期前仍然有效
+func CanAccessNamespace(c *gin.Context, namespace string) bool {
+	value, exists := c.Get("user")
+	if !exists {
+		return false
+	}
+	claims, ok := value.(*utils.Claims)
+	if !ok {
+		return false
+	}
+	return models.NamespaceAllowed(claims.Role, claims.Namespaces, namespace)
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader 请求ID的请求头和响应头
+	RequestIDHeader = "X-Request-ID"
+	// requestIDKey 请求ID在上下文中的键
+	requestIDKey = "request_id"
+)
+
+// ErrorResponse 统一的错误响应
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id"`
+}
+
+// RequestID 为每个请求生成请求ID，调用方已携带时沿用
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Writer.Header().Set(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// GetRequestID 获取当前请求的请求ID
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// ErrorHandler 将处理器通过 c.Error 记录的错误转换为统一的错误响应
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		respondError(c, c.Errors.Last().Err)
+	}
+}
+
+// Recovery 捕获处理器 panic，返回统一的内部错误响应
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		respondError(c, apperrors.ErrInternal.Wrap(fmt.Errorf("panic: %v", recovered)))
+	})
+}
+
+// AbortWithError 记录错误并中止后续处理器，响应由 ErrorHandler 统一写出
+func AbortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+func respondError(c *gin.Context, err error) {
+	serviceErr := apperrors.From(err)
+	requestID := GetRequestID(c)
+
+	if serviceErr.Kind == apperrors.KindInternal {
+		logger.ErrorLogger.Printf("请求 %s %s 处理失败 [%s]: %v", c.Request.Method, c.Request.URL.Path, requestID, err)
+	}
+
+	c.AbortWithStatusJSON(serviceErr.Kind.HTTPStatus(), ErrorResponse{
+		Error:     serviceErr.Message,
+		Code:      serviceErr.Code,
+		RequestID: requestID,
+	})
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"soundwave-go/internal/registry"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceEvent 服务实例的生命周期事件，超过保留时长后由 MongoDB 的 TTL 索引自动删除
+type ServiceEvent struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Namespace  string             `bson:"namespace" json:"namespace"`
+	Service    string             `bson:"service" json:"service"`
+	InstanceID string             `bson:"instance_id" json:"instance_id"`
+	Hostname   string             `bson:"hostname" json:"hostname"`
+	Address    string             `bson:"address" json:"address"`
+	Type       registry.EventType `bson:"type" json:"type"`
+	Status     string             `bson:"status" json:"status"`
+	Version    string             `bson:"version,omitempty" json:"version,omitempty"`
+	Detail     string             `bson:"detail,omitempty" json:"detail,omitempty"`
+	// Ejected 和 HeartbeatLost 为事件发生后实例是否被摘除、是否心跳超时，
+	// 统计时间段开始前的最后一个事件不涉及这两种状态时据此推断实例的可用状态
+	Ejected       bool      `bson:"ejected,omitempty" json:"ejected,omitempty"`
+	HeartbeatLost bool      `bson:"heartbeat_lost,omitempty" json:"heartbeat_lost,omitempty"`
+	Time          time.Time `bson:"time" json:"time"`
+}
+
+// InstanceAvailability 实例在一段时间内的可用性，根据生命周期事件计算。
+// 实例主动注销后的时间不计入观测时长
+type InstanceAvailability struct {
+	InstanceID string        `json:"instance_id"`
+	Hostname   string        `json:"hostname"`
+	Address    string        `json:"address"`
+	Uptime     time.Duration `json:"uptime"`
+	Downtime   time.Duration `json:"downtime"`
+	// Availability 可用时长占观测时长的比例
+	Availability float64 `json:"availability"`
+	// Flaps 由可用变为不可用的次数
+	Flaps         int                `json:"flaps"`
+	LastEvent     registry.EventType `json:"last_event"`
+	LastEventTime time.Time          `json:"last_event_time"`
+}
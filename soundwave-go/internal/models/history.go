@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"soundwave-go/internal/registry"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceHistoryPoint 降采样后写入 MongoDB 的服务历史数据，超过保留时长后由 TTL 索引自动删除
+type ServiceHistoryPoint struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	Namespace       string             `bson:"namespace"`
+	Service         string             `bson:"service"`
+	Time            time.Time          `bson:"time"`
+	Healthy         float64            `bson:"healthy"`
+	Unhealthy       float64            `bson:"unhealthy"`
+	MinHealthy      int                `bson:"min_healthy"`
+	MaxUnhealthy    int                `bson:"max_unhealthy"`
+	Heartbeats      int                `bson:"heartbeats"`
+	LatenessAvg     float64            `bson:"lateness_avg"`
+	LatenessMax     float64            `bson:"lateness_max"`
+	Registrations   int                `bson:"registrations"`
+	Deregistrations int                `bson:"deregistrations"`
+	Expirations     int                `bson:"expirations"`
+	Samples         int                `bson:"samples"`
+}
+
+// ServiceHistory 服务在一段时间内的历史数据，Step 为每个点的时长
+type ServiceHistory struct {
+	Since  time.Time               `json:"since"`
+	Until  time.Time               `json:"until"`
+	Step   time.Duration           `json:"step"`
+	Points []registry.HistoryPoint `json:"points"`
+}
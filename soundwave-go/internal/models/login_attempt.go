@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginAttempt 登录尝试记录
+type LoginAttempt struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username  string             `bson:"username" json:"username"`
+	UserID    string             `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Provider  string             `bson:"provider,omitempty" json:"provider,omitempty"`
+	IP        string             `bson:"ip" json:"ip"`
+	UserAgent string             `bson:"user_agent" json:"user_agent"`
+	Success   bool               `bson:"success" json:"success"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// 登录失败原因
+const (
+	LoginReasonUserNotFound  = "user_not_found"
+	LoginReasonWrongPassword = "wrong_password"
+	LoginReasonLocked        = "locked"
+	LoginReasonMFAPending    = "mfa_pending"
+	LoginReasonInvalidMFA    = "invalid_mfa_code"
+	LoginReasonProviderError = "provider_error"
+	LoginReasonNoRoleMapped  = "no_role_mapped"
+)
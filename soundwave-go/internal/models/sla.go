@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// SLAIncident 服务健康实例数低于要求的一段时间
+type SLAIncident struct {
+	Start time.Time `json:"start"`
+	// End 为空表示故障仍在持续
+	End      *time.Time    `json:"end,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// LowestHealthy 故障期间最少的健康实例数
+	LowestHealthy int `json:"lowest_healthy"`
+}
+
+// SLAReport 服务在一个时间窗口内的可用性报告。
+// 服务可用指至少有 MinHealthy 个健康实例，所有实例都已注销的时间不计入观测时长
+type SLAReport struct {
+	Namespace    string                 `json:"namespace"`
+	Service      string                 `json:"service"`
+	Window       string                 `json:"window"`
+	Since        time.Time              `json:"since"`
+	Until        time.Time              `json:"until"`
+	MinHealthy   int                    `json:"min_healthy"`
+	Availability float64                `json:"availability"`
+	Uptime       time.Duration          `json:"uptime"`
+	Downtime     time.Duration          `json:"downtime"`
+	Incidents    []SLAIncident          `json:"incidents"`
+	Instances    []InstanceAvailability `json:"instances"`
+}
+
+// SLASummary 服务可用性概要，用于统计页面
+type SLASummary struct {
+	Service      string        `json:"service"`
+	Availability float64       `json:"availability"`
+	Downtime     time.Duration `json:"downtime"`
+	Incidents    int           `json:"incidents"`
+	Instances    int           `json:"instances"`
+}
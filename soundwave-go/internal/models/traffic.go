@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"soundwave-go/pkg/routing"
+)
+
+// TrafficPolicy 服务的流量路由规则，每个命名空间内的服务最多一条
+type TrafficPolicy struct {
+	Namespace string                `bson:"namespace" json:"namespace"`
+	Service   string                `bson:"service" json:"service"`
+	Rules     []routing.TrafficRule `bson:"rules" json:"rules"`
+	UpdatedAt time.Time             `bson:"updated_at" json:"updated_at"`
+	UpdatedBy string                `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+}
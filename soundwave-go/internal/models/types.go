@@ -17,4 +17,37 @@ const (
 	PermissionViewStats    Permission = "view_stats"    // 查看服务统计
 	PermissionManageSystem Permission = "manage_system" // 管理系统设置
 	PermissionManageUsers  Permission = "manage_users"  // 管理用户
+	PermissionViewAlarm    Permission = "view_alarm"    // 查看服务报警
 )
+
+// RolePermissions 各角色的默认权限，用于外部身份源自动创建的用户
+var RolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermissionViewServices,
+		PermissionViewStats,
+		PermissionManageSystem,
+		PermissionManageUsers,
+		PermissionViewAlarm,
+	},
+	RoleUser:   {PermissionViewServices, PermissionViewStats},
+	RoleTester: {PermissionViewServices, PermissionViewStats},
+}
+
+// NamespaceAllowed 判断用户能否访问服务命名空间，管理员和未限制命名空间的用户可访问所有命名空间
+func NamespaceAllowed(role Role, namespaces []string, namespace string) bool {
+	if role == RoleAdmin || len(namespaces) == 0 {
+		return true
+	}
+	for _, ns := range namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePolicy 角色安全策略
+type RolePolicy struct {
+	Role        Role `bson:"role" json:"role"`
+	MFARequired bool `bson:"mfa_required" json:"mfa_required"`
+}
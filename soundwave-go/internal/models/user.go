@@ -8,11 +8,27 @@ import (
 
 // User 用户模型
 type User struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username    string             `bson:"username" json:"username"`
-	Password    string             `bson:"password" json:"-"`
-	Role        Role               `bson:"role" json:"role"`
-	Permissions []Permission       `bson:"permissions" json:"permissions"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username           string             `bson:"username" json:"username"`
+	Password           string             `bson:"password" json:"-"`
+	Role               Role               `bson:"role" json:"role"`
+	Permissions        []Permission       `bson:"permissions" json:"permissions"`
+	Namespaces         []string           `bson:"namespaces,omitempty" json:"namespaces,omitempty"` // 可访问的服务命名空间，为空时不限制
+	Email              string             `bson:"email,omitempty" json:"email,omitempty"`
+	Provider           string             `bson:"provider,omitempty" json:"provider,omitempty"` // 外部身份源名称，本地用户为空
+	ExternalID         string             `bson:"external_id,omitempty" json:"-"`
+	PasswordHistory    []string           `bson:"password_history,omitempty" json:"-"`
+	PasswordChangedAt  time.Time          `bson:"password_changed_at" json:"password_changed_at"`
+	MustChangePassword bool               `bson:"must_change_password" json:"must_change_password"`
+	FailedAttempts     int                `bson:"failed_attempts" json:"failed_attempts"`
+	LockedUntil        *time.Time         `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	LastLoginAt        *time.Time         `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+	MFAEnabled         bool               `bson:"mfa_enabled" json:"mfa_enabled"`
+	MFASecret          string             `bson:"mfa_secret,omitempty" json:"-"`
+	MFAPendingSecret   string             `bson:"mfa_pending_secret,omitempty" json:"-"`
+	MFALastStep        int64              `bson:"mfa_last_step,omitempty" json:"-"`
+	MFARecoveryCodes   []string           `bson:"mfa_recovery_codes,omitempty" json:"-"`
+	MFAEnabledAt       *time.Time         `bson:"mfa_enabled_at,omitempty" json:"mfa_enabled_at,omitempty"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
 }
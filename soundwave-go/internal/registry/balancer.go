@@ -2,6 +2,7 @@ package registry
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -27,3 +28,73 @@ func (rb *RandomBalancer) Select(services []*Service) *Service {
 	}
 	return services[rb.rand.Intn(len(services))]
 }
+
+// P2CBalancer 两次随机选择（power of two choices）负载均衡器。
+// 随机挑选两个实例，选择负载代价较低的一个，代价为 EWMA 耗时 ×（正在处理的请求数 + 1）÷ 权重。
+// 没有上报负载的实例按其他实例的平均耗时计算，使新实例能逐步获得流量
+type P2CBalancer struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func NewP2CBalancer() *P2CBalancer {
+	return &P2CBalancer{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (b *P2CBalancer) Select(services []*Service) *Service {
+	switch len(services) {
+	case 0:
+		return nil
+	case 1:
+		return services[0]
+	}
+
+	b.mutex.Lock()
+	i := b.rand.Intn(len(services))
+	j := b.rand.Intn(len(services) - 1)
+	b.mutex.Unlock()
+	if j >= i {
+		j++
+	}
+
+	now := time.Now()
+	defaultLatency := averageLatency(services, now)
+	a, c := services[i], services[j]
+	if loadCost(c, now, defaultLatency) < loadCost(a, now, defaultLatency) {
+		return c
+	}
+	return a
+}
+
+// loadCost 计算实例的负载代价
+func loadCost(service *Service, now time.Time, defaultLatency float64) float64 {
+	latency, inFlight := defaultLatency, 0
+	if load := service.currentLoad(now); load != nil {
+		inFlight = load.InFlight
+		if load.LatencyMs > 0 {
+			latency = load.LatencyMs
+		}
+	}
+	weight := service.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return latency * float64(inFlight+1) / float64(weight)
+}
+
+// averageLatency 有效负载的平均耗时，所有实例都没有上报时返回 1
+func averageLatency(services []*Service, now time.Time) float64 {
+	total, count := 0.0, 0
+	for _, service := range services {
+		if load := service.currentLoad(now); load != nil && load.LatencyMs > 0 {
+			total += load.LatencyMs
+			count++
+		}
+	}
+	if count == 0 {
+		return 1
+	}
+	return total / float64(count)
+}
@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	// deltaRetention 变更记录的保留时长，早于该范围的增量请求需要全量同步
+	deltaRetention = 3 * time.Minute
+	// maxDeltaChanges 最多保留的变更记录条数
+	maxDeltaChanges = 10000
+)
+
+// ChangeType 增量同步中实例的变化类型
+type ChangeType string
+
+const (
+	// ChangeAdded 新增实例
+	ChangeAdded ChangeType = "ADDED"
+	// ChangeModified 实例信息或状态变化，心跳续约不视为变化
+	ChangeModified ChangeType = "MODIFIED"
+	// ChangeRemoved 实例注销或过期
+	ChangeRemoved ChangeType = "REMOVED"
+)
+
+// change 一条变更记录，service 为变更后的实例快照，删除时为删除前的快照
+type change struct {
+	revision   uint64
+	changeType ChangeType
+	namespace  string
+	uniqueID   string
+	service    Service
+	time       time.Time
+}
+
+// Snapshot 带版本号的命名空间全量快照，版本号在所有命名空间间共享
+type Snapshot struct {
+	Namespace string                `json:"namespace"`
+	Revision  uint64                `json:"revision"`
+	Hash      string                `json:"hash"`
+	Services  map[string][]*Service `json:"services"`
+}
+
+// Delta 自指定版本以来的增量变化。
+// 指定版本已超出变更记录的保留范围时 Full 为 true，Services 为全量数据
+type Delta struct {
+	Namespace string                `json:"namespace"`
+	Revision  uint64                `json:"revision"`
+	Hash      string                `json:"hash"`
+	Full      bool                  `json:"full"`
+	Added     []*Service            `json:"added,omitempty"`
+	Modified  []*Service            `json:"modified,omitempty"`
+	Removed   []*Service            `json:"removed,omitempty"`
+	Services  map[string][]*Service `json:"services,omitempty"`
+}
+
+// Snapshot 返回命名空间的全量快照
+func (sr *ServiceRegistry) Snapshot(namespaceName string) *Snapshot {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	return &Snapshot{
+		Namespace: namespaceName,
+		Revision:  sr.revision,
+		Hash:      formatHash(sr.namespaceHashLocked(namespaceName)),
+		Services:  sr.listAllServicesLocked(namespaceName),
+	}
+}
+
+// Delta 返回命名空间内 since 版本之后新增、修改和删除的实例，同一实例的多次变化合并为最终状态。
+// since 早于保留的变更记录或大于当前版本（如注册中心重启）时返回全量数据
+func (sr *ServiceRegistry) Delta(namespaceName string, since uint64) *Delta {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	delta := &Delta{
+		Namespace: namespaceName,
+		Revision:  sr.revision,
+		Hash:      formatHash(sr.namespaceHashLocked(namespaceName)),
+	}
+
+	if since < sr.truncatedRevision || since > sr.revision {
+		delta.Full = true
+		delta.Services = sr.listAllServicesLocked(namespaceName)
+		return delta
+	}
+
+	var services map[string]*Service
+	if ns := sr.namespaceLocked(namespaceName); ns != nil {
+		services = ns.services
+	}
+
+	// 记录每个实例在 since 之后的首次和最后一次变更
+	first := make(map[string]ChangeType)
+	last := make(map[string]*change)
+	order := make([]string, 0)
+	for i := range sr.changes {
+		c := &sr.changes[i]
+		if c.revision <= since || c.namespace != namespaceName {
+			continue
+		}
+		if _, ok := first[c.uniqueID]; !ok {
+			first[c.uniqueID] = c.changeType
+			order = append(order, c.uniqueID)
+		}
+		last[c.uniqueID] = c
+	}
+
+	for _, uniqueID := range order {
+		c := last[uniqueID]
+		if c.changeType == ChangeRemoved {
+			service := c.service
+			delta.Removed = append(delta.Removed, &service)
+			continue
+		}
+
+		service, ok := services[uniqueID]
+		if !ok {
+			continue
+		}
+		if first[uniqueID] == ChangeAdded {
+			delta.Added = append(delta.Added, service)
+		} else {
+			delta.Modified = append(delta.Modified, service)
+		}
+	}
+
+	return delta
+}
+
+// recordChange 记录命名空间内的实例变更并递增版本号，previous 为 nil 表示新增，current 为 nil 表示删除。
+// 调用方需持有写锁
+func (sr *ServiceRegistry) recordChange(ns *namespace, previous, current *Service) {
+	c := change{time: time.Now()}
+	if previous != nil {
+		// previous 与 current 可能是同一实例，需在更新版本号之前移出哈希
+		ns.hash ^= instanceHash(previous)
+	}
+
+	sr.revision++
+	c.revision = sr.revision
+
+	switch {
+	case current == nil:
+		c.changeType = ChangeRemoved
+		c.namespace = previous.Namespace
+		c.uniqueID = previous.UniqueID()
+		c.service = *previous
+	case previous == nil:
+		c.changeType = ChangeAdded
+	default:
+		c.changeType = ChangeModified
+	}
+	if current != nil {
+		current.Revision = sr.revision
+		ns.hash ^= instanceHash(current)
+		c.namespace = current.Namespace
+		c.uniqueID = current.UniqueID()
+		c.service = *current
+	}
+
+	sr.changes = append(sr.changes, c)
+	sr.pruneChanges(c.time)
+}
+
+// pruneChanges 清理超出保留范围的变更记录
+func (sr *ServiceRegistry) pruneChanges(now time.Time) {
+	drop := 0
+	for drop < len(sr.changes) {
+		if len(sr.changes)-drop <= maxDeltaChanges && now.Sub(sr.changes[drop].time) <= deltaRetention {
+			break
+		}
+		drop++
+	}
+	if drop == 0 {
+		return
+	}
+
+	sr.truncatedRevision = sr.changes[drop-1].revision
+	remaining := make([]change, len(sr.changes)-drop)
+	copy(remaining, sr.changes[drop:])
+	sr.changes = remaining
+}
+
+// namespaceHashLocked 命名空间不存在时哈希为 0。调用方需持有读锁
+func (sr *ServiceRegistry) namespaceHashLocked(namespaceName string) uint64 {
+	if ns := sr.namespaceLocked(namespaceName); ns != nil {
+		return ns.hash
+	}
+	return 0
+}
+
+// instanceHash 计算单个实例的哈希。
+// 命名空间的哈希为其中所有实例哈希的异或，实例哈希取 SHA-256("<name>/<hostname>/<id>/<revision>") 的前 8 字节，
+// 消费方可按同样的方式计算本地副本的哈希，与服务端不一致时应全量同步
+func instanceHash(service *Service) uint64 {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%d", service.Name, service.Hostname, service.ID, service.Revision)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func formatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func registerTestInstance(t *testing.T, sr *ServiceRegistry, name, id string) *Service {
+	t.Helper()
+	service := &Service{Name: name, ID: id, Hostname: "host-" + id, IP: "10.0.0.1", Port: 8080}
+	if err := sr.RegisterService(service); err != nil {
+		t.Fatalf("注册实例 %s 失败: %v", id, err)
+	}
+	return service
+}
+
+func instanceIDs(services []*Service) string {
+	ids := make([]string, 0, len(services))
+	for _, service := range services {
+		ids = append(ids, service.ID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func TestDeltaIncremental(t *testing.T) {
+	sr := NewServiceRegistry()
+	registerTestInstance(t, sr, "api", "api-1")
+	registerTestInstance(t, sr, "api", "api-2")
+	since := sr.Snapshot(DefaultNamespace).Revision
+
+	// 新增后又修改的实例计为新增，修改后又删除的实例计为删除
+	registerTestInstance(t, sr, "api", "api-3")
+	if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-3", &HeartbeatReport{Status: StatusDegraded}); err != nil {
+		t.Fatalf("更新心跳失败: %v", err)
+	}
+	if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", &HeartbeatReport{Status: StatusDegraded}); err != nil {
+		t.Fatalf("更新心跳失败: %v", err)
+	}
+	if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-2", &HeartbeatReport{Status: StatusDegraded}); err != nil {
+		t.Fatalf("更新心跳失败: %v", err)
+	}
+	if err := sr.DeregisterService(DefaultNamespace, "api", "api-2"); err != nil {
+		t.Fatalf("注销实例失败: %v", err)
+	}
+	// 其他命名空间的变化不计入
+	if err := sr.RegisterService(&Service{Name: "api", ID: "other", Hostname: "h", IP: "10.0.0.1", Port: 80, Namespace: "staging"}); err != nil {
+		t.Fatalf("注册实例失败: %v", err)
+	}
+
+	delta := sr.Delta(DefaultNamespace, since)
+	if delta.Full {
+		t.Fatal("保留范围内的增量请求不应返回全量数据")
+	}
+	if got := instanceIDs(delta.Added); got != "api-3" {
+		t.Errorf("Added = %s, 期望 api-3", got)
+	}
+	if got := instanceIDs(delta.Modified); got != "api-1" {
+		t.Errorf("Modified = %s, 期望 api-1", got)
+	}
+	if got := instanceIDs(delta.Removed); got != "api-2" {
+		t.Errorf("Removed = %s, 期望 api-2", got)
+	}
+	if snapshot := sr.Snapshot(DefaultNamespace); delta.Revision != snapshot.Revision || delta.Hash != snapshot.Hash {
+		t.Errorf("Delta 版本 %d/%s 与快照 %d/%s 不一致", delta.Revision, delta.Hash, snapshot.Revision, snapshot.Hash)
+	}
+
+	// 心跳续约不产生变更
+	current := delta.Revision
+	if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", &HeartbeatReport{Status: StatusDegraded}); err != nil {
+		t.Fatalf("更新心跳失败: %v", err)
+	}
+	if delta := sr.Delta(DefaultNamespace, current); delta.Full || len(delta.Added)+len(delta.Modified)+len(delta.Removed) != 0 {
+		t.Errorf("心跳续约后 Delta = %+v, 期望没有变化", delta)
+	}
+}
+
+func TestDeltaFallsBackToFull(t *testing.T) {
+	sr := NewServiceRegistry()
+	registerTestInstance(t, sr, "api", "api-1")
+	registerTestInstance(t, sr, "api", "api-2")
+	registerTestInstance(t, sr, "web", "web-1")
+	current := sr.Snapshot(DefaultNamespace).Revision
+
+	// 模拟变更记录超出保留时长被清理
+	sr.mutex.Lock()
+	sr.pruneChanges(time.Now().Add(deltaRetention + time.Minute))
+	sr.mutex.Unlock()
+	registerTestInstance(t, sr, "api", "api-3")
+	latest := sr.Snapshot(DefaultNamespace).Revision
+
+	tests := []struct {
+		name     string
+		since    uint64
+		wantFull bool
+	}{
+		{name: "早于保留的变更记录", since: 1, wantFull: true},
+		{name: "从零开始同步", since: 0, wantFull: true},
+		{name: "大于当前版本", since: latest + 10, wantFull: true},
+		{name: "等于清理后的最早版本", since: current, wantFull: false},
+		{name: "等于当前版本", since: latest, wantFull: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := sr.Delta(DefaultNamespace, tt.since)
+			if delta.Full != tt.wantFull {
+				t.Fatalf("Full = %v, 期望 %v", delta.Full, tt.wantFull)
+			}
+			if delta.Revision != latest {
+				t.Errorf("Revision = %d, 期望 %d", delta.Revision, latest)
+			}
+			if !tt.wantFull {
+				if delta.Services != nil {
+					t.Error("增量数据不应包含全量服务列表")
+				}
+				return
+			}
+			if len(delta.Added)+len(delta.Modified)+len(delta.Removed) != 0 {
+				t.Error("全量数据不应包含增量变化")
+			}
+			if got := instanceIDs(delta.Services["api"]); got != "api-1,api-2,api-3" {
+				t.Errorf("Services[api] = %s, 期望 api-1,api-2,api-3", got)
+			}
+			if got := instanceIDs(delta.Services["web"]); got != "web-1" {
+				t.Errorf("Services[web] = %s, 期望 web-1", got)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsReservedServiceName(t *testing.T) {
+	sr := NewServiceRegistry()
+	err := sr.RegisterService(&Service{Name: "delta", ID: "1", Hostname: "h", IP: "10.0.0.1", Port: 80})
+	if !errors.Is(err, ErrInvalidService) {
+		t.Fatalf("错误 = %v, 期望 %v", err, ErrInvalidService)
+	}
+	if services := sr.ListAllServices(DefaultNamespace); len(services) != 0 {
+		t.Errorf("保留名称的服务不应被注册: %v", services)
+	}
+}
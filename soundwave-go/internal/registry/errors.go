@@ -0,0 +1,28 @@
+package registry
+
+import (
+	apperrors "soundwave-go/internal/errors"
+)
+
+var (
+	// ErrServiceNotFound 服务不存在
+	ErrServiceNotFound = apperrors.NotFound("SERVICE_NOT_FOUND", "服务不存在")
+	// ErrInstanceNotFound 服务实例不存在
+	ErrInstanceNotFound = apperrors.NotFound("INSTANCE_NOT_FOUND", "服务实例不存在")
+	// ErrNoAvailableInstance 服务没有健康的实例
+	ErrNoAvailableInstance = apperrors.Unavailable("NO_AVAILABLE_INSTANCE", "没有可用的服务实例")
+	// ErrInvalidService 服务注册信息不合法
+	ErrInvalidService = apperrors.Validation("INVALID_SERVICE", "无效的服务信息")
+	// ErrRevisionConflict 更新实例时实例已被修改
+	ErrRevisionConflict = apperrors.Conflict("REVISION_CONFLICT", "服务实例已被修改")
+	// ErrInvalidInstanceToken 实例更新自身信息时未携带实例令牌或令牌不匹配
+	ErrInvalidInstanceToken = apperrors.Unauthorized("INVALID_INSTANCE_TOKEN", "实例令牌无效")
+	// ErrInvalidNamespace 命名空间名称不合法
+	ErrInvalidNamespace = apperrors.Validation("INVALID_NAMESPACE", "无效的命名空间")
+	// ErrNamespaceRestricted 匿名调用方读取受限的命名空间
+	ErrNamespaceRestricted = apperrors.Forbidden("NAMESPACE_RESTRICTED", "该命名空间需登录后访问")
+	// ErrInvalidQuery 实例查询条件不合法
+	ErrInvalidQuery = apperrors.Validation("INVALID_QUERY", "无效的查询条件")
+	// ErrInvalidTrafficRule 路由规则不合法
+	ErrInvalidTrafficRule = apperrors.Validation("INVALID_TRAFFIC_RULE", "无效的路由规则")
+)
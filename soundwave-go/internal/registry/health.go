@@ -19,5 +19,5 @@ func NewDefaultHealthCheck(timeout time.Duration) *DefaultHealthCheck {
 }
 
 func (hc *DefaultHealthCheck) Check(service *Service) bool {
-	return service.Status == StatusUP && time.Since(service.LastHeartbeat) <= hc.timeout
+	return service.Status.Available() && time.Since(service.LastHeartbeat) <= hc.timeout
 }
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HeartbeatReport 实例通过心跳上报的状态、依赖的健康情况和负载，各字段均可为空
+type HeartbeatReport struct {
+	// Status 实例自身判断的状态，只能为 UP、DEGRADED 或 STARTING，为空时保持当前状态
+	Status ServiceStatus
+	// Checks 各依赖的健康检查结果，键为依赖名称，如 db、cache，为空时保持上一次上报的结果
+	Checks map[string]DependencyHealth
+	Load   *LoadReport
+}
+
+// DependencyHealth 实例依赖的健康检查结果
+type DependencyHealth struct {
+	// Status 依赖的状态，只能为 UP、DEGRADED 或 DOWN
+	Status    ServiceStatus `json:"status"`
+	Message   string        `json:"message,omitempty"`
+	LatencyMs float64       `json:"latency_ms,omitempty"`
+}
+
+// InstanceHealth 注册中心记录的实例最近一次上报的依赖健康情况，变化不视为实例变化
+type InstanceHealth struct {
+	Checks     map[string]DependencyHealth `json:"checks"`
+	ReportedAt time.Time                   `json:"reported_at"`
+}
+
+// reportableStatuses 实例可以通过心跳上报的状态
+var reportableStatuses = map[ServiceStatus]bool{
+	StatusUP:       true,
+	StatusDegraded: true,
+	StatusStarting: true,
+}
+
+// validate 校验上报的内容
+func (r *HeartbeatReport) validate() error {
+	if r.Status != "" && !reportableStatuses[r.Status] {
+		return ErrInvalidService.WithMessage(fmt.Sprintf("无效的实例状态: %s，可选 UP、DEGRADED、STARTING", r.Status))
+	}
+	for name, check := range r.Checks {
+		switch check.Status {
+		case StatusUP, StatusDegraded, StatusDOWN:
+		default:
+			return ErrInvalidService.WithMessage(fmt.Sprintf("依赖 %s 的状态无效: %s，可选 UP、DEGRADED、DOWN", name, check.Status))
+		}
+	}
+	if r.Load != nil && (r.Load.InFlight < 0 || r.Load.LatencyMs < 0) {
+		return ErrInvalidService.WithMessage("负载数据不能为负数")
+	}
+	return nil
+}
+
+// unhealthyChecks 描述状态不为 UP 的依赖，如 cache=DEGRADED,db=DOWN(连接超时)，全部正常时返回空字符串
+func unhealthyChecks(checks map[string]DependencyHealth) string {
+	names := make([]string, 0, len(checks))
+	for name, check := range checks {
+		if check.Status != StatusUP {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		item := fmt.Sprintf("%s=%s", name, checks[name].Status)
+		if message := checks[name].Message; message != "" {
+			item += "(" + message + ")"
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ",")
+}
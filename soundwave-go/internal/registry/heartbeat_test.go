@@ -0,0 +1,199 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// instanceOf 返回注册表中实例的当前指针
+func instanceOf(t *testing.T, sr *ServiceRegistry, name, id string) *Service {
+	t.Helper()
+	for _, service := range sr.ListAllServices(DefaultNamespace)[name] {
+		if service.ID == id {
+			return service
+		}
+	}
+	t.Fatalf("实例 %s 不存在", id)
+	return nil
+}
+
+func TestUpdateHeartbeatStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		registered ServiceStatus
+		reports    []*HeartbeatReport
+		want       ServiceStatus
+	}{
+		{name: "没有请求体的心跳保持DEGRADED", registered: StatusDegraded, reports: []*HeartbeatReport{nil}, want: StatusDegraded},
+		{name: "没有状态的心跳保持STARTING", registered: StatusStarting, reports: []*HeartbeatReport{{Load: &LoadReport{InFlight: 1}}}, want: StatusStarting},
+		{name: "只上报依赖时保持状态", registered: StatusDegraded, reports: []*HeartbeatReport{{Checks: map[string]DependencyHealth{"db": {Status: StatusUP}}}}, want: StatusDegraded},
+		{name: "显式上报UP", registered: StatusStarting, reports: []*HeartbeatReport{{Status: StatusUP}}, want: StatusUP},
+		{name: "DEGRADED后续约", registered: StatusUP, reports: []*HeartbeatReport{{Status: StatusDegraded}, {}, nil}, want: StatusDegraded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := NewServiceRegistry()
+			if err := sr.RegisterService(&Service{Name: "api", ID: "api-1", Hostname: "h", IP: "10.0.0.1", Port: 80, Status: tt.registered}); err != nil {
+				t.Fatalf("注册失败: %v", err)
+			}
+			for _, report := range tt.reports {
+				if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", report); err != nil {
+					t.Fatalf("更新心跳失败: %v", err)
+				}
+			}
+			if got := instanceOf(t, sr, "api", "api-1").Status; got != tt.want {
+				t.Errorf("Status = %s, 期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateHeartbeatCopiesOnWrite(t *testing.T) {
+	sr := NewServiceRegistry()
+	if err := sr.RegisterService(&Service{Name: "api", ID: "api-1", Hostname: "h", IP: "10.0.0.1", Port: 80, Zone: "z1"}); err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+
+	// 续约不替换实例
+	before := instanceOf(t, sr, "api", "api-1")
+	if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", nil); err != nil {
+		t.Fatalf("更新心跳失败: %v", err)
+	}
+	if instanceOf(t, sr, "api", "api-1") != before {
+		t.Error("没有上报内容的心跳不应替换实例")
+	}
+
+	// 负载和依赖健康情况变化时替换实例，但不产生变更
+	revision := sr.Snapshot(DefaultNamespace).Revision
+	report := &HeartbeatReport{
+		Load:   &LoadReport{InFlight: 3, LatencyMs: 12},
+		Checks: map[string]DependencyHealth{"db": {Status: StatusUP}},
+	}
+	if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", report); err != nil {
+		t.Fatalf("更新心跳失败: %v", err)
+	}
+	after := instanceOf(t, sr, "api", "api-1")
+	if after == before {
+		t.Fatal("上报负载后应替换实例")
+	}
+	if before.Load != nil || before.Health != nil {
+		t.Error("之前返回的实例不应被修改")
+	}
+	if after.Load == nil || after.Load.InFlight != 3 || after.Health == nil || after.Health.Checks["db"].Status != StatusUP {
+		t.Errorf("Load = %+v, Health = %+v", after.Load, after.Health)
+	}
+	if got := sr.Snapshot(DefaultNamespace).Revision; got != revision {
+		t.Errorf("负载变化后版本号 = %d, 期望不变 %d", got, revision)
+	}
+
+	// 状态变化时替换实例并产生变更，未上报的依赖健康情况保持不变
+	if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", &HeartbeatReport{Status: StatusDegraded}); err != nil {
+		t.Fatalf("更新心跳失败: %v", err)
+	}
+	degraded := instanceOf(t, sr, "api", "api-1")
+	if after.Status != StatusUP {
+		t.Errorf("之前返回的实例状态被修改为 %s", after.Status)
+	}
+	if degraded.Status != StatusDegraded || degraded.Health == nil {
+		t.Errorf("Status = %s, Health = %+v", degraded.Status, degraded.Health)
+	}
+	if delta := sr.Delta(DefaultNamespace, revision); len(delta.Modified) != 1 || delta.Modified[0] != degraded {
+		t.Errorf("状态变化后 Delta.Modified = %v", delta.Modified)
+	}
+
+	// 索引中的实例同步替换，按条件查询返回新的副本
+	found, err := sr.FindServices(DefaultNamespace, "api", &InstanceQuery{Zone: "z1"})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(found) != 1 || found[0] != degraded {
+		t.Errorf("查询结果 = %v, 期望当前实例", found)
+	}
+}
+
+func TestUpdateHeartbeatRejectsInvalidReport(t *testing.T) {
+	sr := NewServiceRegistry()
+	registerTestInstance(t, sr, "api", "api-1")
+
+	reports := []*HeartbeatReport{
+		{Status: StatusDOWN},
+		{Status: "BROKEN"},
+		{Checks: map[string]DependencyHealth{"db": {Status: StatusStarting}}},
+		{Load: &LoadReport{InFlight: -1}},
+	}
+	for _, report := range reports {
+		if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", report); !errors.Is(err, ErrInvalidService) {
+			t.Errorf("上报 %+v，错误 = %v, 期望 %v", report, err, ErrInvalidService)
+		}
+	}
+	if got := instanceOf(t, sr, "api", "api-1").Status; got != StatusUP {
+		t.Errorf("无效上报后 Status = %s, 期望 UP", got)
+	}
+}
+
+// expireInstance 将实例的最后心跳时间改为已超过过期时间
+func expireInstance(t *testing.T, sr *ServiceRegistry, name, id string) {
+	t.Helper()
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	for _, service := range sr.namespaces[DefaultNamespace].services {
+		if service.Name == name && service.ID == id {
+			service.LastHeartbeat = time.Now().Add(-2 * serviceExpiration)
+			return
+		}
+	}
+	t.Fatalf("实例 %s 不存在", id)
+}
+
+func TestCheckServicesHealthCopiesOnWrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		preserving bool
+		wantKept   bool
+	}{
+		{name: "心跳超时后剔除", preserving: false, wantKept: false},
+		{name: "自我保护期间保留心跳超时的实例", preserving: true, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := NewServiceRegistry()
+			sr.preservation.status.Active = tt.preserving
+			registerTestInstance(t, sr, "api", "api-1")
+			registerTestInstance(t, sr, "api", "api-2")
+			expireInstance(t, sr, "api", "api-1")
+
+			snapshot := instanceOf(t, sr, "api", "api-1")
+			sr.checkServicesHealth()
+			if snapshot.Status != StatusUP || snapshot.heartbeatLost {
+				t.Errorf("之前返回的实例被修改: Status = %s, heartbeatLost = %v", snapshot.Status, snapshot.heartbeatLost)
+			}
+
+			var current *Service
+			for _, service := range sr.ListAllServices(DefaultNamespace)["api"] {
+				if service.ID == "api-1" {
+					current = service
+				}
+			}
+			if (current != nil) != tt.wantKept {
+				t.Fatalf("实例保留 = %v, 期望 %v", current != nil, tt.wantKept)
+			}
+			if current == nil {
+				return
+			}
+			if current == snapshot || !current.heartbeatLost {
+				t.Fatal("心跳丢失时应以副本替换实例")
+			}
+
+			// 恢复心跳同样替换实例
+			if err := sr.UpdateHeartbeat(DefaultNamespace, "api", "api-1", nil); err != nil {
+				t.Fatalf("更新心跳失败: %v", err)
+			}
+			if recovered := instanceOf(t, sr, "api", "api-1"); recovered == current || recovered.heartbeatLost || !current.heartbeatLost {
+				t.Error("恢复心跳时应以副本替换实例，之前返回的实例不应被修改")
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"soundwave-go/internal/logger"
+)
+
+// HistoryPoint 服务在一个采样周期内的实例数量、心跳和注册变化情况。
+// 多个采样点可以合并为更大周期的点，Samples 记录合并了多少个原始采样
+type HistoryPoint struct {
+	// Time 采样周期的开始时间
+	Time time.Time `json:"time"`
+	// Healthy 和 Unhealthy 为周期内健康、异常实例数的平均值，被摘除的实例计为异常
+	Healthy      float64 `json:"healthy"`
+	Unhealthy    float64 `json:"unhealthy"`
+	MinHealthy   int     `json:"min_healthy"`
+	MaxUnhealthy int     `json:"max_unhealthy"`
+	Heartbeats   int     `json:"heartbeats"`
+	// LatenessAvg 和 LatenessMax 为心跳间隔超过配置的心跳间隔的秒数
+	LatenessAvg float64 `json:"lateness_avg"`
+	LatenessMax float64 `json:"lateness_max"`
+	// Registrations 新实例注册的次数，实例重新注册不计入
+	Registrations   int `json:"registrations"`
+	Deregistrations int `json:"deregistrations"`
+	Expirations     int `json:"expirations"`
+	Samples         int `json:"samples"`
+}
+
+// HistorySeries 一个服务的历史数据
+type HistorySeries struct {
+	Namespace string
+	Service   string
+	Points    []HistoryPoint
+}
+
+// history 各服务的历史数据，每个服务一个固定容量的环形缓冲区，由注册中心的 mutex 保护
+type history struct {
+	enabled           bool
+	resolution        time.Duration
+	heartbeatInterval time.Duration
+	capacity          int
+	series            map[string]*historyRing        // <namespace>/<service> -> 采样点
+	pending           map[string]*historyAccumulator // <namespace>/<service> -> 当前采样周期内的累计值
+}
+
+// historyAccumulator 当前采样周期内累计的心跳和注册变化
+type historyAccumulator struct {
+	heartbeats      int
+	latenessSum     float64
+	latenessMax     float64
+	registrations   int
+	deregistrations int
+	expirations     int
+}
+
+// historyRing 固定容量的环形缓冲区，写满后覆盖最早的采样点
+type historyRing struct {
+	namespace string
+	service   string
+	points    []HistoryPoint
+	next      int
+	full      bool
+}
+
+func (r *historyRing) push(point HistoryPoint) {
+	r.points[r.next] = point
+	r.next = (r.next + 1) % len(r.points)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// between 按时间顺序返回 [since, until) 内的采样点
+func (r *historyRing) between(since, until time.Time) []HistoryPoint {
+	result := make([]HistoryPoint, 0)
+	start, size := 0, r.next
+	if r.full {
+		start, size = r.next, len(r.points)
+	}
+	for i := 0; i < size; i++ {
+		point := r.points[(start+i)%len(r.points)]
+		if !point.Time.Before(since) && point.Time.Before(until) {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+// latest 最近一个采样点的时间
+func (r *historyRing) latest() time.Time {
+	if r.next == 0 && !r.full {
+		return time.Time{}
+	}
+	return r.points[(r.next-1+len(r.points))%len(r.points)].Time
+}
+
+// WithHistory 启用历史数据采样，每 resolution 采样一次，内存中保留最近 retention 的采样点。
+// heartbeatInterval 为实例的心跳间隔，用于计算心跳延迟
+func WithHistory(resolution, retention, heartbeatInterval time.Duration) RegistryOption {
+	return func(sr *ServiceRegistry) {
+		if resolution <= 0 || retention < resolution {
+			return
+		}
+		sr.history = history{
+			enabled:           true,
+			resolution:        resolution,
+			heartbeatInterval: heartbeatInterval,
+			capacity:          int(retention / resolution),
+			series:            make(map[string]*historyRing),
+			pending:           make(map[string]*historyAccumulator),
+		}
+	}
+}
+
+// historyKey 返回服务历史数据的键，命名空间名称不包含 /
+func historyKey(namespaceName, name string) string {
+	return namespaceName + "/" + name
+}
+
+// historyPendingLocked 返回服务当前采样周期的累计值，未启用历史数据时返回 nil。调用方需持有写锁
+func (sr *ServiceRegistry) historyPendingLocked(namespaceName, name string) *historyAccumulator {
+	if !sr.history.enabled {
+		return nil
+	}
+	key := historyKey(namespaceName, name)
+	acc, ok := sr.history.pending[key]
+	if !ok {
+		acc = &historyAccumulator{}
+		sr.history.pending[key] = acc
+	}
+	return acc
+}
+
+// recordHeartbeatLocked 记录一次心跳及其距上一次心跳的间隔。调用方需持有写锁
+func (sr *ServiceRegistry) recordHeartbeatLocked(service *Service, gap time.Duration) {
+	acc := sr.historyPendingLocked(service.Namespace, service.Name)
+	if acc == nil {
+		return
+	}
+	lateness := (gap - sr.history.heartbeatInterval).Seconds()
+	if lateness < 0 {
+		lateness = 0
+	}
+	acc.heartbeats++
+	acc.latenessSum += lateness
+	if lateness > acc.latenessMax {
+		acc.latenessMax = lateness
+	}
+}
+
+// StartHistory 启动历史数据采样，未启用历史数据时不做任何事
+func (sr *ServiceRegistry) StartHistory(ctx context.Context) {
+	if !sr.history.enabled {
+		return
+	}
+	logger.InfoLogger.Printf("启动历史数据采样，间隔时间：%v", sr.history.resolution)
+	go func() {
+		// 对齐到采样间隔的整数倍，便于降采样时按时间分组
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(sr.history.resolution).Add(sr.history.resolution).Sub(now))
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-timer.C:
+				sr.sampleHistory(now.Truncate(sr.history.resolution).Add(-sr.history.resolution))
+				timer.Reset(now.Truncate(sr.history.resolution).Add(sr.history.resolution).Sub(time.Now()))
+			}
+		}
+	}()
+}
+
+// sampleHistory 为每个服务记录一个开始时间为 periodStart 的采样点
+func (sr *ServiceRegistry) sampleHistory(periodStart time.Time) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	h := &sr.history
+	sampled := make(map[string]bool)
+	sample := func(namespaceName, name string, healthy, unhealthy int) {
+		key := historyKey(namespaceName, name)
+		sampled[key] = true
+		point := HistoryPoint{
+			Time:         periodStart,
+			Healthy:      float64(healthy),
+			Unhealthy:    float64(unhealthy),
+			MinHealthy:   healthy,
+			MaxUnhealthy: unhealthy,
+			Samples:      1,
+		}
+		if acc, ok := h.pending[key]; ok {
+			point.Heartbeats = acc.heartbeats
+			if acc.heartbeats > 0 {
+				point.LatenessAvg = acc.latenessSum / float64(acc.heartbeats)
+			}
+			point.LatenessMax = acc.latenessMax
+			point.Registrations = acc.registrations
+			point.Deregistrations = acc.deregistrations
+			point.Expirations = acc.expirations
+		}
+		ring, ok := h.series[key]
+		if !ok {
+			ring = &historyRing{namespace: namespaceName, service: name, points: make([]HistoryPoint, h.capacity)}
+			h.series[key] = ring
+		}
+		ring.push(point)
+	}
+
+	for namespaceName, ns := range sr.namespaces {
+		for name, uniqueIDs := range ns.serviceMap {
+			stats := sr.serviceStatsLocked(ns, name, uniqueIDs)
+			sample(namespaceName, name, stats.HealthyInstances, stats.UnhealthyInstances)
+		}
+	}
+	// 周期内实例全部下线的服务记录一个实例数为 0 的采样点
+	for key := range h.pending {
+		if !sampled[key] {
+			namespaceName, name, _ := strings.Cut(key, "/")
+			sample(namespaceName, name, 0, 0)
+		}
+		delete(h.pending, key)
+	}
+	// 服务下线且采样点全部过期后释放缓冲区
+	expiry := periodStart.Add(-time.Duration(h.capacity) * h.resolution)
+	for key, ring := range h.series {
+		if !sampled[key] && ring.latest().Before(expiry) {
+			delete(h.series, key)
+		}
+	}
+}
+
+// History 返回服务在 [since, until) 内的原始采样点，按时间排序
+func (sr *ServiceRegistry) History(namespaceName, name string, since, until time.Time) []HistoryPoint {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	ring, ok := sr.history.series[historyKey(namespaceName, name)]
+	if !ok {
+		return make([]HistoryPoint, 0)
+	}
+	return ring.between(since, until)
+}
+
+// DownsampleHistory 将所有服务在 [since, until) 内的采样点各合并为一个开始时间为 since 的点，
+// 没有采样点的服务不返回
+func (sr *ServiceRegistry) DownsampleHistory(since, until time.Time) []HistorySeries {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	result := make([]HistorySeries, 0, len(sr.history.series))
+	for _, ring := range sr.history.series {
+		points := ring.between(since, until)
+		if len(points) == 0 {
+			continue
+		}
+		result = append(result, HistorySeries{
+			Namespace: ring.namespace,
+			Service:   ring.service,
+			Points:    []HistoryPoint{MergeHistoryPoints(since, points)},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Service < result[j].Service
+	})
+	return result
+}
+
+// MergeHistoryPoints 将多个采样点合并为一个开始时间为 start 的点，平均值按采样数和心跳数加权
+func MergeHistoryPoints(start time.Time, points []HistoryPoint) HistoryPoint {
+	merged := HistoryPoint{Time: start}
+	var healthy, unhealthy, lateness float64
+	for i, point := range points {
+		if i == 0 || point.MinHealthy < merged.MinHealthy {
+			merged.MinHealthy = point.MinHealthy
+		}
+		if point.MaxUnhealthy > merged.MaxUnhealthy {
+			merged.MaxUnhealthy = point.MaxUnhealthy
+		}
+		if point.LatenessMax > merged.LatenessMax {
+			merged.LatenessMax = point.LatenessMax
+		}
+		healthy += point.Healthy * float64(point.Samples)
+		unhealthy += point.Unhealthy * float64(point.Samples)
+		lateness += point.LatenessAvg * float64(point.Heartbeats)
+		merged.Heartbeats += point.Heartbeats
+		merged.Registrations += point.Registrations
+		merged.Deregistrations += point.Deregistrations
+		merged.Expirations += point.Expirations
+		merged.Samples += point.Samples
+	}
+	if merged.Samples > 0 {
+		merged.Healthy = healthy / float64(merged.Samples)
+		merged.Unhealthy = unhealthy / float64(merged.Samples)
+	}
+	if merged.Heartbeats > 0 {
+		merged.LatenessAvg = lateness / float64(merged.Heartbeats)
+	}
+	return merged
+}
+
+// DownsampleHistoryPoints 按 step 对齐的时间段合并已按时间排序的采样点
+func DownsampleHistoryPoints(points []HistoryPoint, step time.Duration) []HistoryPoint {
+	result := make([]HistoryPoint, 0)
+	for i := 0; i < len(points); {
+		start := points[i].Time.Truncate(step)
+		j := i + 1
+		for j < len(points) && points[j].Time.Before(start.Add(step)) {
+			j++
+		}
+		result = append(result, MergeHistoryPoints(start, points[i:j]))
+		i = j
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package registry
+
+import "soundwave-go/pkg/routing"
+
+// idSet uniqueID 集合
+type idSet map[string]struct{}
+
+// serviceIndex 单个服务的实例倒排索引，用于按标签、元数据和可用区查询实例
+type serviceIndex struct {
+	tags     map[string]idSet            // 标签 -> uniqueID 集合
+	zones    map[string]idSet            // 可用区 -> uniqueID 集合
+	metadata map[string]map[string]idSet // 元数据键 -> 值 -> uniqueID 集合
+}
+
+func newServiceIndex() *serviceIndex {
+	return &serviceIndex{
+		tags:     make(map[string]idSet),
+		zones:    make(map[string]idSet),
+		metadata: make(map[string]map[string]idSet),
+	}
+}
+
+// add 将实例加入索引
+func (idx *serviceIndex) add(service *Service) {
+	uniqueID := service.UniqueID()
+	for _, tag := range service.Tags {
+		addToSet(idx.tags, tag, uniqueID)
+	}
+	if service.Zone != "" {
+		addToSet(idx.zones, service.Zone, uniqueID)
+	}
+	for key, value := range service.Metadata {
+		values, ok := idx.metadata[key]
+		if !ok {
+			values = make(map[string]idSet)
+			idx.metadata[key] = values
+		}
+		addToSet(values, value, uniqueID)
+	}
+}
+
+// remove 将实例移出索引
+func (idx *serviceIndex) remove(service *Service) {
+	uniqueID := service.UniqueID()
+	for _, tag := range service.Tags {
+		removeFromSet(idx.tags, tag, uniqueID)
+	}
+	if service.Zone != "" {
+		removeFromSet(idx.zones, service.Zone, uniqueID)
+	}
+	for key, value := range service.Metadata {
+		if values, ok := idx.metadata[key]; ok {
+			removeFromSet(values, value, uniqueID)
+			if len(values) == 0 {
+				delete(idx.metadata, key)
+			}
+		}
+	}
+}
+
+// candidates 根据查询中可由索引确定的条件（标签、可用区、等值和集合匹配、键存在）求实例集合的交集，
+// 返回 false 表示查询不包含这类条件，需要遍历服务的全部实例
+func (idx *serviceIndex) candidates(query *InstanceQuery) (idSet, bool) {
+	var result idSet
+	narrowed := false
+	intersect := func(set idSet) {
+		if !narrowed {
+			result = set
+			narrowed = true
+			return
+		}
+		next := make(idSet)
+		for id := range result {
+			if _, ok := set[id]; ok {
+				next[id] = struct{}{}
+			}
+		}
+		result = next
+	}
+
+	for _, tag := range query.Tags {
+		intersect(idx.tags[tag])
+	}
+	if query.Zone != "" {
+		intersect(idx.zones[query.Zone])
+	}
+	for _, r := range query.Selector {
+		values := idx.metadata[r.Key]
+		switch r.Operator {
+		case routing.SelectorEquals, routing.SelectorIn:
+			union := make(idSet)
+			for _, value := range r.Values {
+				for id := range values[value] {
+					union[id] = struct{}{}
+				}
+			}
+			intersect(union)
+		case routing.SelectorExists:
+			union := make(idSet)
+			for _, ids := range values {
+				for id := range ids {
+					union[id] = struct{}{}
+				}
+			}
+			intersect(union)
+		}
+	}
+	return result, narrowed
+}
+
+func addToSet(index map[string]idSet, key, uniqueID string) {
+	ids, ok := index[key]
+	if !ok {
+		ids = make(idSet)
+		index[key] = ids
+	}
+	ids[uniqueID] = struct{}{}
+}
+
+func removeFromSet(index map[string]idSet, key, uniqueID string) {
+	if ids, ok := index[key]; ok {
+		delete(ids, uniqueID)
+		if len(ids) == 0 {
+			delete(index, key)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package registry
+
+import "time"
+
+// LoadReport 实例通过心跳上报的负载
+type LoadReport struct {
+	// InFlight 正在处理的请求数
+	InFlight int `json:"in_flight"`
+	// LatencyMs 请求耗时的指数加权移动平均，单位毫秒
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// LoadStats 注册中心记录的实例负载
+type LoadStats struct {
+	InFlight   int       `json:"in_flight"`
+	LatencyMs  float64   `json:"latency_ms"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// loadStaleAfter 超过该时间未更新的负载视为无效
+const loadStaleAfter = serviceExpiration
+
+// currentLoad 返回仍然有效的负载，没有上报或已过期时返回 nil
+func (s *Service) currentLoad(now time.Time) *LoadStats {
+	if s.Load == nil || now.Sub(s.Load.ReportedAt) > loadStaleAfter {
+		return nil
+	}
+	return s.Load
+}
@@ -0,0 +1,197 @@
+package registry
+
+import (
+	"math/rand"
+	"soundwave-go/internal/metrics"
+	"soundwave-go/pkg/routing"
+	"sync"
+	"time"
+)
+
+// DefaultSpilloverThreshold 调用方可用区内健康实例占比低于该值时开始向其他可用区溢出
+const DefaultSpilloverThreshold = 0.7
+
+// 负载均衡选择实例的范围
+const (
+	// ScopeZone 调用方所在可用区
+	ScopeZone = "zone"
+	// ScopeRegion 调用方所在地域的其他可用区
+	ScopeRegion = "region"
+	// ScopeAny 所有实例
+	ScopeAny = "any"
+)
+
+// Locality 调用方所在的地域和可用区
+type Locality struct {
+	Region string `json:"region,omitempty"`
+	Zone   string `json:"zone,omitempty"`
+}
+
+// SelectionDecision 就近选择实例的决策过程，随实例一起返回给调用方
+type SelectionDecision struct {
+	Caller Locality `json:"caller"`
+	// Scope 最终选择实例的范围：zone、region 或 any
+	Scope string `json:"scope"`
+	// LocalHealthy 调用方可用区内的健康实例数
+	LocalHealthy int `json:"local_healthy"`
+	// LocalTotal 调用方可用区内注册的实例总数，包括不健康的实例
+	LocalTotal int `json:"local_total"`
+	// LocalRatio 调用方可用区内健康实例的占比
+	LocalRatio float64 `json:"local_ratio"`
+	// Threshold 溢出阈值
+	Threshold float64 `json:"threshold"`
+	// Spillover 是否因本地容量不足选择了其他可用区的实例
+	Spillover bool `json:"spillover"`
+	// Reason 决策说明
+	Reason string `json:"reason"`
+	// Route 命中的路由规则，未配置或未命中时为空
+	Route *routing.TrafficRoute `json:"route,omitempty"`
+}
+
+// LocalityBalancer 就近负载均衡器，优先选择调用方所在可用区的实例。
+// 本地健康实例占比低于阈值时，按 占比/阈值 的概率留在本地，其余请求溢出到同地域的其他可用区，
+// 同地域没有健康实例时再溢出到所有实例。各范围内的实例由 fallback 负载均衡器选择
+type LocalityBalancer struct {
+	threshold float64
+	fallback  LoadBalancer
+	rand      *rand.Rand
+	mutex     sync.Mutex
+}
+
+// NewLocalityBalancer 创建就近负载均衡器，threshold 不在 (0, 1] 内时使用默认阈值
+func NewLocalityBalancer(threshold float64, fallback LoadBalancer) *LocalityBalancer {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultSpilloverThreshold
+	}
+	if fallback == nil {
+		fallback = NewRandomBalancer()
+	}
+	return &LocalityBalancer{
+		threshold: threshold,
+		fallback:  fallback,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Select 不指定调用方位置时直接使用 fallback 负载均衡器，实现 LoadBalancer 接口
+func (lb *LocalityBalancer) Select(services []*Service) *Service {
+	return lb.fallback.Select(services)
+}
+
+// SelectNear 从健康实例中就近选择，localTotal 为调用方可用区内注册的实例总数
+func (lb *LocalityBalancer) SelectNear(services []*Service, caller Locality, localTotal int) (*Service, *SelectionDecision) {
+	decision := &SelectionDecision{
+		Caller:    caller,
+		Scope:     ScopeAny,
+		Threshold: lb.threshold,
+	}
+	if len(services) == 0 {
+		return nil, decision
+	}
+	if caller.Zone == "" && caller.Region == "" {
+		decision.Reason = "未指定调用方位置"
+		return lb.fallback.Select(services), decision
+	}
+
+	var zone, region []*Service
+	for _, service := range services {
+		switch {
+		case caller.Zone != "" && service.Zone == caller.Zone:
+			zone = append(zone, service)
+		case caller.Region != "" && service.Region == caller.Region:
+			region = append(region, service)
+		}
+	}
+
+	if caller.Zone != "" {
+		decision.LocalHealthy = len(zone)
+		decision.LocalTotal = localTotal
+		if localTotal < len(zone) {
+			decision.LocalTotal = len(zone)
+		}
+		if decision.LocalTotal > 0 {
+			decision.LocalRatio = float64(len(zone)) / float64(decision.LocalTotal)
+		}
+
+		if len(zone) > 0 {
+			if decision.LocalRatio >= lb.threshold {
+				decision.Scope = ScopeZone
+				decision.Reason = "本地可用区容量充足"
+				return lb.fallback.Select(zone), decision
+			}
+			if lb.chance(decision.LocalRatio / lb.threshold) {
+				decision.Scope = ScopeZone
+				decision.Reason = "本地可用区容量低于阈值，按比例保留在本地"
+				return lb.fallback.Select(zone), decision
+			}
+		}
+		decision.Spillover = true
+	}
+
+	if len(region) > 0 {
+		decision.Scope = ScopeRegion
+		decision.Reason = "溢出到同地域的其他可用区"
+		if caller.Zone == "" {
+			decision.Reason = "选择同地域的实例"
+		}
+		return lb.fallback.Select(region), decision
+	}
+
+	// 同地域没有健康实例时从所有实例中选择，包括本地可用区
+	decision.Reason = "同地域没有可用实例，溢出到所有实例"
+	return lb.fallback.Select(services), decision
+}
+
+func (lb *LocalityBalancer) chance(p float64) bool {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	return lb.rand.Float64() < p
+}
+
+// SelectInstance 从满足查询条件的健康实例中选择一个，并返回选择的决策过程。
+// 先按服务的路由规则确定目标实例组，再在组内就近选择
+func (sr *ServiceRegistry) SelectInstance(namespaceName, name string, query *InstanceQuery, caller Locality, route routing.RouteContext) (*Service, *SelectionDecision, error) {
+	services, err := sr.FindServices(namespaceName, name, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	services, traffic := sr.applyTrafficPolicy(namespaceName, name, services, route)
+
+	localTotal := sr.zoneSize(namespaceName, name, caller.Zone, func(service *Service) bool {
+		if !query.Matches(service) {
+			return false
+		}
+		return traffic == nil || traffic.Fallback || traffic.Matches(service.Version, service.Metadata)
+	})
+	service, decision := sr.locality.SelectNear(services, caller, localTotal)
+	if service == nil {
+		return nil, nil, ErrNoAvailableInstance
+	}
+	decision.Route = traffic
+	metrics.BalancerSelections.WithLabelValues(namespaceName, name, service.ID).Inc()
+	metrics.LocalitySelections.WithLabelValues(namespaceName, name, decision.Scope).Inc()
+	return service, decision, nil
+}
+
+// zoneSize 返回服务在可用区内满足条件的实例总数，包括不健康的实例
+func (sr *ServiceRegistry) zoneSize(namespaceName, name, zone string, match func(*Service) bool) int {
+	if zone == "" {
+		return 0
+	}
+
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	if ns := sr.namespaceLocked(namespaceName); ns != nil {
+		if idx, ok := ns.indexes[name]; ok {
+			count := 0
+			for uniqueID := range idx.zones[zone] {
+				if service, ok := ns.services[uniqueID]; ok && match(service) {
+					count++
+				}
+			}
+			return count
+		}
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// localityInstances 按 <zone>:<数量> 生成健康实例，可用区的前缀为地域，如 cn-east-a 属于 cn-east
+func localityInstances(counts map[string]int) []*Service {
+	services := make([]*Service, 0)
+	for zone, n := range counts {
+		for i := 0; i < n; i++ {
+			services = append(services, &Service{
+				Name:   "api",
+				ID:     fmt.Sprintf("%s-%d", zone, i),
+				Region: zone[:len(zone)-2],
+				Zone:   zone,
+			})
+		}
+	}
+	return services
+}
+
+func TestSelectNearScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		instances  map[string]int
+		caller     Locality
+		localTotal int
+		wantScope  string
+		wantSpill  bool
+	}{
+		{name: "未指定调用方位置", instances: map[string]int{"cn-east-a": 2}, wantScope: ScopeAny},
+		{name: "本地容量充足", instances: map[string]int{"cn-east-a": 3, "cn-east-b": 3}, caller: Locality{Region: "cn-east", Zone: "cn-east-a"}, localTotal: 4, wantScope: ScopeZone},
+		{name: "本地没有健康实例时溢出到同地域", instances: map[string]int{"cn-east-b": 2, "cn-north-a": 2}, caller: Locality{Region: "cn-east", Zone: "cn-east-a"}, localTotal: 3, wantScope: ScopeRegion, wantSpill: true},
+		{name: "同地域没有实例时溢出到所有实例", instances: map[string]int{"cn-north-a": 2}, caller: Locality{Region: "cn-east", Zone: "cn-east-a"}, localTotal: 3, wantScope: ScopeAny, wantSpill: true},
+		{name: "只指定地域", instances: map[string]int{"cn-east-b": 1, "cn-north-a": 2}, caller: Locality{Region: "cn-east"}, wantScope: ScopeRegion},
+		{name: "地域不存在", instances: map[string]int{"cn-north-a": 2}, caller: Locality{Region: "cn-east"}, wantScope: ScopeAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLocalityBalancer(0.7, nil)
+			lb.rand = rand.New(rand.NewSource(1))
+			service, decision := lb.SelectNear(localityInstances(tt.instances), tt.caller, tt.localTotal)
+			if service == nil {
+				t.Fatal("没有选中实例")
+			}
+			if decision.Scope != tt.wantScope || decision.Spillover != tt.wantSpill {
+				t.Fatalf("Scope = %s, Spillover = %v, 期望 %s, %v（%s）", decision.Scope, decision.Spillover, tt.wantScope, tt.wantSpill, decision.Reason)
+			}
+			switch tt.wantScope {
+			case ScopeZone:
+				if service.Zone != tt.caller.Zone {
+					t.Errorf("选中 %s, 期望本地可用区 %s 的实例", service.ID, tt.caller.Zone)
+				}
+			case ScopeRegion:
+				if service.Region != tt.caller.Region || service.Zone == tt.caller.Zone {
+					t.Errorf("选中 %s, 期望同地域其他可用区的实例", service.ID)
+				}
+			}
+		})
+	}
+
+	if service, _ := NewLocalityBalancer(0.7, nil).SelectNear(nil, Locality{Zone: "cn-east-a"}, 0); service != nil {
+		t.Errorf("没有实例时选中 %s", service.ID)
+	}
+}
+
+func TestSelectNearSpilloverRatio(t *testing.T) {
+	const rounds = 20000
+
+	tests := []struct {
+		name       string
+		threshold  float64
+		local      int
+		localTotal int
+		// wantLocal 留在本地可用区的期望比例，为 min(1, 本地健康占比/阈值)
+		wantLocal float64
+	}{
+		{name: "占比等于阈值", threshold: 0.7, local: 7, localTotal: 10, wantLocal: 1},
+		{name: "占比为阈值的一半", threshold: 0.7, local: 7, localTotal: 20, wantLocal: 0.5},
+		{name: "占比为阈值的四分之一", threshold: 0.8, local: 2, localTotal: 10, wantLocal: 0.25},
+		{name: "注册总数小于健康数时视为全部健康", threshold: 0.7, local: 3, localTotal: 1, wantLocal: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLocalityBalancer(tt.threshold, nil)
+			lb.rand = rand.New(rand.NewSource(42))
+			services := localityInstances(map[string]int{"cn-east-a": tt.local, "cn-east-b": 5})
+			caller := Locality{Region: "cn-east", Zone: "cn-east-a"}
+
+			local := 0
+			for i := 0; i < rounds; i++ {
+				service, decision := lb.SelectNear(services, caller, tt.localTotal)
+				if service.Zone == caller.Zone {
+					local++
+					if decision.Scope != ScopeZone || decision.Spillover {
+						t.Fatalf("选中本地实例但 Scope = %s, Spillover = %v", decision.Scope, decision.Spillover)
+					}
+				} else if decision.Scope != ScopeRegion || !decision.Spillover {
+					t.Fatalf("选中 %s 但 Scope = %s, Spillover = %v", service.ID, decision.Scope, decision.Spillover)
+				}
+			}
+			if got := float64(local) / rounds; math.Abs(got-tt.wantLocal) > 0.02 {
+				t.Errorf("留在本地的比例 = %.3f, 期望 %.3f", got, tt.wantLocal)
+			}
+		})
+	}
+}
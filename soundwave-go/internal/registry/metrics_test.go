@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"testing"
+
+	"soundwave-go/internal/metrics"
+	"soundwave-go/pkg/routing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBalancerSelectionsDeletedOnDeregister(t *testing.T) {
+	sr := NewServiceRegistry()
+	service := &Service{Namespace: "metrics-test", Name: "api", ID: "api-1", Hostname: "h", IP: "10.0.0.1", Port: 80}
+	if err := sr.RegisterService(service); err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+	if _, _, err := sr.SelectInstance("metrics-test", "api", nil, Locality{}, routing.RouteContext{}); err != nil {
+		t.Fatalf("选择实例失败: %v", err)
+	}
+
+	counter := metrics.BalancerSelections.WithLabelValues("metrics-test", "api", "api-1")
+	if got := testutil.ToFloat64(counter); got != 1 {
+		t.Fatalf("选择次数 = %v, 期望 1", got)
+	}
+
+	if err := sr.DeregisterService("metrics-test", "api", "api-1"); err != nil {
+		t.Fatalf("注销失败: %v", err)
+	}
+	// 序列已删除时 WithLabelValues 重新创建计数为 0 的序列
+	if got := testutil.ToFloat64(metrics.BalancerSelections.WithLabelValues("metrics-test", "api", "api-1")); got != 0 {
+		t.Errorf("注销后选择次数 = %v, 期望序列被删除", got)
+	}
+	metrics.BalancerSelections.DeleteLabelValues("metrics-test", "api", "api-1")
+}
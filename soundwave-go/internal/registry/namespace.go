@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// DefaultNamespace 未指定命名空间时使用的默认命名空间
+const DefaultNamespace = "default"
+
+// namespacePattern 命名空间名称规则，与 DNS 标签一致，便于在 DNS 查询中使用
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// namespace 命名空间内的注册表，不同命名空间的同名服务互相隔离
+type namespace struct {
+	services   map[string]*Service      // uniqueID -> Service
+	serviceMap map[string][]string      // 服务名称 -> uniqueID列表
+	hash       uint64                   // 命名空间内所有实例哈希的异或，用于增量同步的一致性校验
+	indexes    map[string]*serviceIndex // 服务名称 -> 实例索引
+}
+
+func newNamespace() *namespace {
+	return &namespace{
+		services:   make(map[string]*Service),
+		serviceMap: make(map[string][]string),
+		indexes:    make(map[string]*serviceIndex),
+	}
+}
+
+// index 将实例加入所属服务的索引
+func (ns *namespace) index(service *Service) {
+	idx, ok := ns.indexes[service.Name]
+	if !ok {
+		idx = newServiceIndex()
+		ns.indexes[service.Name] = idx
+	}
+	idx.add(service)
+}
+
+// unindex 将实例移出所属服务的索引，服务没有实例时删除索引
+func (ns *namespace) unindex(service *Service) {
+	if idx, ok := ns.indexes[service.Name]; ok {
+		idx.remove(service)
+		if len(ns.serviceMap[service.Name]) == 0 {
+			delete(ns.indexes, service.Name)
+		}
+	}
+}
+
+// replace 以副本替换注册表和索引中的实例，已返回给调用方的实例不受影响
+func (ns *namespace) replace(service, updated *Service) {
+	ns.unindex(service)
+	ns.services[service.UniqueID()] = updated
+	ns.index(updated)
+}
+
+// NamespaceInfo 命名空间概况
+type NamespaceInfo struct {
+	Name      string `json:"name"`
+	Services  int    `json:"services"`
+	Instances int    `json:"instances"`
+}
+
+// NormalizeNamespace 空值视为默认命名空间，并校验名称格式
+func NormalizeNamespace(name string) (string, error) {
+	if name == "" {
+		return DefaultNamespace, nil
+	}
+	if len(name) > 63 || !namespacePattern.MatchString(name) {
+		return "", ErrInvalidNamespace.WithMessage(fmt.Sprintf("无效的命名空间: %s", name))
+	}
+	return name, nil
+}
+
+// WithRestrictedNamespaces 设置受限的命名空间。匿名调用方（未登录的 HTTP 接口、gRPC 和 DNS）不能读取其中的服务，
+// 实例仍可注册、发送心跳和上报调用结果
+func WithRestrictedNamespaces(names []string) RegistryOption {
+	return func(sr *ServiceRegistry) {
+		sr.restrictedNamespaces = make(map[string]bool, len(names))
+		for _, name := range names {
+			sr.restrictedNamespaces[name] = true
+		}
+	}
+}
+
+// NamespaceRestricted 判断命名空间是否受限，受限的命名空间只能由有权限的登录用户读取
+func (sr *ServiceRegistry) NamespaceRestricted(name string) bool {
+	return sr.restrictedNamespaces[name]
+}
+
+// ListNamespaces 返回所有命名空间的概况，默认命名空间始终存在，按名称排序
+func (sr *ServiceRegistry) ListNamespaces() []NamespaceInfo {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	result := make([]NamespaceInfo, 0, len(sr.namespaces)+1)
+	if _, ok := sr.namespaces[DefaultNamespace]; !ok {
+		result = append(result, NamespaceInfo{Name: DefaultNamespace})
+	}
+	for name, ns := range sr.namespaces {
+		result = append(result, NamespaceInfo{
+			Name:      name,
+			Services:  len(ns.serviceMap),
+			Instances: len(ns.services),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// namespaceLocked 获取命名空间，不存在时返回 nil。调用方需持有锁
+func (sr *ServiceRegistry) namespaceLocked(name string) *namespace {
+	return sr.namespaces[name]
+}
+
+// namespaceForWriteLocked 获取命名空间，不存在时创建。调用方需持有写锁
+func (sr *ServiceRegistry) namespaceForWriteLocked(name string) *namespace {
+	ns, ok := sr.namespaces[name]
+	if !ok {
+		ns = newNamespace()
+		sr.namespaces[name] = ns
+	}
+	return ns
+}
+
+// removeIfEmptyLocked 命名空间内没有服务时删除。调用方需持有写锁
+func (sr *ServiceRegistry) removeIfEmptyLocked(name string) {
+	if ns, ok := sr.namespaces[name]; ok && len(ns.services) == 0 && len(ns.serviceMap) == 0 {
+		delete(sr.namespaces, name)
+	}
+}
@@ -0,0 +1,219 @@
+package registry
+
+import (
+	"fmt"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/metrics"
+	"time"
+)
+
+// OutlierSource 实例调用结果的来源
+type OutlierSource string
+
+const (
+	// SourceCaller 调用方上报的请求结果
+	SourceCaller OutlierSource = "caller"
+	// SourceProbe 注册中心主动探测的结果
+	SourceProbe OutlierSource = "probe"
+)
+
+// OutlierPolicy 异常实例检测策略。实例连续失败次数或统计窗口内的错误率超过阈值时被摘除，
+// 摘除期间不出现在服务发现结果中，摘除时长随摘除次数指数增长
+type OutlierPolicy struct {
+	// ConsecutiveFailures 连续失败多少次后摘除，0 表示不按连续失败摘除
+	ConsecutiveFailures int
+	// ErrorRate 统计窗口内错误率达到该值时摘除，0 表示不按错误率摘除
+	ErrorRate float64
+	// MinRequests 统计窗口内请求数达到该值才计算错误率
+	MinRequests int
+	// Interval 错误率的统计窗口
+	Interval time.Duration
+	// BaseEjectionTime 首次摘除的时长，之后每次摘除翻倍
+	BaseEjectionTime time.Duration
+	// MaxEjectionTime 摘除时长的上限，恢复后超过该时间未再被摘除时重新从首次摘除时长开始计算
+	MaxEjectionTime time.Duration
+	// MaxEjectionPercent 同一服务最多被摘除的实例比例，避免服务整体不可用
+	MaxEjectionPercent float64
+}
+
+// DefaultOutlierPolicy 返回默认的异常实例检测策略
+func DefaultOutlierPolicy() OutlierPolicy {
+	return OutlierPolicy{
+		ConsecutiveFailures: 5,
+		ErrorRate:           0.5,
+		MinRequests:         20,
+		Interval:            time.Minute,
+		BaseEjectionTime:    30 * time.Second,
+		MaxEjectionTime:     10 * time.Minute,
+		MaxEjectionPercent:  0.5,
+	}
+}
+
+// OutlierReport 一次上报的调用结果，按先成功后失败的顺序计入连续失败次数
+type OutlierReport struct {
+	Source    OutlierSource
+	Successes int
+	Failures  int
+}
+
+// Ejection 实例的摘除信息
+type Ejection struct {
+	Reason    string        `json:"reason"`
+	Source    OutlierSource `json:"source"`
+	EjectedAt time.Time     `json:"ejected_at"`
+	Until     time.Time     `json:"until"`
+	// Count 连续被摘除的次数，决定本次摘除的时长
+	Count int `json:"count"`
+}
+
+// outlierState 实例的调用结果统计，由注册中心的写锁保护
+type outlierState struct {
+	windowStart  time.Time
+	requests     int
+	failures     int
+	consecutive  int
+	ejections    int
+	lastRestored time.Time
+}
+
+// WithOutlierDetection 启用异常实例检测
+func WithOutlierDetection(policy OutlierPolicy) RegistryOption {
+	return func(sr *ServiceRegistry) {
+		sr.outlierPolicy = &policy
+	}
+}
+
+// ReportOutcome 记录实例的调用结果，达到摘除条件时摘除实例并返回摘除信息。
+// 实例已被摘除或未启用异常实例检测时只校验实例是否存在
+func (sr *ServiceRegistry) ReportOutcome(namespaceName, name, id string, report OutlierReport) (*Ejection, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+	uniqueIDs, exists := ns.serviceMap[name]
+	if !exists {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+
+	var service *Service
+	for _, uniqueID := range uniqueIDs {
+		if s, ok := ns.services[uniqueID]; ok && s.ID == id {
+			service = s
+			break
+		}
+	}
+	if service == nil {
+		return nil, ErrInstanceNotFound.WithMessage(fmt.Sprintf("服务实例 %s 不存在", id))
+	}
+
+	policy := sr.outlierPolicy
+	if policy == nil || service.Ejection != nil {
+		return service.Ejection, nil
+	}
+
+	now := time.Now()
+	state := service.outlier
+	if state == nil {
+		state = &outlierState{}
+		service.outlier = state
+	}
+	if now.Sub(state.windowStart) > policy.Interval {
+		state.windowStart = now
+		state.requests = 0
+		state.failures = 0
+	}
+	state.requests += report.Successes + report.Failures
+	state.failures += report.Failures
+	if report.Successes > 0 {
+		state.consecutive = 0
+	}
+	state.consecutive += report.Failures
+
+	var reason string
+	switch {
+	case policy.ConsecutiveFailures > 0 && state.consecutive >= policy.ConsecutiveFailures:
+		reason = fmt.Sprintf("连续失败 %d 次", state.consecutive)
+	case policy.ErrorRate > 0 && state.requests >= policy.MinRequests &&
+		float64(state.failures)/float64(state.requests) >= policy.ErrorRate:
+		reason = fmt.Sprintf("错误率 %.1f%%（%d/%d）", float64(state.failures)*100/float64(state.requests), state.failures, state.requests)
+	default:
+		return nil, nil
+	}
+
+	return sr.ejectLocked(ns, service, reason, report.Source, now), nil
+}
+
+// ejectLocked 摘除实例并返回摘除信息，已摘除的实例比例达到上限时放弃摘除并返回 nil。
+// 摘除后的实例是替换进注册表的副本，已返回给调用方的实例不受影响。调用方需持有写锁
+func (sr *ServiceRegistry) ejectLocked(ns *namespace, service *Service, reason string, source OutlierSource, now time.Time) *Ejection {
+	policy := sr.outlierPolicy
+	state := service.outlier
+
+	total, ejected := 0, 0
+	for _, uniqueID := range ns.serviceMap[service.Name] {
+		if s, ok := ns.services[uniqueID]; ok {
+			total++
+			if s.Ejection != nil {
+				ejected++
+			}
+		}
+	}
+	if float64(ejected+1) > float64(total)*policy.MaxEjectionPercent {
+		metrics.OutlierEjections.WithLabelValues(service.Namespace, service.Name, string(source), "capped").Inc()
+		logger.WarnLogger.Printf("服务实例 %s/%s %s，已摘除的实例比例达到上限（%d/%d），不再摘除",
+			service.Namespace, service.UniqueID(), reason, ejected, total)
+		// 重新开始统计，避免每次上报都重复尝试摘除
+		state.requests, state.failures, state.consecutive = 0, 0, 0
+		state.windowStart = now
+		return nil
+	}
+
+	if !state.lastRestored.IsZero() && now.Sub(state.lastRestored) > policy.MaxEjectionTime {
+		state.ejections = 0
+	}
+	state.ejections++
+	duration := policy.BaseEjectionTime
+	for i := 1; i < state.ejections && duration < policy.MaxEjectionTime; i++ {
+		duration *= 2
+	}
+	if duration > policy.MaxEjectionTime {
+		duration = policy.MaxEjectionTime
+	}
+	state.requests, state.failures, state.consecutive = 0, 0, 0
+
+	updated := *service
+	updated.Ejection = &Ejection{
+		Reason:    reason,
+		Source:    source,
+		EjectedAt: now,
+		Until:     now.Add(duration),
+		Count:     state.ejections,
+	}
+	sr.replaceLocked(ns, service, &updated)
+	metrics.OutlierEjections.WithLabelValues(updated.Namespace, updated.Name, string(source), "ejected").Inc()
+	sr.publish(EventEjected, &updated)
+	logger.WarnLogger.Printf("摘除异常实例：%s/%s，原因：%s，时长：%v", updated.Namespace, updated.UniqueID(), reason, duration)
+	return updated.Ejection
+}
+
+// restoreLocked 摘除到期后以副本替换实例，恢复后的实例参与服务发现。调用方需持有写锁
+func (sr *ServiceRegistry) restoreLocked(ns *namespace, service *Service, now time.Time) {
+	updated := *service
+	updated.Ejection = nil
+	if updated.outlier != nil {
+		updated.outlier.lastRestored = now
+		updated.outlier.windowStart = now
+	}
+	sr.replaceLocked(ns, service, &updated)
+	sr.publish(EventRestored, &updated)
+	logger.InfoLogger.Printf("恢复被摘除的实例：%s/%s", updated.Namespace, updated.UniqueID())
+}
+
+// replaceLocked 以修改后的副本替换注册表和索引中的实例并记录变更。调用方需持有写锁
+func (sr *ServiceRegistry) replaceLocked(ns *namespace, service, updated *Service) {
+	ns.replace(service, updated)
+	sr.recordChange(ns, service, updated)
+}
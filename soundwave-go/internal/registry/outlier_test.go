@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutlierEjectionCopiesOnWrite(t *testing.T) {
+	sr := NewServiceRegistry(WithOutlierDetection(OutlierPolicy{
+		ConsecutiveFailures: 2,
+		BaseEjectionTime:    10 * time.Millisecond,
+		MaxEjectionTime:     time.Second,
+		MaxEjectionPercent:  0.5,
+	}))
+	for _, id := range []string{"api-1", "api-2"} {
+		if err := sr.RegisterService(&Service{Name: "api", ID: id, Hostname: "h", IP: "10.0.0.1", Port: 80}); err != nil {
+			t.Fatalf("注册失败: %v", err)
+		}
+	}
+
+	before := instanceOf(t, sr, "api", "api-1")
+	revision := sr.Snapshot(DefaultNamespace).Revision
+	ejection, err := sr.ReportOutcome(DefaultNamespace, "api", "api-1", OutlierReport{Source: SourceCaller, Failures: 2})
+	if err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+	if ejection == nil {
+		t.Fatal("连续失败 2 次后应摘除实例")
+	}
+
+	// 摘除时替换实例，之前返回的实例不受影响
+	ejected := instanceOf(t, sr, "api", "api-1")
+	if ejected == before || before.Ejection != nil {
+		t.Fatal("摘除时应以副本替换实例，之前返回的实例不应被修改")
+	}
+	if ejected.Ejection != ejection {
+		t.Errorf("Ejection = %+v, 期望 %+v", ejected.Ejection, ejection)
+	}
+	if delta := sr.Delta(DefaultNamespace, revision); len(delta.Modified) != 1 || delta.Modified[0] != ejected {
+		t.Errorf("摘除后 Delta.Modified = %v", delta.Modified)
+	}
+
+	// 已摘除的实例比例达到上限时不再摘除
+	if ejection, _ := sr.ReportOutcome(DefaultNamespace, "api", "api-2", OutlierReport{Source: SourceCaller, Failures: 2}); ejection != nil {
+		t.Errorf("摘除比例达到上限时仍摘除了 api-2: %+v", ejection)
+	}
+
+	// 摘除到期后恢复，同样替换为新的副本
+	time.Sleep(20 * time.Millisecond)
+	sr.checkServicesHealth()
+	restored := instanceOf(t, sr, "api", "api-1")
+	if restored == ejected || ejected.Ejection == nil {
+		t.Fatal("恢复时应以副本替换实例，之前返回的实例不应被修改")
+	}
+	if restored.Ejection != nil {
+		t.Errorf("恢复后 Ejection = %+v, 期望 nil", restored.Ejection)
+	}
+	found, err := sr.FindServices(DefaultNamespace, "api", nil)
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("恢复后查询到 %d 个实例, 期望 2", len(found))
+	}
+}
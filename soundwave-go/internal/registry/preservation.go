@@ -0,0 +1,178 @@
+package registry
+
+import (
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/metrics"
+	"time"
+)
+
+const (
+	// DefaultRenewalThreshold 心跳续约率低于该值时进入自我保护模式
+	DefaultRenewalThreshold = 0.85
+	// renewalWindow 统计心跳续约率的窗口
+	renewalWindow = time.Minute
+)
+
+// SelfPreservationStatus 自我保护模式的状态。
+// 注册中心自身网络异常时大量实例的心跳会同时中断，此时剔除实例会导致整个集群的服务发现失效。
+// 统计窗口内实际收到的心跳数低于期望值的一定比例时进入自我保护模式，不再剔除心跳超时的实例，
+// 服务发现继续返回这些实例；心跳续约率恢复后自动退出
+type SelfPreservationStatus struct {
+	Enabled bool `json:"enabled"`
+	Active  bool `json:"active"`
+	// Since 进入自我保护模式的时间
+	Since     *time.Time `json:"since,omitempty"`
+	Threshold float64    `json:"threshold"`
+	// ExpectedRenewals 最近一个统计窗口内期望收到的心跳数，按各实例实际的平均心跳间隔计算
+	ExpectedRenewals float64 `json:"expected_renewals"`
+	// ActualRenewals 最近一个统计窗口内实际收到的心跳数
+	ActualRenewals int `json:"actual_renewals"`
+	// RenewalRate 实际心跳数与期望心跳数之比
+	RenewalRate float64 `json:"renewal_rate"`
+	// SkippedEvictions 本次自我保护期间未剔除的心跳超时实例数
+	SkippedEvictions int `json:"skipped_evictions"`
+	// LastEvaluated 最近一次计算续约率的时间
+	LastEvaluated time.Time `json:"last_evaluated"`
+}
+
+// selfPreservation 自我保护模式的统计数据，由注册中心的写锁保护
+type selfPreservation struct {
+	heartbeatInterval time.Duration
+	renewals          int             // 当前时间段内收到的心跳数
+	periodStart       time.Time       // 当前时间段的开始时间
+	buckets           []renewalBucket // 统计窗口内已结束的时间段
+	status            SelfPreservationStatus
+}
+
+// renewalBucket 一次健康检查间隔内收到的心跳数
+type renewalBucket struct {
+	start    time.Time
+	renewals int
+}
+
+// WithSelfPreservation 启用自我保护模式，threshold 为心跳续约率阈值，heartbeatInterval 为实例默认的心跳间隔，
+// 统计窗口内的数据不足两个心跳间隔时不做判断
+func WithSelfPreservation(threshold float64, heartbeatInterval time.Duration) RegistryOption {
+	return func(sr *ServiceRegistry) {
+		if threshold <= 0 || threshold > 1 {
+			threshold = DefaultRenewalThreshold
+		}
+		sr.preservation = selfPreservation{
+			heartbeatInterval: heartbeatInterval,
+			status: SelfPreservationStatus{
+				Enabled:     true,
+				Threshold:   threshold,
+				RenewalRate: 1,
+			},
+		}
+	}
+}
+
+// SelfPreservation 返回自我保护模式的状态
+func (sr *ServiceRegistry) SelfPreservation() SelfPreservationStatus {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	return sr.preservation.status
+}
+
+// preservingLocked 是否处于自我保护模式。调用方需持有锁
+func (sr *ServiceRegistry) preservingLocked() bool {
+	return sr.preservation.status.Active
+}
+
+// renewLocked 记录实例的一次心跳续约。调用方需持有写锁
+func (sr *ServiceRegistry) renewLocked(service *Service, now time.Time) {
+	service.LastHeartbeat = now
+	service.heartbeats++
+	sr.preservation.renewals++
+}
+
+// observedHeartbeatInterval 实例首次注册以来的平均心跳间隔，还没有收到心跳时返回 0
+func (s *Service) observedHeartbeatInterval() time.Duration {
+	if s.heartbeats == 0 {
+		return 0
+	}
+	return s.LastHeartbeat.Sub(s.registeredAt) / time.Duration(s.heartbeats)
+}
+
+// evaluatePreservationLocked 每次健康检查时计算最近一个统计窗口内的心跳续约率，并进入或退出自我保护模式。
+// 在剔除实例之前调用，使心跳大面积中断时先进入自我保护模式。
+// 每个实例的期望心跳数按其实际的平均心跳间隔和在窗口内的注册时长计算并减去一次，避免心跳较慢的实例、
+// 新注册的实例和心跳时间点的抖动拉低续约率；还没有收到心跳的实例无法得知心跳间隔，不计入期望值。
+// 心跳超时的实例过期后再经过一个统计窗口不再计入期望值，避免已下线的实例使注册中心一直处于自我保护模式；
+// 窗口内没有期望的心跳时（如所有实例的心跳都已中断）无法判断，保持当前状态。
+// 调用方需持有写锁
+func (sr *ServiceRegistry) evaluatePreservationLocked(now time.Time) {
+	p := &sr.preservation
+	if !p.status.Enabled || p.heartbeatInterval <= 0 {
+		return
+	}
+	if p.periodStart.IsZero() {
+		p.periodStart = now
+		return
+	}
+
+	p.buckets = append(p.buckets, renewalBucket{start: p.periodStart, renewals: p.renewals})
+	p.periodStart = now
+	p.renewals = 0
+	drop := 0
+	for drop < len(p.buckets)-1 && now.Sub(p.buckets[drop+1].start) >= renewalWindow {
+		drop++
+	}
+	p.buckets = p.buckets[drop:]
+
+	// 窗口内的数据不足两个心跳间隔时不做判断
+	span := now.Sub(p.buckets[0].start)
+	if span < 2*p.heartbeatInterval {
+		return
+	}
+
+	actual := 0
+	for _, bucket := range p.buckets {
+		actual += bucket.renewals
+	}
+	windowStart := p.buckets[0].start
+	expected := 0.0
+	for _, ns := range sr.namespaces {
+		for _, service := range ns.services {
+			interval := service.observedHeartbeatInterval()
+			if interval <= 0 || now.Sub(service.LastHeartbeat) > serviceExpiration+renewalWindow {
+				continue
+			}
+			start := service.registeredAt
+			if start.Before(windowStart) {
+				start = windowStart
+			}
+			if n := float64(now.Sub(start))/float64(interval) - 1; n > 0 {
+				expected += n
+			}
+		}
+	}
+
+	p.status.ExpectedRenewals = expected
+	p.status.ActualRenewals = actual
+	p.status.LastEvaluated = now
+	if expected < 1 {
+		return
+	}
+	rate := float64(actual) / expected
+	p.status.RenewalRate = rate
+
+	switch active := rate < p.status.Threshold; {
+	case active && !p.status.Active:
+		since := now
+		p.status.Active = true
+		p.status.Since = &since
+		p.status.SkippedEvictions = 0
+		metrics.SelfPreservationActive.Set(1)
+		logger.WarnLogger.Printf("心跳续约率 %.1f%%（%d/%.0f）低于阈值 %.0f%%，进入自我保护模式，暂停剔除心跳超时的实例",
+			rate*100, actual, expected, p.status.Threshold*100)
+	case !active && p.status.Active:
+		p.status.Active = false
+		p.status.Since = nil
+		metrics.SelfPreservationActive.Set(0)
+		logger.InfoLogger.Printf("心跳续约率恢复到 %.1f%%，退出自我保护模式，期间保留了 %d 个心跳超时的实例",
+			rate*100, p.status.SkippedEvictions)
+	}
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// preservationPhase 模拟的一段时间，前 alive 个实例每隔 period 发送一次心跳
+type preservationPhase struct {
+	duration   time.Duration
+	alive      int
+	period     time.Duration
+	wantActive bool // 这段时间结束时是否处于自我保护模式
+}
+
+// simulatePreservation 从 start 开始按模拟时钟发送心跳，每 10 秒计算一次续约率，返回模拟结束的时间
+func simulatePreservation(t *testing.T, sr *ServiceRegistry, instances []*Service, start time.Time, phases []preservationPhase) time.Time {
+	t.Helper()
+	const step = 5 * time.Second
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	for _, service := range instances {
+		service.registeredAt = start
+		service.LastHeartbeat = start
+	}
+
+	now := start
+	for i, phase := range phases {
+		for elapsed := step; elapsed <= phase.duration; elapsed += step {
+			now = now.Add(step)
+			for j, service := range instances[:phase.alive] {
+				// 各实例的心跳时间点错开
+				if (now.Sub(start)+time.Duration(j)*step)%phase.period == 0 {
+					sr.renewLocked(service, now)
+				}
+			}
+			if now.Sub(start)%(10*time.Second) == 0 {
+				sr.evaluatePreservationLocked(now)
+			}
+		}
+		if sr.preservation.status.Active != phase.wantActive {
+			t.Fatalf("第 %d 段结束时 Active = %v, 期望 %v（续约率 %.2f，%d/%.1f）", i+1, sr.preservation.status.Active, phase.wantActive,
+				sr.preservation.status.RenewalRate, sr.preservation.status.ActualRenewals, sr.preservation.status.ExpectedRenewals)
+		}
+	}
+	return now
+}
+
+func TestSelfPreservation(t *testing.T) {
+	const interval = 10 * time.Second
+
+	tests := []struct {
+		name   string
+		phases []preservationPhase
+	}{
+		{
+			name: "心跳间隔大于配置的实例不触发自我保护",
+			phases: []preservationPhase{
+				{duration: 5 * time.Minute, alive: 10, period: 30 * time.Second},
+			},
+		},
+		{
+			name: "大面积心跳中断时进入并在恢复后退出",
+			phases: []preservationPhase{
+				{duration: 3 * time.Minute, alive: 10, period: interval},
+				{duration: 40 * time.Second, alive: 2, period: interval, wantActive: true},
+				{duration: 2 * time.Minute, alive: 10, period: interval},
+			},
+		},
+		{
+			name: "下线的实例超出统计窗口后退出",
+			phases: []preservationPhase{
+				{duration: 3 * time.Minute, alive: 10, period: interval},
+				{duration: time.Minute, alive: 6, period: interval, wantActive: true},
+				{duration: 2 * time.Minute, alive: 6, period: interval},
+			},
+		},
+		{
+			name: "所有实例的心跳中断时保持自我保护",
+			phases: []preservationPhase{
+				{duration: 3 * time.Minute, alive: 10, period: interval},
+				{duration: 5 * time.Minute, alive: 0, period: interval, wantActive: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := NewServiceRegistry(WithSelfPreservation(DefaultRenewalThreshold, interval))
+			instances := make([]*Service, 0, 10)
+			for i := 0; i < 10; i++ {
+				instances = append(instances, registerTestInstance(t, sr, "api", fmt.Sprintf("api-%d", i)))
+			}
+			simulatePreservation(t, sr, instances, time.Now().Add(-time.Hour), tt.phases)
+		})
+	}
+}
+
+func TestSelfPreservationSkipsEviction(t *testing.T) {
+	const interval = 10 * time.Second
+	sr := NewServiceRegistry(WithSelfPreservation(DefaultRenewalThreshold, interval))
+	instances := make([]*Service, 0, 10)
+	for i := 0; i < 10; i++ {
+		instances = append(instances, registerTestInstance(t, sr, "api", fmt.Sprintf("api-%d", i)))
+	}
+
+	// 模拟在当前时间结束，心跳中断的实例已超过过期时间
+	phases := []preservationPhase{
+		{duration: 3 * time.Minute, alive: 10, period: interval},
+		{duration: 50 * time.Second, alive: 2, period: interval, wantActive: true},
+	}
+	simulatePreservation(t, sr, instances, time.Now().Add(-3*time.Minute-50*time.Second), phases)
+
+	sr.checkServicesHealth()
+	status := sr.SelfPreservation()
+	if !status.Active {
+		t.Fatalf("健康检查后退出了自我保护模式（续约率 %.2f）", status.RenewalRate)
+	}
+	if got := len(sr.ListAllServices(DefaultNamespace)["api"]); got != 10 {
+		t.Errorf("自我保护期间剩余 %d 个实例, 期望 10", got)
+	}
+	if status.SkippedEvictions != 8 {
+		t.Errorf("SkippedEvictions = %d, 期望 8", status.SkippedEvictions)
+	}
+
+}
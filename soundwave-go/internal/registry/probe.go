@@ -0,0 +1,129 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"soundwave-go/internal/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// HealthCheckPathMetadata 实例元数据中的健康检查路径，设置后主动探测使用 HTTP GET，否则只建立 TCP 连接
+	HealthCheckPathMetadata = "health_check_path"
+	// probeConcurrency 同时探测的实例数
+	probeConcurrency = 16
+)
+
+// probeTarget 待探测的实例
+type probeTarget struct {
+	namespace string
+	name      string
+	id        string
+	address   string
+	path      string
+}
+
+// StartProbe 定期主动探测所有健康且未被摘除的实例，探测结果与调用方上报的结果一起用于异常实例检测
+func (sr *ServiceRegistry) StartProbe(ctx context.Context, interval, timeout time.Duration) {
+	logger.InfoLogger.Printf("启动实例主动探测，间隔时间：%v", interval)
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sr.probeAll(ctx, client, timeout)
+			}
+		}
+	}()
+}
+
+// probeAll 探测一轮所有实例
+func (sr *ServiceRegistry) probeAll(ctx context.Context, client *http.Client, timeout time.Duration) {
+	targets := sr.probeTargets()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for _, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(target probeTarget) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			report := OutlierReport{Source: SourceProbe, Successes: 1}
+			if err := probe(ctx, client, target, timeout); err != nil {
+				logger.WarnLogger.Printf("探测实例 %s/%s/%s 失败: %v", target.namespace, target.name, target.id, err)
+				report = OutlierReport{Source: SourceProbe, Failures: 1}
+			}
+			// 探测期间实例可能已注销，忽略此类错误
+			sr.ReportOutcome(target.namespace, target.name, target.id, report)
+		}(target)
+	}
+	wg.Wait()
+}
+
+// probeTargets 返回所有健康且未被摘除的实例
+func (sr *ServiceRegistry) probeTargets() []probeTarget {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	now := time.Now()
+	targets := make([]probeTarget, 0)
+	for namespaceName, ns := range sr.namespaces {
+		for _, service := range ns.services {
+			if !service.Status.Available() || service.Ejection != nil || now.Sub(service.LastHeartbeat) > serviceExpiration {
+				continue
+			}
+			targets = append(targets, probeTarget{
+				namespace: namespaceName,
+				name:      service.Name,
+				id:        service.ID,
+				address:   net.JoinHostPort(service.IP, strconv.Itoa(service.Port)),
+				path:      service.Metadata[HealthCheckPathMetadata],
+			})
+		}
+	}
+	return targets
+}
+
+// probe 设置了健康检查路径时发送 HTTP GET，5xx 视为失败；否则只建立 TCP 连接
+func probe(ctx context.Context, client *http.Client, target probeTarget, timeout time.Duration) error {
+	if target.path == "" {
+		conn, err := net.DialTimeout("tcp", target.address, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	url := "http://" + target.address + "/" + strings.TrimPrefix(target.path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("健康检查返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
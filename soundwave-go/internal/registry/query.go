@@ -0,0 +1,152 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"soundwave-go/pkg/routing"
+	"strings"
+	"time"
+)
+
+// InstanceQuery 实例查询条件，各条件需同时满足
+type InstanceQuery struct {
+	Tags     []string                   // 需包含全部标签
+	Selector routing.Selector           // 元数据选择器
+	Version  *routing.VersionConstraint // 版本范围
+	Zone     string                     // 可用区
+}
+
+// ParseInstanceQuery 解析查询参数，selector 与 version 的语法见 routing.ParseSelector 和 routing.ParseVersionConstraint。
+// 没有任何条件时返回 nil
+func ParseInstanceQuery(tags []string, selector, version, zone string) (*InstanceQuery, error) {
+	query := &InstanceQuery{Zone: strings.TrimSpace(zone)}
+	for _, tag := range tags {
+		for _, t := range strings.Split(tag, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				query.Tags = append(query.Tags, t)
+			}
+		}
+	}
+
+	var err error
+	if query.Selector, err = routing.ParseSelector(selector); err != nil {
+		return nil, ErrInvalidQuery.WithMessage(err.Error())
+	}
+	if strings.TrimSpace(version) != "" {
+		if query.Version, err = routing.ParseVersionConstraint(version); err != nil {
+			return nil, ErrInvalidQuery.WithMessage(err.Error())
+		}
+	}
+
+	if query.Empty() {
+		return nil, nil
+	}
+	return query, nil
+}
+
+// Empty 判断是否没有任何条件
+func (q *InstanceQuery) Empty() bool {
+	return q == nil || (len(q.Tags) == 0 && len(q.Selector) == 0 && q.Version == nil && q.Zone == "")
+}
+
+// Matches 判断实例是否满足查询条件
+func (q *InstanceQuery) Matches(service *Service) bool {
+	if q.Empty() {
+		return true
+	}
+	for _, tag := range q.Tags {
+		if !containsString(service.Tags, tag) {
+			return false
+		}
+	}
+	if q.Zone != "" && service.Zone != q.Zone {
+		return false
+	}
+	if q.Version != nil && !q.Version.Matches(service.Version) {
+		return false
+	}
+	return q.Selector.Matches(service.Metadata)
+}
+
+// FindServices 获取服务中满足查询条件的健康实例，query 为 nil 时返回全部健康实例，被摘除的实例不计入。
+// 自我保护模式下心跳超时的实例仍视为健康
+// 标签、可用区和元数据的等值条件先通过索引缩小范围，再逐个校验其余条件
+func (sr *ServiceRegistry) FindServices(namespaceName, name string, query *InstanceQuery) ([]*Service, error) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+	uniqueIDs, exists := ns.serviceMap[name]
+	if !exists {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+
+	if !query.Empty() {
+		if idx, ok := ns.indexes[name]; ok {
+			if candidates, narrowed := idx.candidates(query); narrowed {
+				// 按 uniqueID 排序，保证轮询等负载均衡策略看到稳定的顺序
+				filtered := make([]string, 0, len(candidates))
+				for uniqueID := range candidates {
+					filtered = append(filtered, uniqueID)
+				}
+				sort.Strings(filtered)
+				uniqueIDs = filtered
+			}
+		}
+	}
+
+	// 获取所有活跃的服务实例
+	activeServices := make([]*Service, 0)
+	for _, uniqueID := range uniqueIDs {
+		if service, ok := ns.services[uniqueID]; ok {
+			fresh := time.Since(service.LastHeartbeat) <= serviceExpiration || sr.preservingLocked()
+			if service.Status.Available() && service.Ejection == nil && fresh && query.Matches(service) {
+				activeServices = append(activeServices, service)
+			}
+		}
+	}
+
+	if len(activeServices) == 0 {
+		if !query.Empty() {
+			return nil, ErrNoAvailableInstance.WithMessage(fmt.Sprintf("服务 %s 没有满足条件的可用实例", name))
+		}
+		return nil, ErrNoAvailableInstance.WithMessage(fmt.Sprintf("服务 %s 没有可用的实例", name))
+	}
+
+	return activeServices, nil
+}
+
+// ResolveServiceName 不区分大小写查找命名空间内已注册的服务名称，供 DNS 等大小写不敏感的协议使用。
+// 存在完全匹配的名称时优先返回，否则返回按字典序最小的匹配名称
+func (sr *ServiceRegistry) ResolveServiceName(namespaceName, name string) (string, bool) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return "", false
+	}
+	if _, exists := ns.serviceMap[name]; exists {
+		return name, true
+	}
+
+	resolved := ""
+	for registered := range ns.serviceMap {
+		if strings.EqualFold(registered, name) && (resolved == "" || registered < resolved) {
+			resolved = registered
+		}
+	}
+	return resolved, resolved != ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -4,27 +4,37 @@ import (
 	"context"
 	"fmt"
 	"soundwave-go/internal/logger"
+	"soundwave-go/internal/metrics"
+	"soundwave-go/pkg/routing"
 	"time"
 )
 
+// reservedServiceNames 与 /services 下的固定路由冲突的服务名称，使用这些名称注册的服务无法按名称发现
+var reservedServiceNames = map[string]bool{
+	"delta": true,
+}
+
 // NewServiceRegistry 创建新的服务注册中心
 func NewServiceRegistry(opts ...RegistryOption) *ServiceRegistry {
 	sr := &ServiceRegistry{
-		services:    make(map[string]*Service),
-		serviceMap:  make(map[string][]string),
+		namespaces:  make(map[string]*namespace),
 		healthCheck: NewDefaultHealthCheck(serviceExpiration),
 		balancer:    NewRandomBalancer(),
+		watchers:    make(map[*watcher]struct{}),
+
+		trafficPolicies: make(map[string]*routing.TrafficPolicy),
 	}
 
 	// 应用选项
 	for _, opt := range opts {
 		opt(sr)
 	}
+	sr.locality = NewLocalityBalancer(sr.spilloverThreshold, sr.balancer)
 
 	return sr
 }
 
-// RegisterService 注册服务
+// RegisterService 注册服务，Namespace 为空时注册到默认命名空间
 func (sr *ServiceRegistry) RegisterService(service *Service) error {
 	sr.mutex.Lock()
 	defer sr.mutex.Unlock()
@@ -32,9 +42,20 @@ func (sr *ServiceRegistry) RegisterService(service *Service) error {
 	// 验证服务信息
 	if service.Name == "" || service.ID == "" || service.Hostname == "" {
 		logger.ErrorLogger.Printf("服务注册失败：信息不完整 %+v", service)
-		return fmt.Errorf("服务名称、ID和主机名不能为空")
+		return ErrInvalidService.WithMessage("服务名称、ID和主机名不能为空")
+	}
+	if reservedServiceNames[service.Name] {
+		return ErrInvalidService.WithMessage(fmt.Sprintf("服务名称 %s 为保留名称", service.Name))
 	}
 
+	// 验证命名空间
+	namespaceName, err := NormalizeNamespace(service.Namespace)
+	if err != nil {
+		logger.ErrorLogger.Printf("服务注册失败：%v", err)
+		return err
+	}
+	service.Namespace = namespaceName
+
 	// 验证IP地址
 	if err := service.ValidateIP(); err != nil {
 		logger.ErrorLogger.Printf("服务注册失败：%v", err)
@@ -43,140 +64,252 @@ func (sr *ServiceRegistry) RegisterService(service *Service) error {
 
 	// 验证端口
 	if service.Port <= 0 || service.Port > 65535 {
-		return fmt.Errorf("无效的端口号: %d", service.Port)
+		return ErrInvalidService.WithMessage(fmt.Sprintf("无效的端口号: %d", service.Port))
 	}
 
-	// 设置服务状态和心跳时间
-	service.Status = StatusUP
+	// 设置服务状态和心跳时间，未指定状态时视为 UP
+	if service.Status == "" {
+		service.Status = StatusUP
+	}
+	if !reportableStatuses[service.Status] {
+		return ErrInvalidService.WithMessage(fmt.Sprintf("无效的实例状态: %s，可选 UP、DEGRADED、STARTING", service.Status))
+	}
 	service.LastHeartbeat = time.Now()
 	if service.StartTime.IsZero() {
 		service.StartTime = time.Now()
 	}
 
+	// 每次注册都重新颁发实例令牌，之前颁发的令牌失效
+	token, err := newInstanceToken()
+	if err != nil {
+		return fmt.Errorf("生成实例令牌失败: %w", err)
+	}
+	service.token = token
+
 	// 生成唯一标识符
 	uniqueID := service.UniqueID()
+	ns := sr.namespaceForWriteLocked(namespaceName)
 
 	// 存储服务实例
-	sr.services[uniqueID] = service
+	previous := ns.services[uniqueID]
+	if previous != nil {
+		ns.unindex(previous)
+		// 重新注册不解除摘除，也不清空调用结果统计
+		service.Ejection = previous.Ejection
+		service.outlier = previous.outlier
+		service.registeredAt = previous.registeredAt
+		service.heartbeats = previous.heartbeats
+	} else {
+		service.registeredAt = time.Now()
+		if acc := sr.historyPendingLocked(namespaceName, service.Name); acc != nil {
+			acc.registrations++
+		}
+	}
+	ns.services[uniqueID] = service
+	ns.index(service)
+	sr.recordChange(ns, previous, service)
 
 	// 更新服务名称到uniqueID的映射
-	if _, exists := sr.serviceMap[service.Name]; !exists {
-		sr.serviceMap[service.Name] = make([]string, 0)
+	if _, exists := ns.serviceMap[service.Name]; !exists {
+		ns.serviceMap[service.Name] = make([]string, 0)
 	}
 
 	// 检查是否已存在该uniqueID
 	found := false
-	for _, id := range sr.serviceMap[service.Name] {
+	for _, id := range ns.serviceMap[service.Name] {
 		if id == uniqueID {
 			found = true
 			break
 		}
 	}
 	if !found {
-		sr.serviceMap[service.Name] = append(sr.serviceMap[service.Name], uniqueID)
+		ns.serviceMap[service.Name] = append(ns.serviceMap[service.Name], uniqueID)
 	}
 
-	logger.InfoLogger.Printf("注册服务实例：%s，地址：%s", uniqueID, service.GetAddress())
+	metrics.Registrations.WithLabelValues(namespaceName, service.Name).Inc()
+	sr.publishRegistration(previous, service)
+	logger.InfoLogger.Printf("注册服务实例：%s/%s，地址：%s", namespaceName, uniqueID, service.GetAddress())
 	return nil
 }
 
 // DeregisterService 注销服务
-func (sr *ServiceRegistry) DeregisterService(name, id string) error {
+func (sr *ServiceRegistry) DeregisterService(namespaceName, name, id string) error {
 	sr.mutex.Lock()
 	defer sr.mutex.Unlock()
 
 	// 检查服务是否存在
-	uniqueIDs, exists := sr.serviceMap[name]
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+	uniqueIDs, exists := ns.serviceMap[name]
 	if !exists {
-		return fmt.Errorf("服务 %s 不存在", name)
+		return ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
 	}
 
 	// 查找并移除指定的服务实例
 	for i, uniqueID := range uniqueIDs {
-		if service, ok := sr.services[uniqueID]; ok {
+		if service, ok := ns.services[uniqueID]; ok {
 			if service.ID == id {
 				// 从serviceMap中移除该uniqueID
-				sr.serviceMap[name] = append(uniqueIDs[:i], uniqueIDs[i+1:]...)
+				ns.serviceMap[name] = append(uniqueIDs[:i], uniqueIDs[i+1:]...)
 				// 从services中删除该服务实例
-				delete(sr.services, uniqueID)
+				delete(ns.services, uniqueID)
+				sr.recordChange(ns, service, nil)
+				if acc := sr.historyPendingLocked(namespaceName, name); acc != nil {
+					acc.deregistrations++
+				}
 
 				// 如果该服务没有实例了，则删除该服务条目
-				if len(sr.serviceMap[name]) == 0 {
-					delete(sr.serviceMap, name)
+				if len(ns.serviceMap[name]) == 0 {
+					delete(ns.serviceMap, name)
 				}
+				ns.unindex(service)
+				sr.removeIfEmptyLocked(namespaceName)
+				metrics.Deregistrations.WithLabelValues(namespaceName, name).Inc()
+				metrics.BalancerSelections.DeleteLabelValues(namespaceName, name, service.ID)
+				sr.publish(EventDeregistered, service)
 				return nil
 			}
 		}
 	}
 
-	return fmt.Errorf("服务实例 %s 不存在", id)
+	return ErrInstanceNotFound.WithMessage(fmt.Sprintf("服务实例 %s 不存在", id))
 }
 
 // GetService 获取服务实例
-func (sr *ServiceRegistry) GetService(name string) ([]*Service, error) {
+func (sr *ServiceRegistry) GetService(namespaceName, name string) ([]*Service, error) {
+	return sr.FindServices(namespaceName, name, nil)
+}
+
+// GetInstance 获取服务的单个实例，不论实例是否健康，返回实例的副本
+func (sr *ServiceRegistry) GetInstance(namespaceName, name, id string) (*Service, error) {
 	sr.mutex.RLock()
 	defer sr.mutex.RUnlock()
 
-	uniqueIDs, exists := sr.serviceMap[name]
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+	uniqueIDs, exists := ns.serviceMap[name]
 	if !exists {
-		return nil, fmt.Errorf("服务 %s 不存在", name)
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
 	}
 
-	// 获取所有活跃的服务实例
-	activeServices := make([]*Service, 0)
 	for _, uniqueID := range uniqueIDs {
-		if service, ok := sr.services[uniqueID]; ok {
-			if service.Status == StatusUP && time.Since(service.LastHeartbeat) <= serviceExpiration {
-				activeServices = append(activeServices, service)
-			}
+		if service, ok := ns.services[uniqueID]; ok && service.ID == id {
+			instance := *service
+			return &instance, nil
 		}
 	}
+	return nil, ErrInstanceNotFound.WithMessage(fmt.Sprintf("服务实例 %s 不存在", id))
+}
 
-	if len(activeServices) == 0 {
-		return nil, fmt.Errorf("服务 %s 没有可用的实例", name)
+// UpdateHeartbeat 更新服务心跳时间，并记录心跳上报的状态、依赖健康情况和负载，report 中未携带的内容保持不变，
+// report 为 nil 时只续约。状态变化时发布状态变化事件
+func (sr *ServiceRegistry) UpdateHeartbeat(namespaceName, name, id string, report *HeartbeatReport) error {
+	if report == nil {
+		report = &HeartbeatReport{}
+	}
+	if err := report.validate(); err != nil {
+		return err
 	}
 
-	return activeServices, nil
-}
-
-// UpdateHeartbeat 更新服务心跳时间
-func (sr *ServiceRegistry) UpdateHeartbeat(name, id string) error {
 	sr.mutex.Lock()
 	defer sr.mutex.Unlock()
 
 	// 检查服务是否存在
-	uniqueIDs, exists := sr.serviceMap[name]
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+	uniqueIDs, exists := ns.serviceMap[name]
 	if !exists {
-		return fmt.Errorf("服务 %s 不存在", name)
+		return ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
 	}
 
 	// 查找并更新服务实例
 	for _, uniqueID := range uniqueIDs {
-		if service, ok := sr.services[uniqueID]; ok {
+		if service, ok := ns.services[uniqueID]; ok {
 			if service.ID == id {
-				service.LastHeartbeat = time.Now()
-				service.Status = StatusUP
+				now := time.Now()
+				metrics.Heartbeats.WithLabelValues(namespaceName, name).Inc()
+				metrics.HeartbeatInterval.WithLabelValues(namespaceName, name).Observe(now.Sub(service.LastHeartbeat).Seconds())
+				sr.recordHeartbeatLocked(service, now.Sub(service.LastHeartbeat))
+				sr.renewLocked(service, now)
+				if service.heartbeatLost {
+					recovered := *service
+					recovered.heartbeatLost = false
+					ns.replace(service, &recovered)
+					service = &recovered
+					sr.publish(EventHeartbeatRecovered, service)
+				}
+				if report.Load == nil && len(report.Checks) == 0 && (report.Status == "" || report.Status == service.Status) {
+					return nil
+				}
+
+				// 复制后替换，已返回给调用方的实例不受影响
+				updated := *service
+				if report.Load != nil {
+					updated.Load = &LoadStats{
+						InFlight:   report.Load.InFlight,
+						LatencyMs:  report.Load.LatencyMs,
+						ReportedAt: now,
+					}
+				}
+				if len(report.Checks) > 0 {
+					updated.Health = &InstanceHealth{Checks: report.Checks, ReportedAt: now}
+				}
+				if report.Status != "" {
+					updated.Status = report.Status
+				}
+				ns.services[uniqueID] = &updated
+				if updated.Status == service.Status {
+					// 负载和依赖健康情况的变化不视为实例变化，索引中的实例需替换为新的副本
+					ns.unindex(service)
+					ns.index(&updated)
+					return nil
+				}
+
+				detail := fmt.Sprintf("%s -> %s", service.Status, updated.Status)
+				if unhealthy := unhealthyChecks(report.Checks); unhealthy != "" {
+					detail += "，异常依赖: " + unhealthy
+				}
+				ns.unindex(service)
+				ns.index(&updated)
+				sr.recordChange(ns, service, &updated)
+				sr.publishDetail(EventStatusChanged, &updated, detail)
 				return nil
 			}
 		}
 	}
 
-	return fmt.Errorf("服务实例 %s 不存在", id)
+	return ErrInstanceNotFound.WithMessage(fmt.Sprintf("服务实例 %s 不存在", id))
 }
 
-// ListAllServices 获取所有注册的服务
-func (sr *ServiceRegistry) ListAllServices() map[string][]*Service {
+// ListAllServices 获取命名空间内所有注册的服务
+func (sr *ServiceRegistry) ListAllServices(namespaceName string) map[string][]*Service {
 	sr.mutex.RLock()
 	defer sr.mutex.RUnlock()
 
+	return sr.listAllServicesLocked(namespaceName)
+}
+
+// listAllServicesLocked 调用方需持有读锁
+func (sr *ServiceRegistry) listAllServicesLocked(namespaceName string) map[string][]*Service {
 	// 创建服务列表的副本
 	result := make(map[string][]*Service)
 
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return result
+	}
+
 	// 遍历serviceMap获取所有服务
-	for name, uniqueIDs := range sr.serviceMap {
+	for name, uniqueIDs := range ns.serviceMap {
 		services := make([]*Service, 0)
 		for _, uniqueID := range uniqueIDs {
-			if service, ok := sr.services[uniqueID]; ok {
+			if service, ok := ns.services[uniqueID]; ok {
 				services = append(services, service)
 			}
 		}
@@ -188,6 +321,28 @@ func (sr *ServiceRegistry) ListAllServices() map[string][]*Service {
 	return result
 }
 
+// CountInstances 按命名空间、服务名和状态统计实例数量，供指标采集使用
+func (sr *ServiceRegistry) CountInstances() map[string]map[string]map[string]int {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	result := make(map[string]map[string]map[string]int, len(sr.namespaces))
+	for namespaceName, ns := range sr.namespaces {
+		services := make(map[string]map[string]int, len(ns.serviceMap))
+		for name, uniqueIDs := range ns.serviceMap {
+			counts := make(map[string]int)
+			for _, uniqueID := range uniqueIDs {
+				if service, ok := ns.services[uniqueID]; ok {
+					counts[string(service.Status)]++
+				}
+			}
+			services[name] = counts
+		}
+		result[namespaceName] = services
+	}
+	return result
+}
+
 // StartHealthCheck 启动健康检查定时任务
 func (sr *ServiceRegistry) StartHealthCheck(ctx context.Context, interval time.Duration) {
 	logger.InfoLogger.Printf("启动健康检查，间隔时间：%v", interval)
@@ -212,46 +367,76 @@ func (sr *ServiceRegistry) checkServicesHealth() {
 	defer sr.mutex.Unlock()
 
 	now := time.Now()
-	// 遍历所有服务
-	for serviceName, uniqueIDs := range sr.serviceMap {
-		activeUniqueIDs := make([]string, 0)
+	defer func() {
+		metrics.HealthCheckDuration.Observe(time.Since(now).Seconds())
+	}()
 
-		// 检查每个服务实例
-		for _, uniqueID := range uniqueIDs {
-			if service, ok := sr.services[uniqueID]; ok {
-				// 如果服务实例在过期时间内有心跳，则保留
-				if service.Status == StatusUP && now.Sub(service.LastHeartbeat) <= serviceExpiration {
-					activeUniqueIDs = append(activeUniqueIDs, uniqueID)
-				} else {
-					// 更新服务状态为离线
-					service.Status = StatusDOWN
-					// 从services中移除过期的服务实例
-					delete(sr.services, uniqueID)
+	// 自我保护模式下不剔除心跳超时的实例
+	sr.evaluatePreservationLocked(now)
+	preserving := sr.preservingLocked()
+
+	for namespaceName, ns := range sr.namespaces {
+		// 遍历所有服务
+		for serviceName, uniqueIDs := range ns.serviceMap {
+			activeUniqueIDs := make([]string, 0)
+
+			// 检查每个服务实例
+			for _, uniqueID := range uniqueIDs {
+				if service, ok := ns.services[uniqueID]; ok {
+					// 如果服务实例在过期时间内有心跳，则保留
+					expired := now.Sub(service.LastHeartbeat) > serviceExpiration
+					if expired && !service.heartbeatLost {
+						// 复制后替换，心跳丢失不视为实例变化
+						updated := *service
+						updated.heartbeatLost = true
+						ns.replace(service, &updated)
+						service = &updated
+						sr.publishDetail(EventHeartbeatLost, service, fmt.Sprintf("最后心跳时间 %s", service.LastHeartbeat.Format(time.RFC3339)))
+					}
+					if service.Status != StatusDOWN && (!expired || preserving) {
+						activeUniqueIDs = append(activeUniqueIDs, uniqueID)
+						if expired {
+							sr.preservation.status.SkippedEvictions++
+						}
+						if service.Ejection != nil && !now.Before(service.Ejection.Until) {
+							sr.restoreLocked(ns, service, now)
+						}
+					} else {
+						// 从services中移除过期的服务实例，变更记录和事件中的实例为状态改为离线的副本
+						delete(ns.services, uniqueID)
+						if idx, ok := ns.indexes[serviceName]; ok {
+							idx.remove(service)
+						}
+						removed := *service
+						removed.Status = StatusDOWN
+						service = &removed
+						sr.recordChange(ns, service, nil)
+						metrics.Expirations.WithLabelValues(namespaceName, serviceName).Inc()
+						metrics.BalancerSelections.DeleteLabelValues(namespaceName, serviceName, service.ID)
+						if acc := sr.historyPendingLocked(namespaceName, serviceName); acc != nil {
+							acc.expirations++
+						}
+						sr.publish(EventExpired, service)
+					}
 				}
 			}
-		}
 
-		// 更新serviceMap
-		if len(activeUniqueIDs) > 0 {
-			sr.serviceMap[serviceName] = activeUniqueIDs
-		} else {
-			// 如果没有活跃实例，删除该服务
-			delete(sr.serviceMap, serviceName)
+			// 更新serviceMap
+			if len(activeUniqueIDs) > 0 {
+				ns.serviceMap[serviceName] = activeUniqueIDs
+			} else {
+				// 如果没有活跃实例，删除该服务
+				delete(ns.serviceMap, serviceName)
+				delete(ns.indexes, serviceName)
+			}
 		}
+		sr.removeIfEmptyLocked(namespaceName)
 	}
 }
 
-// GetServiceWithLoadBalancing 使用负载均衡获取服务实例
-func (sr *ServiceRegistry) GetServiceWithLoadBalancing(name string) (*Service, error) {
-	services, err := sr.GetService(name)
-	if err != nil {
-		return nil, err
-	}
-
-	service := sr.balancer.Select(services)
-	if service == nil {
-		return nil, fmt.Errorf("没有可用的服务实例")
-	}
-
-	return service, nil
+// GetServiceWithLoadBalancing 使用负载均衡从满足查询条件的实例中选择一个，query 为 nil 时不做筛选。
+// 需要就近选择或按请求匹配路由规则时使用 SelectInstance
+func (sr *ServiceRegistry) GetServiceWithLoadBalancing(namespaceName, name string, query *InstanceQuery) (*Service, error) {
+	service, _, err := sr.SelectInstance(namespaceName, name, query, Locality{}, routing.RouteContext{})
+	return service, err
 }
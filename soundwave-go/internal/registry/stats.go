@@ -2,39 +2,77 @@ package registry
 
 import (
 	"fmt"
+	"sort"
 	"time"
 )
 
 // ServiceStats 服务统计信息
 type ServiceStats struct {
+	Name               string        `json:"name"`
 	TotalInstances     int           `json:"total_instances"`
 	HealthyInstances   int           `json:"healthy_instances"`
 	UnhealthyInstances int           `json:"unhealthy_instances"`
+	EjectedInstances   int           `json:"ejected_instances"`
 	AverageUptime      time.Duration `json:"average_uptime"`
 	LastUpdateTime     time.Time     `json:"last_update_time"`
 }
 
 // GetServiceStats 获取服务统计信息
-func (sr *ServiceRegistry) GetServiceStats(name string) (*ServiceStats, error) {
+func (sr *ServiceRegistry) GetServiceStats(namespaceName, name string) (*ServiceStats, error) {
 	sr.mutex.RLock()
 	defer sr.mutex.RUnlock()
 
-	uniqueIDs, exists := sr.serviceMap[name]
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+	uniqueIDs, exists := ns.serviceMap[name]
 	if !exists {
-		return nil, fmt.Errorf("服务 %s 不存在", name)
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+
+	return sr.serviceStatsLocked(ns, name, uniqueIDs), nil
+}
+
+// ListServiceStats 获取命名空间内所有服务的统计信息，按服务名称排序
+func (sr *ServiceRegistry) ListServiceStats(namespaceName string) []*ServiceStats {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	result := make([]*ServiceStats, 0)
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return result
+	}
+
+	for name, uniqueIDs := range ns.serviceMap {
+		result = append(result, sr.serviceStatsLocked(ns, name, uniqueIDs))
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
 
+// serviceStatsLocked 调用方需持有读锁
+func (sr *ServiceRegistry) serviceStatsLocked(ns *namespace, name string, uniqueIDs []string) *ServiceStats {
 	stats := &ServiceStats{
+		Name:           name,
 		TotalInstances: len(uniqueIDs),
 		LastUpdateTime: time.Now(),
 	}
 
 	var totalUptime time.Duration
 	for _, uniqueID := range uniqueIDs {
-		if service, ok := sr.services[uniqueID]; ok {
-			if sr.healthCheck.Check(service) {
+		if service, ok := ns.services[uniqueID]; ok {
+			switch {
+			case service.Ejection != nil:
+				// 被摘除的实例心跳正常，但不参与服务发现
+				stats.EjectedInstances++
+				stats.UnhealthyInstances++
+			case sr.healthCheck.Check(service):
 				stats.HealthyInstances++
-			} else {
+			default:
 				stats.UnhealthyInstances++
 			}
 			totalUptime += time.Since(service.StartTime)
@@ -45,5 +83,5 @@ func (sr *ServiceRegistry) GetServiceStats(name string) (*ServiceStats, error) {
 		stats.AverageUptime = totalUptime / time.Duration(stats.TotalInstances)
 	}
 
-	return stats, nil
+	return stats
 }
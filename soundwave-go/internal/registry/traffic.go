@@ -0,0 +1,52 @@
+package registry
+
+import "soundwave-go/pkg/routing"
+
+// SetTrafficPolicy 设置服务的路由规则，policy 为 nil 时删除
+func (sr *ServiceRegistry) SetTrafficPolicy(namespaceName, name string, policy *routing.TrafficPolicy) {
+	sr.trafficMutex.Lock()
+	defer sr.trafficMutex.Unlock()
+
+	key := namespaceName + "/" + name
+	if policy == nil {
+		delete(sr.trafficPolicies, key)
+		return
+	}
+	sr.trafficPolicies[key] = policy
+}
+
+// ReplaceTrafficPolicies 替换所有服务的路由规则，键为 <namespace>/<service>
+func (sr *ServiceRegistry) ReplaceTrafficPolicies(policies map[string]*routing.TrafficPolicy) {
+	sr.trafficMutex.Lock()
+	defer sr.trafficMutex.Unlock()
+
+	sr.trafficPolicies = policies
+}
+
+// TrafficPolicy 获取服务的路由规则，未配置时返回 nil
+func (sr *ServiceRegistry) TrafficPolicy(namespaceName, name string) *routing.TrafficPolicy {
+	sr.trafficMutex.RLock()
+	defer sr.trafficMutex.RUnlock()
+
+	return sr.trafficPolicies[namespaceName+"/"+name]
+}
+
+// applyTrafficPolicy 按路由规则筛选实例，目标实例组没有可用实例时返回全部实例并标记 Fallback
+func (sr *ServiceRegistry) applyTrafficPolicy(namespaceName, name string, services []*Service, route routing.RouteContext) ([]*Service, *routing.TrafficRoute) {
+	traffic := sr.TrafficPolicy(namespaceName, name).Route(route)
+	if traffic == nil {
+		return services, nil
+	}
+
+	filtered := make([]*Service, 0, len(services))
+	for _, service := range services {
+		if traffic.Matches(service.Version, service.Metadata) {
+			filtered = append(filtered, service)
+		}
+	}
+	if len(filtered) == 0 {
+		traffic.Fallback = true
+		return services, traffic
+	}
+	return filtered, traffic
+}
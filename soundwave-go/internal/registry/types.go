@@ -3,6 +3,8 @@ package registry
 import (
 	"fmt"
 	"net"
+	"soundwave-go/pkg/routing"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -20,6 +22,8 @@ const (
 	StatusUP ServiceStatus = "UP"
 	// StatusDOWN 表示服务已离线
 	StatusDOWN ServiceStatus = "DOWN"
+	// StatusDegraded 表示服务可用但部分依赖异常，仍参与服务发现
+	StatusDegraded ServiceStatus = "DEGRADED"
 	// StatusStarting 表示服务正在启动
 	StatusStarting ServiceStatus = "STARTING"
 	// StatusOutOfService 表示服务已手动下线
@@ -29,8 +33,14 @@ const (
 // ServiceStatus 定义服务状态类型
 type ServiceStatus string
 
+// Available 该状态的实例是否可以接收请求，心跳正常时参与服务发现
+func (s ServiceStatus) Available() bool {
+	return s == StatusUP || s == StatusDegraded
+}
+
 // Service 表示一个服务实例
 type Service struct {
+	Namespace     string            `json:"namespace"`
 	Name          string            `json:"name"`
 	ID            string            `json:"id"`
 	Hostname      string            `json:"hostname"`
@@ -42,23 +52,40 @@ type Service struct {
 	Weight        int               `json:"weight"`
 	StartTime     time.Time         `json:"start_time"`
 	Version       string            `json:"version"`
+	Tags          []string          `json:"tags,omitempty"`
+	Region        string            `json:"region,omitempty"`
+	Zone          string            `json:"zone,omitempty"`
+	// Revision 实例最近一次变化时注册表的版本号
+	Revision uint64 `json:"revision"`
+	// Load 最近一次心跳上报的负载，负载变化不视为实例变化
+	Load *LoadStats `json:"load,omitempty"`
+	// Health 最近一次心跳上报的依赖健康情况，没有上报时为空
+	Health *InstanceHealth `json:"health,omitempty"`
+	// Ejection 实例因调用异常被摘除时的摘除信息，摘除期间不出现在服务发现结果中
+	Ejection *Ejection `json:"ejection,omitempty"`
+
+	outlier       *outlierState
+	registeredAt  time.Time // 首次注册到注册中心的时间，用于计算期望的心跳数
+	heartbeats    int       // 首次注册以来收到的心跳数，用于计算实例实际的心跳间隔
+	heartbeatLost bool      // 已记录心跳超时事件，恢复心跳前不再重复记录
+	token         string    // 注册时颁发的实例令牌，不对外序列化
 }
 
 // GetAddress 返回服务地址
 func (s *Service) GetAddress() string {
-	return fmt.Sprintf("%s:%d", s.IP, s.Port)
+	return net.JoinHostPort(s.IP, strconv.Itoa(s.Port))
 }
 
 // ValidateIP 验证IP地址格式
 func (s *Service) ValidateIP() error {
 	if s.IP == "" {
-		return fmt.Errorf("IP地址不能为空")
+		return ErrInvalidService.WithMessage("IP地址不能为空")
 	}
 
 	// 验证IP地址格式
 	ip := net.ParseIP(s.IP)
 	if ip == nil {
-		return fmt.Errorf("无效的IP地址格式: %s", s.IP)
+		return ErrInvalidService.WithMessage(fmt.Sprintf("无效的IP地址格式: %s", s.IP))
 	}
 
 	return nil
@@ -69,13 +96,44 @@ func (s *Service) UniqueID() string {
 	return fmt.Sprintf("%s-%s-%s", s.Name, s.Hostname, s.ID)
 }
 
+// HeartbeatLost 实例是否已心跳超时且尚未恢复
+func (s *Service) HeartbeatLost() bool {
+	return s.heartbeatLost
+}
+
 // ServiceRegistry 服务注册中心
 type ServiceRegistry struct {
-	services    map[string]*Service // 修改为使用 uniqueID -> Service
-	serviceMap  map[string][]string // 新增：服务名称 -> uniqueID列表的映射
+	namespaces  map[string]*namespace // 命名空间 -> 命名空间内的注册表
 	mutex       sync.RWMutex
 	healthCheck HealthCheck
 	balancer    LoadBalancer
+	locality    *LocalityBalancer
+	watchers    map[*watcher]struct{}
+	watchMutex  sync.Mutex
+
+	// spilloverThreshold 就近负载均衡的溢出阈值
+	spilloverThreshold float64
+
+	// restrictedNamespaces 不对匿名调用方开放读取的命名空间，创建后不再修改
+	restrictedNamespaces map[string]bool
+
+	// trafficPolicies 服务的路由规则，键为 <namespace>/<service>
+	trafficPolicies map[string]*routing.TrafficPolicy
+	trafficMutex    sync.RWMutex
+
+	// outlierPolicy 异常实例检测策略，为 nil 时不检测
+	outlierPolicy *OutlierPolicy
+
+	// preservation 自我保护模式，由 mutex 保护
+	preservation selfPreservation
+
+	// history 各服务的历史数据，由 mutex 保护
+	history history
+
+	// 增量同步相关，由 mutex 保护
+	revision          uint64
+	changes           []change
+	truncatedRevision uint64
 }
 
 // RegistryOption 定义注册中心的配置选项
@@ -94,3 +152,10 @@ func WithLoadBalancer(lb LoadBalancer) RegistryOption {
 		sr.balancer = lb
 	}
 }
+
+// WithSpilloverThreshold 设置就近负载均衡的溢出阈值，调用方可用区内健康实例占比低于该值时开始选择其他可用区的实例
+func WithSpilloverThreshold(threshold float64) RegistryOption {
+	return func(sr *ServiceRegistry) {
+		sr.spilloverThreshold = threshold
+	}
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+)
+
+// InstanceUpdate 实例的部分更新，为 nil 的字段保持不变
+type InstanceUpdate struct {
+	// Metadata 需要修改的元数据，值为 nil 时删除该键
+	Metadata map[string]*string
+	Weight   *int
+	Version  *string
+	// Revision 调用方读取到的实例版本号，不为 0 且与实例当前的版本号不一致时返回 ErrRevisionConflict
+	Revision uint64
+	// Token 实例更新自身信息时携带的实例令牌，不为 nil 时与注册时颁发的令牌不一致则返回 ErrInvalidInstanceToken。
+	// 管理员更新时为 nil
+	Token *string
+}
+
+// Empty 是否没有需要修改的字段
+func (u InstanceUpdate) Empty() bool {
+	return len(u.Metadata) == 0 && u.Weight == nil && u.Version == nil
+}
+
+// UpdateInstance 部分更新实例的元数据、权重和版本，不影响心跳、状态和启动时间，返回更新后实例的副本。
+// 内容没有变化时不修改实例的版本号，也不发布事件
+func (sr *ServiceRegistry) UpdateInstance(namespaceName, name, id string, update InstanceUpdate) (*Service, error) {
+	if update.Weight != nil && *update.Weight < 0 {
+		return nil, ErrInvalidService.WithMessage(fmt.Sprintf("无效的权重: %d", *update.Weight))
+	}
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+	uniqueIDs, exists := ns.serviceMap[name]
+	if !exists {
+		return nil, ErrServiceNotFound.WithMessage(fmt.Sprintf("服务 %s 不存在", name))
+	}
+
+	var service *Service
+	for _, uniqueID := range uniqueIDs {
+		if s, ok := ns.services[uniqueID]; ok && s.ID == id {
+			service = s
+			break
+		}
+	}
+	if service == nil {
+		return nil, ErrInstanceNotFound.WithMessage(fmt.Sprintf("服务实例 %s 不存在", id))
+	}
+	if update.Token != nil && (service.token == "" || subtle.ConstantTimeCompare([]byte(*update.Token), []byte(service.token)) != 1) {
+		return nil, ErrInvalidInstanceToken
+	}
+	if update.Revision != 0 && update.Revision != service.Revision {
+		return nil, ErrRevisionConflict.WithMessage(fmt.Sprintf("服务实例 %s 已被修改，当前版本号为 %d", id, service.Revision))
+	}
+
+	// 复制后替换，已返回给调用方的实例不受影响
+	updated := *service
+	updated.Metadata = make(map[string]string, len(service.Metadata)+len(update.Metadata))
+	for key, value := range service.Metadata {
+		updated.Metadata[key] = value
+	}
+	for key, value := range update.Metadata {
+		if value == nil {
+			delete(updated.Metadata, key)
+		} else {
+			updated.Metadata[key] = *value
+		}
+	}
+	if update.Weight != nil {
+		updated.Weight = *update.Weight
+	}
+	if update.Version != nil {
+		updated.Version = *update.Version
+	}
+
+	metadata := metadataDiff(service.Metadata, updated.Metadata)
+	if metadata == "" && updated.Weight == service.Weight && updated.Version == service.Version {
+		return &updated, nil
+	}
+
+	ns.unindex(service)
+	ns.services[service.UniqueID()] = &updated
+	ns.index(&updated)
+	sr.recordChange(ns, service, &updated)
+
+	if updated.Version != service.Version {
+		sr.publishDetail(EventVersionChanged, &updated, fmt.Sprintf("%s -> %s", service.Version, updated.Version))
+	}
+	if metadata != "" {
+		sr.publishDetail(EventMetadataChanged, &updated, metadata)
+	}
+	if updated.Weight != service.Weight {
+		sr.publishDetail(EventWeightChanged, &updated, fmt.Sprintf("%d -> %d", service.Weight, updated.Weight))
+	}
+	// 返回副本，注册表中的实例在释放锁后仍会被心跳更新
+	result := updated
+	return &result, nil
+}
+
+// VerifyInstanceToken 校验实例令牌，实例不存在或令牌不一致时返回 ErrInvalidInstanceToken，
+// 用于确认请求来自命名空间内已注册的实例
+func (sr *ServiceRegistry) VerifyInstanceToken(namespaceName, name, id, token string) error {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	ns := sr.namespaceLocked(namespaceName)
+	if ns == nil || token == "" {
+		return ErrInvalidInstanceToken
+	}
+	for _, uniqueID := range ns.serviceMap[name] {
+		if s, ok := ns.services[uniqueID]; ok && s.ID == id {
+			if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				return ErrInvalidInstanceToken
+			}
+			return nil
+		}
+	}
+	return ErrInvalidInstanceToken
+}
+
+// Token 返回注册时颁发的实例令牌，实例更新自身信息时用于证明身份，每次注册都会重新颁发
+func (s *Service) Token() string {
+	return s.token
+}
+
+// newInstanceToken 生成随机的实例令牌
+func newInstanceToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+func stringPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+func TestUpdateInstanceToken(t *testing.T) {
+	sr := NewServiceRegistry()
+	service := registerTestInstance(t, sr, "api", "api-1")
+	token := service.Token()
+	if token == "" {
+		t.Fatal("注册后应颁发实例令牌")
+	}
+
+	tests := []struct {
+		name    string
+		token   *string
+		wantErr error
+	}{
+		{name: "缺少令牌", token: stringPtr(""), wantErr: ErrInvalidInstanceToken},
+		{name: "令牌不匹配", token: stringPtr("forged"), wantErr: ErrInvalidInstanceToken},
+		{name: "令牌正确", token: stringPtr(token)},
+		{name: "管理员更新不校验令牌", token: nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sr.UpdateInstance(DefaultNamespace, "api", "api-1", InstanceUpdate{Weight: intPtr(i + 1), Token: tt.token})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("错误 = %v, 期望 %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("更新失败: %v", err)
+			}
+		})
+	}
+
+	// 重新注册后旧令牌失效
+	reregistered := registerTestInstance(t, sr, "api", "api-1")
+	if reregistered.Token() == token {
+		t.Fatal("重新注册应颁发新的实例令牌")
+	}
+	if _, err := sr.UpdateInstance(DefaultNamespace, "api", "api-1", InstanceUpdate{Weight: intPtr(9), Token: &token}); !errors.Is(err, ErrInvalidInstanceToken) {
+		t.Errorf("使用旧令牌更新，错误 = %v, 期望 %v", err, ErrInvalidInstanceToken)
+	}
+}
+
+func TestUpdateInstanceReturnsCopy(t *testing.T) {
+	sr := NewServiceRegistry()
+	registerTestInstance(t, sr, "api", "api-1")
+
+	for _, update := range []InstanceUpdate{
+		{Weight: intPtr(5)},
+		// 内容没有变化
+		{Weight: intPtr(5)},
+	} {
+		before := sr.Snapshot(DefaultNamespace).Revision
+		updated, err := sr.UpdateInstance(DefaultNamespace, "api", "api-1", update)
+		if err != nil {
+			t.Fatalf("更新失败: %v", err)
+		}
+		for _, stored := range sr.ListAllServices(DefaultNamespace)["api"] {
+			if stored == updated {
+				t.Fatal("UpdateInstance 不应返回注册表中的实例")
+			}
+		}
+		if updated.Weight != 5 {
+			t.Errorf("Weight = %d, 期望 5", updated.Weight)
+		}
+		if after := sr.Snapshot(DefaultNamespace).Revision; updated.Revision != after {
+			t.Errorf("返回的实例版本号 %d 与注册表版本号 %d 不一致（更新前 %d）", updated.Revision, after, before)
+		}
+	}
+}
+
+func TestUpdateInstanceRevisionConflict(t *testing.T) {
+	sr := NewServiceRegistry()
+	registerTestInstance(t, sr, "api", "api-1")
+
+	first, err := sr.UpdateInstance(DefaultNamespace, "api", "api-1", InstanceUpdate{Version: stringPtr("1.1.0")})
+	if err != nil {
+		t.Fatalf("更新失败: %v", err)
+	}
+	stale := first.Revision - 1
+	if _, err := sr.UpdateInstance(DefaultNamespace, "api", "api-1", InstanceUpdate{Version: stringPtr("1.2.0"), Revision: stale}); !errors.Is(err, ErrRevisionConflict) {
+		t.Fatalf("错误 = %v, 期望 %v", err, ErrRevisionConflict)
+	}
+	if _, err := sr.UpdateInstance(DefaultNamespace, "api", "api-1", InstanceUpdate{Version: stringPtr("1.2.0"), Revision: first.Revision}); err != nil {
+		t.Fatalf("使用最新版本号更新失败: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/metrics"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchBufferSize 每个订阅者的事件缓冲区大小
+const watchBufferSize = 64
+
+// EventType 注册中心事件类型
+type EventType string
+
+const (
+	// EventRegistered 实例注册
+	EventRegistered EventType = "REGISTERED"
+	// EventDeregistered 实例主动注销
+	EventDeregistered EventType = "DEREGISTERED"
+	// EventExpired 实例心跳超时被剔除
+	EventExpired EventType = "EXPIRED"
+	// EventHeartbeatLost 实例心跳超时，自我保护模式下不会被剔除
+	EventHeartbeatLost EventType = "HEARTBEAT_LOST"
+	// EventHeartbeatRecovered 心跳超时但未被剔除的实例恢复心跳
+	EventHeartbeatRecovered EventType = "HEARTBEAT_RECOVERED"
+	// EventStatusChanged 实例状态变化
+	EventStatusChanged EventType = "STATUS_CHANGED"
+	// EventMetadataChanged 实例重新注册或更新时元数据变化
+	EventMetadataChanged EventType = "METADATA_CHANGED"
+	// EventVersionChanged 实例重新注册或更新时版本变化
+	EventVersionChanged EventType = "VERSION_CHANGED"
+	// EventWeightChanged 实例更新时权重变化
+	EventWeightChanged EventType = "WEIGHT_CHANGED"
+	// EventEjected 实例因调用异常被摘除
+	EventEjected EventType = "EJECTED"
+	// EventRestored 被摘除的实例到期恢复
+	EventRestored EventType = "RESTORED"
+)
+
+// Event 服务实例变化事件，Service 为事件发生时的实例快照，Detail 为变化的说明
+type Event struct {
+	Type    EventType `json:"type"`
+	Service Service   `json:"service"`
+	Detail  string    `json:"detail,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+type watcher struct {
+	namespace string
+	name      string
+	events    chan Event
+	queue     *eventQueue // 不为 nil 时事件先进入无界队列，不丢弃
+}
+
+// eventQueue 不丢弃事件的订阅者的无界队列，由 pump 按顺序送入订阅者的通道
+type eventQueue struct {
+	mutex  sync.Mutex
+	events []Event
+	notify chan struct{} // 有新事件时通知 pump
+	done   chan struct{} // 取消订阅时关闭
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+func (q *eventQueue) push(event Event) {
+	q.mutex.Lock()
+	q.events = append(q.events, event)
+	q.mutex.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pump 将队列中的事件依次送入 out，取消订阅后关闭 out 并丢弃未送出的事件
+func (q *eventQueue) pump(out chan<- Event) {
+	defer close(out)
+	for {
+		q.mutex.Lock()
+		events := q.events
+		q.events = nil
+		q.mutex.Unlock()
+
+		for _, event := range events {
+			select {
+			case out <- event:
+			case <-q.done:
+				return
+			}
+		}
+
+		select {
+		case <-q.notify:
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// Subscribe 订阅命名空间内服务实例的变化，name 为空时订阅命名空间内的所有服务，
+// namespace 也为空时订阅所有命名空间，返回事件通道和取消订阅函数
+//
+// 订阅者处理过慢导致缓冲区已满时丢弃事件并计入 watch_events_dropped_total 指标，
+// 订阅者应将事件视为变化通知并重新获取实例列表；需要完整事件时使用 SubscribeLossless
+func (sr *ServiceRegistry) Subscribe(namespaceName, name string) (<-chan Event, func()) {
+	w := &watcher{
+		namespace: namespaceName,
+		name:      name,
+		events:    make(chan Event, watchBufferSize),
+	}
+	return w.events, sr.addWatcher(w)
+}
+
+// SubscribeLossless 与 Subscribe 相同，但订阅者处理过慢时事件在内存中排队而不丢弃，
+// 用于需要完整记录每个事件的订阅者，如事件持久化。订阅者长时间不读取时队列会持续增长
+func (sr *ServiceRegistry) SubscribeLossless(namespaceName, name string) (<-chan Event, func()) {
+	w := &watcher{
+		namespace: namespaceName,
+		name:      name,
+		events:    make(chan Event, watchBufferSize),
+		queue:     newEventQueue(),
+	}
+	go w.queue.pump(w.events)
+	return w.events, sr.addWatcher(w)
+}
+
+// addWatcher 添加订阅者，返回取消订阅函数
+func (sr *ServiceRegistry) addWatcher(w *watcher) func() {
+	sr.watchMutex.Lock()
+	sr.watchers[w] = struct{}{}
+	sr.watchMutex.Unlock()
+
+	return func() {
+		sr.watchMutex.Lock()
+		defer sr.watchMutex.Unlock()
+		if _, ok := sr.watchers[w]; ok {
+			delete(sr.watchers, w)
+			if w.queue != nil {
+				close(w.queue.done)
+			} else {
+				close(w.events)
+			}
+		}
+	}
+}
+
+// publish 通知订阅者，不阻塞注册表的写操作
+func (sr *ServiceRegistry) publish(eventType EventType, service *Service) {
+	sr.publishDetail(eventType, service, "")
+}
+
+// publishDetail 通知订阅者并附带变化的说明
+func (sr *ServiceRegistry) publishDetail(eventType EventType, service *Service, detail string) {
+	event := Event{
+		Type:    eventType,
+		Service: *service,
+		Detail:  detail,
+		Time:    time.Now(),
+	}
+
+	sr.watchMutex.Lock()
+	defer sr.watchMutex.Unlock()
+
+	for w := range sr.watchers {
+		if w.namespace != "" && w.namespace != service.Namespace {
+			continue
+		}
+		if w.name != "" && w.name != service.Name {
+			continue
+		}
+		if w.queue != nil {
+			w.queue.push(event)
+			continue
+		}
+		select {
+		case w.events <- event:
+		default:
+			metrics.WatchEventsDropped.WithLabelValues(service.Namespace, service.Name).Inc()
+			logger.WarnLogger.Printf("订阅者处理过慢，丢弃事件: %s %s/%s", eventType, service.Namespace, service.UniqueID())
+		}
+	}
+}
+
+// publishRegistration 发布注册事件。实例重新注册时版本或元数据发生变化则发布对应的变化事件，否则发布注册事件
+func (sr *ServiceRegistry) publishRegistration(previous, service *Service) {
+	if previous == nil {
+		sr.publish(EventRegistered, service)
+		return
+	}
+
+	changed := false
+	if previous.Version != service.Version {
+		sr.publishDetail(EventVersionChanged, service, fmt.Sprintf("%s -> %s", previous.Version, service.Version))
+		changed = true
+	}
+	if detail := metadataDiff(previous.Metadata, service.Metadata); detail != "" {
+		sr.publishDetail(EventMetadataChanged, service, detail)
+		changed = true
+	}
+	if !changed {
+		sr.publish(EventRegistered, service)
+	}
+}
+
+// metadataDiff 描述元数据的变化，如 +zone=a,-canary,env=dev->prod，没有变化时返回空字符串
+func metadataDiff(previous, current map[string]string) string {
+	keys := make([]string, 0, len(previous)+len(current))
+	for key := range previous {
+		keys = append(keys, key)
+	}
+	for key := range current {
+		if _, ok := previous[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]string, 0)
+	for _, key := range keys {
+		old, hadOld := previous[key]
+		value, hasNew := current[key]
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("+%s=%s", key, value))
+		case !hasNew:
+			changes = append(changes, "-"+key)
+		case old != value:
+			changes = append(changes, fmt.Sprintf("%s=%s->%s", key, old, value))
+		}
+	}
+	return strings.Join(changes, ",")
+}
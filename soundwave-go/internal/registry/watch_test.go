@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"soundwave-go/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSubscribeOverflow(t *testing.T) {
+	const published = watchBufferSize + 20
+
+	tests := []struct {
+		name      string
+		subscribe func(sr *ServiceRegistry) (<-chan Event, func())
+		want      int
+	}{
+		{name: "缓冲区满后丢弃事件", subscribe: func(sr *ServiceRegistry) (<-chan Event, func()) { return sr.Subscribe("", "") }, want: watchBufferSize},
+		{name: "不丢弃事件的订阅", subscribe: func(sr *ServiceRegistry) (<-chan Event, func()) { return sr.SubscribeLossless("", "") }, want: published},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace := fmt.Sprintf("watch-test-%d", tt.want)
+			dropped := metrics.WatchEventsDropped.WithLabelValues(namespace, "api")
+			before := testutil.ToFloat64(dropped)
+
+			sr := NewServiceRegistry()
+			events, cancel := tt.subscribe(sr)
+			defer cancel()
+
+			// 订阅者读取之前发布全部事件
+			for i := 0; i < published; i++ {
+				if err := sr.RegisterService(&Service{Namespace: namespace, Name: "api", ID: fmt.Sprintf("api-%d", i), Hostname: "h", IP: "10.0.0.1", Port: 80}); err != nil {
+					t.Fatalf("注册失败: %v", err)
+				}
+			}
+
+			received := 0
+			timeout := time.After(time.Second)
+		loop:
+			for received < published {
+				select {
+				case event := <-events:
+					if want := fmt.Sprintf("api-%d", received); event.Service.ID != want {
+						t.Fatalf("第 %d 个事件为 %s, 期望 %s", received+1, event.Service.ID, want)
+					}
+					received++
+				case <-timeout:
+					break loop
+				}
+			}
+			if received != tt.want {
+				t.Errorf("收到 %d 个事件, 期望 %d", received, tt.want)
+			}
+			if got := testutil.ToFloat64(dropped) - before; got != float64(published-tt.want) {
+				t.Errorf("丢弃计数 = %v, 期望 %d", got, published-tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeLosslessCancel(t *testing.T) {
+	sr := NewServiceRegistry()
+	events, cancel := sr.SubscribeLossless("", "")
+	for i := 0; i < watchBufferSize*2; i++ {
+		registerTestInstance(t, sr, "api", fmt.Sprintf("api-%d", i))
+	}
+
+	// 取消订阅后通道关闭，未送出的事件被丢弃
+	cancel()
+	cancel()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("取消订阅后通道未关闭")
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
+	apperrors "soundwave-go/internal/errors"
 	"soundwave-go/internal/models"
 	"soundwave-go/internal/utils"
 
@@ -11,22 +13,30 @@ import (
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Provider string `json:"provider"` // 为空或 local 表示本地账户，否则为 LDAP 等身份源名称
 }
 
+// LoginMFARequest 登录第二步，提交第一步返回的 mfa_token 和 TOTP 或恢复码
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// 密码长度和复杂度由 security.password_policy 配置校验
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=32"`
-	Password string `json:"password" binding:"required,min=6,max=32"`
+	Password string `json:"password" binding:"required"`
 }
 
 type ChangePasswordRequest struct {
-	OldPassword string `json:"oldPassword" binding:"required,min=6,max=32"`
-	NewPassword string `json:"newPassword" binding:"required,min=6,max=32"`
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
 }
 
 func (s *Server) HandleRegister(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperrors.InvalidRequest(err))
 		return
 	}
 
@@ -36,7 +46,7 @@ func (s *Server) HandleRegister(c *gin.Context) {
 	}
 
 	if err := s.authService.Register(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -46,39 +56,98 @@ func (s *Server) HandleRegister(c *gin.Context) {
 func (s *Server) HandleLogin(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	var user *models.User
+	if req.Provider != "" && req.Provider != "local" {
+		var ok bool
+		if user, ok = s.loginWithProvider(c, req); !ok {
+			return
+		}
+	} else {
+		var err error
+		user, err = s.authService.Login(req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	// 启用 MFA 的用户需要完成第二步验证
+	if user.MFAEnabled {
+		mfaToken, err := s.authService.GenerateMFAToken(user)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+		return
+	}
+
+	s.respondLoginSuccess(c, user)
+}
+
+// HandleLoginMFA 登录第二步，校验 TOTP 验证码或恢复码
+func (s *Server) HandleLoginMFA(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
 		return
 	}
 
-	user, err := s.authService.Login(req.Username, req.Password)
+	user, err := s.authService.VerifyLoginMFA(req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	s.respondLoginSuccess(c, user)
+}
+
+// respondLoginSuccess 生成会话 token 并返回登录结果
+func (s *Server) respondLoginSuccess(c *gin.Context, user *models.User) {
 	// 生成 JWT token
-	token, err := s.authService.GenerateToken(user)
+	token, mfaSetupRequired, err := s.authService.GenerateToken(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user": gin.H{
-			"id":          user.ID,
-			"username":    user.Username,
-			"role":        user.Role,
-			"permissions": user.Permissions,
-		},
+		"token":                token,
+		"must_change_password": user.MustChangePassword,
+		"mfa_setup_required":   mfaSetupRequired,
+		"user":                 loginUser(user),
 	})
 }
 
+// loginUser 登录响应中返回的用户信息
+func loginUser(user *models.User) gin.H {
+	return gin.H{
+		"id":          user.ID,
+		"username":    user.Username,
+		"role":        user.Role,
+		"permissions": user.Permissions,
+		"provider":    user.Provider,
+	}
+}
+
+// userJSON 将登录用户信息编码为 JSON 字符串，用于跳转 URL
+func userJSON(user *models.User) string {
+	data, _ := json.Marshal(loginUser(user))
+	return string(data)
+}
+
 func (s *Server) GetUserMenus(c *gin.Context) {
 	user := c.MustGet("user").(*utils.Claims)
 	menus, err := s.menuService.GetUserMenus(user.Permissions)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -88,7 +157,7 @@ func (s *Server) GetUserMenus(c *gin.Context) {
 func (s *Server) HandleChangePassword(c *gin.Context) {
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperrors.InvalidRequest(err))
 		return
 	}
 
@@ -97,7 +166,13 @@ func (s *Server) HandleChangePassword(c *gin.Context) {
 
 	err := s.authService.ChangePassword(claims.UserID, req.OldPassword, req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+
+	// 强制改密的 token 仍受限，需要重新登录获取新 token
+	if claims.MustChangePassword {
+		c.JSON(http.StatusOK, gin.H{"message": "密码修改成功，请重新登录", "relogin": true})
 		return
 	}
 
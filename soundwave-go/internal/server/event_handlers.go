@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAvailabilityWindow 未指定 since 时计算可用性的时间范围
+const defaultAvailabilityWindow = 24 * time.Hour
+
+// timeRange 解析 RFC3339 格式的 since 和 until 参数，未指定时为零值
+func timeRange(c *gin.Context) (since, until time.Time, ok bool) {
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的" + param.name + "参数，需为RFC3339格式"))
+			return since, until, false
+		}
+		*param.value = t
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("until不能早于since"))
+		return since, until, false
+	}
+	return since, until, true
+}
+
+// ListServiceEvents 分页查询服务实例的生命周期事件，支持按实例、事件类型和时间筛选
+func (s *Server) ListServiceEvents(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+	since, until, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	filter := service.EventFilter{
+		Namespace:  namespace,
+		Service:    c.Param("name"),
+		InstanceID: c.Query("instance"),
+		Since:      since,
+		Until:      until,
+		Page:       1,
+		PageSize:   50,
+	}
+	for _, value := range c.QueryArray("type") {
+		for _, eventType := range strings.Split(value, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				filter.Types = append(filter.Types, strings.ToUpper(eventType))
+			}
+		}
+	}
+	for _, param := range []struct {
+		name  string
+		value *int64
+	}{{"page", &filter.Page}, {"page_size", &filter.PageSize}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || value <= 0 || (param.name == "page_size" && value > 500) {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的" + param.name + "参数"))
+			return
+		}
+		*param.value = value
+	}
+
+	events, total, err := s.eventService.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":    events,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// GetServiceAvailability 根据生命周期事件计算服务各实例的可用性，默认统计最近24小时
+func (s *Server) GetServiceAvailability(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+	since, until, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if since.IsZero() {
+		since = until.Add(-defaultAvailabilityWindow)
+	}
+
+	instances, err := s.eventService.Availability(c.Request.Context(), namespace, c.Param("name"), since, until)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since":     since,
+		"until":     until,
+		"instances": instances,
+	})
+}
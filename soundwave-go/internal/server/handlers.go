@@ -2,7 +2,10 @@ package server
 
 import (
 	"net/http"
+	apperrors "soundwave-go/internal/errors"
 	"soundwave-go/internal/registry"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,82 +14,170 @@ import (
 func (s *Server) RegisterService(c *gin.Context) {
 	var req ServiceRegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "无效的请求参数: " + err.Error(),
-		})
+		c.Error(apperrors.InvalidRequest(err))
 		return
 	}
 
+	// 请求体未指定命名空间时使用查询参数
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = c.Query("namespace")
+	}
+
 	service := &registry.Service{
-		Name:     req.Name,
-		ID:       req.ID,
-		Hostname: req.Hostname,
-		IP:       req.IP,
-		Port:     req.Port,
-		Metadata: req.Metadata,
-		Version:  req.Version,
+		Namespace: namespace,
+		Name:      req.Name,
+		ID:        req.ID,
+		Hostname:  req.Hostname,
+		IP:        req.IP,
+		Port:      req.Port,
+		Metadata:  req.Metadata,
+		Version:   req.Version,
+		Weight:    req.Weight,
+		Status:    registry.ServiceStatus(strings.ToUpper(req.Status)),
+		Tags:      req.Tags,
+		Region:    req.Region,
+		Zone:      req.Zone,
 	}
 
 	if err := s.registry.RegisterService(service); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "服务注册失败: " + err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
+	// 实例令牌只在注册响应中返回，实例更新自身信息时使用
 	c.JSON(http.StatusOK, gin.H{
 		"message": "服务注册成功",
 		"service": service,
+		"token":   service.Token(),
 	})
 }
 
+// instanceQuery 解析实例筛选参数：
+//   - tag: 标签，可重复或以逗号分隔，需全部包含
+//   - selector: 元数据选择器，如 env=prod,region in (cn-east,cn-north),!deprecated
+//   - version: 语义化版本范围，如 ^1.2.0、>=1.0.0 <2.0.0
+//   - zone: 可用区
+func instanceQuery(c *gin.Context) (*registry.InstanceQuery, error) {
+	return registry.ParseInstanceQuery(c.QueryArray("tag"), c.Query("selector"), c.Query("version"), c.Query("zone"))
+}
+
 // DiscoverService 处理服务发现请求
 func (s *Server) DiscoverService(c *gin.Context) {
 	serviceName := c.Param("name")
 	if serviceName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "服务名称不能为空",
-		})
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("服务名称不能为空"))
 		return
 	}
 
-	services, err := s.registry.GetService(serviceName)
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	query, err := instanceQuery(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	services, err := s.registry.FindServices(namespace, serviceName, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := gin.H{
 		"services": services,
-	})
+	}
+	// 下发路由规则，供客户端负载均衡使用
+	if policy := s.registry.TrafficPolicy(namespace, serviceName); policy != nil {
+		response["rules"] = policy.Rules
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// ListServices 获取所有注册的服务
+// ListServices 获取命名空间内所有注册的服务，revision 和 hash 用于后续增量同步
 func (s *Server) ListServices(c *gin.Context) {
-	services := s.registry.ListAllServices()
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	snapshot := s.registry.Snapshot(namespace)
 	c.JSON(http.StatusOK, gin.H{
-		"services": services,
+		"namespace":         snapshot.Namespace,
+		"services":          snapshot.Services,
+		"revision":          snapshot.Revision,
+		"hash":              snapshot.Hash,
+		"self_preservation": s.registry.SelfPreservation(),
+	})
+}
+
+// GetRegistryStatus 获取注册中心的状态
+func (s *Server) GetRegistryStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"self_preservation": s.registry.SelfPreservation(),
 	})
 }
 
+// GetServicesDelta 获取指定版本之后的增量变化，版本过旧时返回全量数据
+func (s *Server) GetServicesDelta(c *gin.Context) {
+	since, err := strconv.ParseUint(c.Query("since"), 10, 64)
+	if err != nil {
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的since参数"))
+		return
+	}
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, s.registry.Delta(namespace, since))
+}
+
 // UpdateHeartbeat 处理服务心跳请求
 func (s *Server) UpdateHeartbeat(c *gin.Context) {
 	serviceName := c.Param("name")
 	serviceID := c.Param("id")
 
 	if serviceName == "" || serviceID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "服务名称和ID不能为空",
-		})
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("服务名称和ID不能为空"))
+		return
+	}
+
+	namespace, ok := s.instanceNamespaceQuery(c)
+	if !ok {
 		return
 	}
 
-	if err := s.registry.UpdateHeartbeat(serviceName, serviceID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+	// 请求体可选，携带实例的状态、依赖的健康情况和负载
+	report := &registry.HeartbeatReport{}
+	if c.Request.ContentLength != 0 {
+		var req HeartbeatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(apperrors.InvalidRequest(err))
+			return
+		}
+		report.Status = registry.ServiceStatus(strings.ToUpper(req.Status))
+		for name, check := range req.Checks {
+			check.Status = registry.ServiceStatus(strings.ToUpper(string(check.Status)))
+			req.Checks[name] = check
+		}
+		report.Checks = req.Checks
+		if req.InFlight != nil || req.LatencyMs != nil {
+			report.Load = &registry.LoadReport{}
+			if req.InFlight != nil {
+				report.Load.InFlight = *req.InFlight
+			}
+			if req.LatencyMs != nil {
+				report.Load.LatencyMs = *req.LatencyMs
+			}
+		}
+	}
+
+	if err := s.registry.UpdateHeartbeat(namespace, serviceName, serviceID, report); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -95,21 +186,143 @@ func (s *Server) UpdateHeartbeat(c *gin.Context) {
 	})
 }
 
+// ReportOutcome 处理调用方上报的实例调用结果，实例达到摘除条件时返回摘除信息。
+// 只接受同一命名空间内已注册实例的上报，调用方凭自身的实例令牌证明身份
+func (s *Server) ReportOutcome(c *gin.Context) {
+	serviceName := c.Param("name")
+	serviceID := c.Param("id")
+
+	var req ReportOutcomeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+	if req.Successes+req.Failures == 0 {
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("上报的调用次数不能为0"))
+		return
+	}
+
+	namespace, ok := s.instanceNamespaceQuery(c)
+	if !ok {
+		return
+	}
+	if err := s.registry.VerifyInstanceToken(namespace, req.CallerService, req.CallerID, c.GetHeader(instanceTokenHeader)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	ejection, err := s.registry.ReportOutcome(namespace, serviceName, serviceID, registry.OutlierReport{
+		Source:    registry.SourceCaller,
+		Successes: req.Successes,
+		Failures:  req.Failures,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "上报成功",
+		"ejection": ejection,
+	})
+}
+
+// GetInstance 获取单个实例的详情，包括心跳上报的状态、依赖健康情况、负载和摘除信息
+func (s *Server) GetInstance(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	service, err := s.registry.GetInstance(namespace, c.Param("name"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service": service,
+	})
+}
+
+// instanceTokenHeader 实例更新自身信息时携带注册时颁发的实例令牌的请求头
+const instanceTokenHeader = "X-Instance-Token"
+
+// UpdateInstance 实例更新自身的元数据、权重和版本，无需重新注册。
+// 需通过 X-Instance-Token 请求头携带注册时颁发的实例令牌
+func (s *Server) UpdateInstance(c *gin.Context) {
+	token := c.GetHeader(instanceTokenHeader)
+	s.updateInstance(c, &token)
+}
+
+// AdminUpdateInstance 管理员部分更新实例的元数据、权重和版本，不校验实例令牌
+func (s *Server) AdminUpdateInstance(c *gin.Context) {
+	s.updateInstance(c, nil)
+}
+
+// updateInstance 部分更新实例，token 为 nil 时不校验实例令牌，指定 revision 时实例已被修改则返回冲突
+func (s *Server) updateInstance(c *gin.Context, token *string) {
+	serviceName := c.Param("name")
+	serviceID := c.Param("id")
+
+	var req UpdateInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	// 管理员按命名空间权限校验，实例凭实例令牌更新自身
+	var namespace string
+	var ok bool
+	if token == nil {
+		namespace, ok = s.namespaceQuery(c)
+	} else {
+		namespace, ok = s.instanceNamespaceQuery(c)
+	}
+	if !ok {
+		return
+	}
+
+	update := registry.InstanceUpdate{
+		Metadata: req.Metadata,
+		Weight:   req.Weight,
+		Version:  req.Version,
+		Revision: req.Revision,
+		Token:    token,
+	}
+	if update.Empty() {
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("没有需要更新的字段"))
+		return
+	}
+
+	service, err := s.registry.UpdateInstance(namespace, serviceName, serviceID, update)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "服务实例更新成功",
+		"service": service,
+	})
+}
+
 // GetServiceStats 获取服务统计信息
 func (s *Server) GetServiceStats(c *gin.Context) {
 	serviceName := c.Param("name")
 	if serviceName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "服务名称不能为空",
-		})
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("服务名称不能为空"))
+		return
+	}
+
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
 		return
 	}
 
-	stats, err := s.registry.GetServiceStats(serviceName)
+	stats, err := s.registry.GetServiceStats(namespace, serviceName)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -117,3 +330,17 @@ func (s *Server) GetServiceStats(c *gin.Context) {
 		"stats": stats,
 	})
 }
+
+// ListServiceStats 获取命名空间内所有服务的统计信息
+func (s *Server) ListServiceStats(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":         namespace,
+		"stats":             s.registry.ListServiceStats(namespace),
+		"self_preservation": s.registry.SelfPreservation(),
+	})
+}
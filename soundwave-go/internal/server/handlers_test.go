@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"soundwave-go/internal/middleware"
+	"soundwave-go/internal/registry"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReportOutcomeRequiresCallerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sr := registry.NewServiceRegistry(registry.WithOutlierDetection(registry.DefaultOutlierPolicy()))
+	caller := &registry.Service{Name: "web", ID: "1", Hostname: "h1", IP: "10.0.0.1", Port: 80}
+	for _, service := range []*registry.Service{
+		caller,
+		{Name: "api", ID: "1", Hostname: "h2", IP: "10.0.0.2", Port: 80},
+		{Namespace: "staging", Name: "web", ID: "1", Hostname: "h3", IP: "10.0.0.3", Port: 80},
+	} {
+		if err := sr.RegisterService(service); err != nil {
+			t.Fatalf("注册失败: %v", err)
+		}
+	}
+
+	s := &Server{registry: sr}
+	engine := gin.New()
+	engine.Use(middleware.ErrorHandler())
+	engine.POST("/services/:name/:id/report", s.ReportOutcome)
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		token  string
+		want   int
+	}{
+		{name: "已注册的调用方", target: "/services/api/1/report", body: `{"caller_service":"web","caller_id":"1","failures":1}`, token: caller.Token(), want: http.StatusOK},
+		{name: "未携带实例令牌", target: "/services/api/1/report", body: `{"caller_service":"web","caller_id":"1","failures":1}`, want: http.StatusUnauthorized},
+		{name: "实例令牌与调用方不一致", target: "/services/api/1/report", body: `{"caller_service":"web","caller_id":"2","failures":1}`, token: caller.Token(), want: http.StatusUnauthorized},
+		{name: "调用方不在同一命名空间", target: "/services/api/1/report?namespace=staging", body: `{"caller_service":"web","caller_id":"1","failures":1}`, token: caller.Token(), want: http.StatusUnauthorized},
+		{name: "未指定调用方", target: "/services/api/1/report", body: `{"failures":1}`, token: caller.Token(), want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set(instanceTokenHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("状态码 = %d, 期望 %d，响应: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	apperrors "soundwave-go/internal/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultHistoryWindow 未指定 since 时查询历史数据的时间范围
+const defaultHistoryWindow = time.Hour
+
+// GetServiceHistory 查询服务的实例数量、心跳延迟和注册变化的历史数据，默认返回最近1小时。
+// step 为每个点的时长，如 1m，不指定时按时间范围自动计算
+func (s *Server) GetServiceHistory(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+	since, until, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if since.IsZero() {
+		since = until.Add(-defaultHistoryWindow)
+	}
+
+	var step time.Duration
+	if raw := c.Query("step"); raw != "" {
+		var err error
+		if step, err = time.ParseDuration(raw); err != nil || step <= 0 {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的step参数，如 30s、5m"))
+			return
+		}
+	}
+
+	history, err := s.historyService.Range(c.Request.Context(), namespace, c.Param("name"), since, until, step)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
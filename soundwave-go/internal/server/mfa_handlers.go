@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// GetMFAStatus 查询当前用户的 MFA 状态
+func (s *Server) GetMFAStatus(c *gin.Context) {
+	claims := c.MustGet("user").(*utils.Claims)
+
+	status, err := s.authService.GetMFAStatus(c.Request.Context(), claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mfa": status})
+}
+
+// SetupMFA 生成 TOTP 密钥和二维码 URI
+func (s *Server) SetupMFA(c *gin.Context) {
+	claims := c.MustGet("user").(*utils.Claims)
+
+	setup, err := s.authService.SetupMFA(c.Request.Context(), claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"setup": setup})
+}
+
+// EnableMFA 校验验证码并启用 MFA，恢复码仅在此返回一次
+func (s *Server) EnableMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	claims := c.MustGet("user").(*utils.Claims)
+	codes, err := s.authService.EnableMFA(c.Request.Context(), claims.UserID, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "MFA启用成功，请妥善保存恢复码",
+		"recovery_codes": codes,
+		"relogin":        claims.MFASetupRequired,
+	})
+}
+
+// DisableMFA 关闭 MFA
+func (s *Server) DisableMFA(c *gin.Context) {
+	var req DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	claims := c.MustGet("user").(*utils.Claims)
+	if err := s.authService.DisableMFA(c.Request.Context(), claims.UserID, req.Password, req.Code); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "MFA已关闭"})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (s *Server) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	claims := c.MustGet("user").(*utils.Claims)
+	codes, err := s.authService.RegenerateRecoveryCodes(c.Request.Context(), claims.UserID, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "恢复码已重新生成",
+		"recovery_codes": codes,
+	})
+}
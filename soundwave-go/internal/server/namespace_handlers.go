@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+
+	"soundwave-go/internal/middleware"
+	"soundwave-go/internal/registry"
+
+	"github.com/gin-gonic/gin"
+)
+
+// namespaceQuery 读取 namespace 查询参数，未指定时使用默认命名空间，并校验当前请求能否读取该命名空间，
+// 校验失败时记录错误并返回 false
+func (s *Server) namespaceQuery(c *gin.Context) (string, bool) {
+	namespace, ok := s.instanceNamespaceQuery(c)
+	if !ok {
+		return "", false
+	}
+	if _, authenticated := c.Get("user"); authenticated {
+		if !middleware.CanAccessNamespace(c, namespace) {
+			c.Error(middleware.ErrNamespaceForbidden)
+			return "", false
+		}
+	} else if s.registry.NamespaceRestricted(namespace) {
+		// 匿名请求来自公共接口，受限的命名空间需登录后通过 /api 接口读取
+		c.Error(registry.ErrNamespaceRestricted)
+		return "", false
+	}
+	return namespace, true
+}
+
+// instanceNamespaceQuery 读取实例发送心跳、上报调用结果时的 namespace 查询参数，只校验名称格式。
+// 实例只操作自身，受限的命名空间内的实例同样可以调用
+func (s *Server) instanceNamespaceQuery(c *gin.Context) (string, bool) {
+	namespace, err := registry.NormalizeNamespace(c.Query("namespace"))
+	if err != nil {
+		c.Error(err)
+		return "", false
+	}
+	return namespace, true
+}
+
+// ListNamespaces 获取当前用户可访问的命名空间
+func (s *Server) ListNamespaces(c *gin.Context) {
+	namespaces := make([]registry.NamespaceInfo, 0)
+	for _, info := range s.registry.ListNamespaces() {
+		if middleware.CanAccessNamespace(c, info.Name) {
+			namespaces = append(namespaces, info)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespaces": namespaces,
+	})
+}
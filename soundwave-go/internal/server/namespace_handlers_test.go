@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/middleware"
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/registry"
+	"soundwave-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListServicesNamespaceAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.DefaultConfig()
+	sr := registry.NewServiceRegistry(registry.WithRestrictedNamespaces([]string{"prod"}))
+	for _, namespace := range []string{"prod", "staging"} {
+		if err := sr.RegisterService(&registry.Service{Namespace: namespace, Name: "api", ID: "1", Hostname: "h", IP: "10.0.0.1", Port: 80}); err != nil {
+			t.Fatalf("注册失败: %v", err)
+		}
+	}
+
+	s := &Server{config: cfg, registry: sr}
+	engine := gin.New()
+	engine.Use(middleware.ErrorHandler())
+	engine.GET("/services", s.ListServices)
+	engine.GET("/api/services", middleware.AuthRequired(cfg, models.PermissionViewServices), s.ListServices)
+
+	token := func(role models.Role, namespaces ...string) string {
+		t.Helper()
+		token, err := utils.GenerateToken(cfg, &models.User{
+			ID:          primitive.NewObjectID(),
+			Username:    "tester",
+			Role:        role,
+			Permissions: []models.Permission{models.PermissionViewServices},
+			Namespaces:  namespaces,
+		})
+		if err != nil {
+			t.Fatalf("生成token失败: %v", err)
+		}
+		return token
+	}
+	stagingUser := token(models.RoleUser, "staging")
+	admin := token(models.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		want   int
+	}{
+		{name: "限制为staging的用户读取prod", target: "/api/services?namespace=prod", token: stagingUser, want: http.StatusForbidden},
+		{name: "限制为staging的用户读取staging", target: "/api/services?namespace=staging", token: stagingUser, want: http.StatusOK},
+		{name: "限制为staging的用户读取默认命名空间", target: "/api/services", token: stagingUser, want: http.StatusForbidden},
+		{name: "管理员读取prod", target: "/api/services?namespace=prod", token: admin, want: http.StatusOK},
+		{name: "匿名读取受限的prod", target: "/services?namespace=prod", want: http.StatusForbidden},
+		{name: "匿名读取未受限的staging", target: "/services?namespace=staging", want: http.StatusOK},
+		{name: "控制台接口需要登录", target: "/api/services?namespace=staging", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("状态码 = %d, 期望 %d，响应: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestInstanceCallsInRestrictedNamespace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sr := registry.NewServiceRegistry(registry.WithRestrictedNamespaces([]string{"prod"}))
+	if err := sr.RegisterService(&registry.Service{Namespace: "prod", Name: "api", ID: "1", Hostname: "h", IP: "10.0.0.1", Port: 80}); err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+
+	s := &Server{registry: sr}
+	engine := gin.New()
+	engine.Use(middleware.ErrorHandler())
+	engine.PUT("/services/:name/:id/heartbeat", s.UpdateHeartbeat)
+
+	// 受限的命名空间内的实例仍可发送心跳
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/services/api/1/heartbeat?namespace=prod", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("状态码 = %d, 期望 200，响应: %s", w.Code, w.Body.String())
+	}
+}
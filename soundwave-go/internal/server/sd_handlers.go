@@ -0,0 +1,163 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/registry"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sdLabelPrefix 服务发现元标签前缀，Prometheus 会在 relabel 之后丢弃 __meta_ 开头的标签
+	sdLabelPrefix = "__meta_soundwave_"
+	// sdScrapeKey 元数据中该键为 false 时不对实例进行采集
+	sdScrapeKey = "prometheus.io/scrape"
+	// sdPathKey 元数据中指定指标路径
+	sdPathKey = "prometheus.io/path"
+	// sdSchemeKey 元数据中指定采集协议
+	sdSchemeKey = "prometheus.io/scheme"
+	// sdPortKey 元数据中指定指标端口，未指定时使用服务端口
+	sdPortKey = "prometheus.io/port"
+)
+
+// invalidLabelChars 不能出现在 Prometheus 标签名中的字符
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// TargetGroup Prometheus http_sd_config 的目标组
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// PrometheusSD 以 Prometheus HTTP 服务发现格式返回注册的服务实例
+//
+// 支持的查询参数：
+//   - namespace: 命名空间，可重复，默认返回所有未受限的命名空间的实例
+//   - service: 服务名称，可重复
+//   - status: 实例状态，可重复，默认返回 UP 和 DEGRADED 状态的实例
+//   - metadata: 元数据选择器，格式为 key=value，可重复，需全部匹配
+func (s *Server) PrometheusSD(c *gin.Context) {
+	// 该接口不需要登录，不返回受限的命名空间
+	namespaces := c.QueryArray("namespace")
+	if len(namespaces) == 0 {
+		for _, info := range s.registry.ListNamespaces() {
+			if !s.registry.NamespaceRestricted(info.Name) {
+				namespaces = append(namespaces, info.Name)
+			}
+		}
+	}
+	for _, namespace := range namespaces {
+		if s.registry.NamespaceRestricted(namespace) {
+			c.Error(registry.ErrNamespaceRestricted)
+			return
+		}
+	}
+
+	services := toSet(c.QueryArray("service"))
+
+	statuses := toSet(c.QueryArray("status"))
+	if len(statuses) == 0 {
+		statuses[string(registry.StatusUP)] = true
+		statuses[string(registry.StatusDegraded)] = true
+	}
+
+	selector := make(map[string]string)
+	for _, item := range c.QueryArray("metadata") {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的元数据选择器: " + item))
+			return
+		}
+		selector[key] = value
+	}
+
+	groups := make([]TargetGroup, 0)
+	for _, namespace := range namespaces {
+		for name, instances := range s.registry.ListAllServices(namespace) {
+			if len(services) > 0 && !services[name] {
+				continue
+			}
+			for _, instance := range instances {
+				if !statuses[string(instance.Status)] || !matchMetadata(instance.Metadata, selector) {
+					continue
+				}
+				if scrape, err := strconv.ParseBool(instance.Metadata[sdScrapeKey]); err == nil && !scrape {
+					continue
+				}
+				groups = append(groups, targetGroup(instance))
+			}
+		}
+	}
+
+	// 保证输出顺序稳定，避免 Prometheus 认为目标频繁变化
+	sort.Slice(groups, func(i, j int) bool {
+		li, lj := groups[i].Labels, groups[j].Labels
+		if li[sdLabelPrefix+"namespace"] != lj[sdLabelPrefix+"namespace"] {
+			return li[sdLabelPrefix+"namespace"] < lj[sdLabelPrefix+"namespace"]
+		}
+		if li[sdLabelPrefix+"service"] != lj[sdLabelPrefix+"service"] {
+			return li[sdLabelPrefix+"service"] < lj[sdLabelPrefix+"service"]
+		}
+		return li[sdLabelPrefix+"service_id"] < lj[sdLabelPrefix+"service_id"]
+	})
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// targetGroup 将服务实例转换为目标组
+func targetGroup(instance *registry.Service) TargetGroup {
+	target := instance.GetAddress()
+	if port := instance.Metadata[sdPortKey]; port != "" {
+		target = net.JoinHostPort(instance.IP, port)
+	}
+
+	labels := map[string]string{
+		sdLabelPrefix + "namespace":  instance.Namespace,
+		sdLabelPrefix + "service":    instance.Name,
+		sdLabelPrefix + "service_id": instance.ID,
+		sdLabelPrefix + "hostname":   instance.Hostname,
+		sdLabelPrefix + "version":    instance.Version,
+		sdLabelPrefix + "status":     string(instance.Status),
+	}
+	for key, value := range instance.Metadata {
+		labels[sdLabelPrefix+"metadata_"+invalidLabelChars.ReplaceAllString(key, "_")] = value
+	}
+	if path := instance.Metadata[sdPathKey]; path != "" {
+		labels["__metrics_path__"] = path
+	}
+	if scheme := instance.Metadata[sdSchemeKey]; scheme != "" {
+		labels["__scheme__"] = scheme
+	}
+
+	return TargetGroup{
+		Targets: []string{target},
+		Labels:  labels,
+	}
+}
+
+// matchMetadata 判断元数据是否满足全部选择条件
+func matchMetadata(metadata, selector map[string]string) bool {
+	for key, value := range selector {
+		if v, ok := metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
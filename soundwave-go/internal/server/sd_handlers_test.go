@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"soundwave-go/internal/registry"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPrometheusSD(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sr := registry.NewServiceRegistry()
+	for _, service := range []*registry.Service{
+		{Name: "web", ID: "1", Hostname: "h1", IP: "10.0.0.2", Port: 80},
+		{Name: "api", ID: "1", Hostname: "h2", IP: "fd00::1", Port: 8080},
+		{Name: "api", ID: "2", Hostname: "h3", IP: "fd00::2", Port: 8080, Metadata: map[string]string{sdPortKey: "9100"}},
+	} {
+		if err := sr.RegisterService(service); err != nil {
+			t.Fatalf("注册失败: %v", err)
+		}
+	}
+	s := &Server{registry: sr}
+
+	// 多次请求的输出顺序一致，服务名称不同但实例ID相同的实例按服务名称排序
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/sd/prometheus", nil)
+		s.PrometheusSD(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("状态码 = %d, 期望 200", w.Code)
+		}
+
+		var groups []TargetGroup
+		if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		targets := make([]string, 0, len(groups))
+		for _, group := range groups {
+			targets = append(targets, group.Targets...)
+		}
+		want := []string{"[fd00::1]:8080", "[fd00::2]:9100", "10.0.0.2:80"}
+		if len(targets) != len(want) {
+			t.Fatalf("Targets = %v, 期望 %v", targets, want)
+		}
+		for j := range want {
+			if targets[j] != want[j] {
+				t.Fatalf("第 %d 次请求 Targets = %v, 期望 %v", i+1, targets, want)
+			}
+		}
+	}
+}
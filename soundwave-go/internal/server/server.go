@@ -6,11 +6,17 @@ import (
 	"net/http"
 	"soundwave-go/internal/config"
 	"soundwave-go/internal/db"
+	"soundwave-go/internal/dnsserver"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/grpcserver"
+	"soundwave-go/internal/identity"
 	"soundwave-go/internal/logger"
+	"soundwave-go/internal/metrics"
 	"soundwave-go/internal/middleware"
 	"soundwave-go/internal/models"
 	"soundwave-go/internal/registry"
 	"soundwave-go/internal/service"
+	"soundwave-go/pkg/routing"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,6 +31,13 @@ type Server struct {
 	authService *service.AuthService
 	menuService *service.MenuService
 	userService *service.UserService
+
+	rolePolicyService *service.RolePolicyService
+	trafficService    *service.TrafficService
+	eventService      *service.EventService
+	historyService    *service.HistoryService
+	dnsServer         *dnsserver.Server
+	grpcServer        *grpcserver.Server
 }
 
 func NewServer(cfg *config.Config) *Server {
@@ -32,12 +45,41 @@ func NewServer(cfg *config.Config) *Server {
 		cfg = config.DefaultConfig()
 	}
 
-	r := gin.Default()
-	registry := registry.NewServiceRegistry()
+	r := gin.New()
+	var balancer registry.LoadBalancer = registry.NewP2CBalancer()
+	if cfg.Registry.Balancer == "random" {
+		balancer = registry.NewRandomBalancer()
+	}
+	registryOptions := []registry.RegistryOption{
+		registry.WithLoadBalancer(balancer),
+		registry.WithSpilloverThreshold(cfg.Registry.SpilloverThreshold),
+		registry.WithRestrictedNamespaces(cfg.Registry.RestrictedNamespaces),
+	}
+	if cfg.Registry.SelfPreservation {
+		registryOptions = append(registryOptions, registry.WithSelfPreservation(cfg.Registry.RenewalThreshold, cfg.Registry.HeartbeatInterval))
+	}
+	if cfg.Outlier.Enabled {
+		registryOptions = append(registryOptions, registry.WithOutlierDetection(registry.OutlierPolicy{
+			ConsecutiveFailures: cfg.Outlier.ConsecutiveFailures,
+			ErrorRate:           cfg.Outlier.ErrorRate,
+			MinRequests:         cfg.Outlier.MinRequests,
+			Interval:            cfg.Outlier.Interval,
+			BaseEjectionTime:    cfg.Outlier.BaseEjectionTime,
+			MaxEjectionTime:     cfg.Outlier.MaxEjectionTime,
+			MaxEjectionPercent:  cfg.Outlier.MaxEjectionPercent,
+		}))
+	}
+	if cfg.History.Enabled {
+		registryOptions = append(registryOptions, registry.WithHistory(cfg.History.Resolution, cfg.History.MemoryRetention, cfg.Registry.HeartbeatInterval))
+	}
+	registry := registry.NewServiceRegistry(registryOptions...)
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// 添加CORS中间件
-	r.Use(middleware.CORS())
+	// 请求ID、统一错误响应和CORS中间件
+	r.Use(gin.Logger(), middleware.RequestID(), middleware.Metrics(), middleware.CORS(), middleware.ErrorHandler(), middleware.Recovery())
+	r.NoRoute(func(c *gin.Context) {
+		c.Error(apperrors.ErrNotFound)
+	})
 
 	// 初始化 MongoDB 连接
 	mongodb, err := db.NewMongoDB(cfg.MongoDB.URI, cfg.MongoDB.Database)
@@ -51,50 +93,125 @@ func NewServer(cfg *config.Config) *Server {
 		logger.ErrorLogger.Fatalf("加载初始化数据失败: %v", err)
 	}
 
+	// 执行数据库迁移
+	if err := db.Migrate(ctx, mongodb.Database(), cfg); err != nil {
+		logger.ErrorLogger.Fatalf("数据库迁移失败: %v", err)
+	}
+
 	// 初始化数据库
-	if err := db.InitializeData(mongodb.Database(), initData); err != nil {
+	if err := db.InitializeData(mongodb.Database(), cfg, initData); err != nil {
 		logger.ErrorLogger.Fatalf("初始化数据失败: %v", err)
 	}
 
+	rolePolicyService := service.NewRolePolicyService(mongodb.Database(), cfg)
+	providers := identity.NewRegistry(ctx, cfg.SSO)
+
 	server := &Server{
 		engine:      r,
 		registry:    registry,
 		config:      cfg,
 		ctx:         ctx,
 		cancel:      cancel,
-		authService: service.NewAuthService(mongodb.Database(), cfg),
+		authService: service.NewAuthService(mongodb.Database(), cfg, rolePolicyService, providers),
 		menuService: service.NewMenuService(mongodb.Collection("menus")),
-		userService: service.NewUserService(mongodb.Database()),
+		userService: service.NewUserService(mongodb.Database(), cfg),
+
+		rolePolicyService: rolePolicyService,
+		trafficService:    service.NewTrafficService(mongodb.Database(), cfg, registry),
+		eventService:      service.NewEventService(mongodb.Database(), cfg, registry),
+		historyService:    service.NewHistoryService(mongodb.Database(), cfg, registry),
+	}
+
+	// 加载路由规则，并定期同步其他节点的修改
+	if err := server.trafficService.Load(ctx); err != nil {
+		logger.ErrorLogger.Printf("加载路由规则失败: %v", err)
+	}
+	server.trafficService.StartSync(ctx, trafficSyncInterval)
+
+	// 记录实例生命周期事件
+	if cfg.Events.Enabled {
+		server.eventService.Start(ctx)
+	}
+
+	// 采样服务的历史数据，并定期降采样写入 MongoDB
+	if cfg.History.Enabled {
+		registry.StartHistory(ctx)
+		server.historyService.Start(ctx)
+	}
+
+	if err := metrics.RegisterInstanceCounter(registry); err != nil {
+		logger.ErrorLogger.Printf("注册实例数量指标失败: %v", err)
 	}
 
 	// 注册路由
 	server.registerRoutes()
 	// 启动健康检查
 	registry.StartHealthCheck(ctx, cfg.Registry.HeartbeatInterval)
+	// 启动实例主动探测
+	if cfg.Outlier.Enabled && cfg.Outlier.ProbeInterval > 0 {
+		registry.StartProbe(ctx, cfg.Outlier.ProbeInterval, cfg.Outlier.ProbeTimeout)
+	}
+
+	// 启动内置 DNS 服务
+	if cfg.DNS.Enabled {
+		server.dnsServer = dnsserver.NewServer(cfg.DNS, registry)
+		if err := server.dnsServer.Start(); err != nil {
+			logger.ErrorLogger.Fatalf("启动DNS服务失败: %v", err)
+		}
+	}
+
+	// 启动 gRPC 服务
+	if cfg.GRPC.Enabled {
+		server.grpcServer = grpcserver.NewServer(cfg.GRPC, registry)
+		if err := server.grpcServer.Start(); err != nil {
+			logger.ErrorLogger.Fatalf("启动gRPC服务失败: %v", err)
+		}
+	}
 
 	logger.InfoLogger.Printf("服务器初始化完成，配置：%+v", cfg)
 	return server
 }
 
 func (s *Server) registerRoutes() {
+	// Prometheus 指标接口
+	if s.config.Metrics.Enabled {
+		s.engine.GET(s.config.Metrics.Path, gin.WrapH(metrics.Handler()))
+	}
+
 	// 服务注册接口
 	s.engine.POST("/services", s.RegisterService)
 	// 服务发现接口
 	s.engine.GET("/services/:name", s.DiscoverService)
 	// 获取所有服务列表
 	s.engine.GET("/services", s.ListServices)
+	// 增量同步接口
+	s.engine.GET("/services/delta", s.GetServicesDelta)
 	// 服务心跳接口
 	s.engine.PUT("/services/:name/:id/heartbeat", s.UpdateHeartbeat)
+	// 实例详情接口
+	s.engine.GET("/services/:name/:id", s.GetInstance)
+	// 实例更新自身元数据、权重和版本的接口，需携带注册时颁发的实例令牌
+	s.engine.PATCH("/services/:name/:id", s.UpdateInstance)
+	// 调用结果上报接口，用于异常实例检测
+	s.engine.POST("/services/:name/:id/report", s.ReportOutcome)
 	// 服务统计信息
 	s.engine.GET("/services/:name/stats", s.GetServiceStats)
 	// 负载均衡获取服务
 	s.engine.GET("/services/:name/instance", s.GetServiceInstance)
+	// 注册中心状态，包括自我保护模式
+	s.engine.GET("/registry/status", s.GetRegistryStatus)
+	// Prometheus HTTP 服务发现接口
+	s.engine.GET("/sd/prometheus", s.PrometheusSD)
 
 	// 认证相关路由
 	auth := s.engine.Group("/auth")
 	{
 		auth.POST("/register", s.HandleRegister)
 		auth.POST("/login", s.HandleLogin)
+		auth.POST("/login/mfa", s.HandleLoginMFA)
+		auth.GET("/providers", s.ListAuthProviders)
+		auth.GET("/oidc/:provider/login", s.HandleOIDCLogin)
+		auth.GET("/oidc/:provider/callback", s.HandleOIDCCallback)
 	}
 
 	// 需要认证的路由
@@ -110,19 +227,55 @@ func (s *Server) registerRoutes() {
 		services.Use(middleware.AuthRequired(s.config, models.PermissionViewServices))
 		{
 			services.GET("", s.ListServices)
+			services.GET("/:name/events", s.ListServiceEvents)
+			services.GET("/:name/availability", s.GetServiceAvailability)
+			services.GET("/:name/sla", s.GetServiceSLA)
+			services.GET("/:name/history", s.GetServiceHistory)
+		}
+
+		namespaces := api.Group("/namespaces")
+		namespaces.Use(middleware.AuthRequired(s.config, models.PermissionViewServices))
+		{
+			namespaces.GET("", s.ListNamespaces)
+		}
+
+		servicesAdmin := api.Group("/services")
+		servicesAdmin.Use(middleware.AuthRequired(s.config, models.PermissionManageSystem))
+		{
+			servicesAdmin.PATCH("/:name/:id", s.AdminUpdateInstance)
+		}
+
+		traffic := api.Group("/traffic")
+		traffic.Use(middleware.AuthRequired(s.config, models.PermissionViewServices))
+		{
+			traffic.GET("", s.ListTrafficPolicies)
+			traffic.GET("/:name", s.GetTrafficPolicy)
+		}
+
+		trafficAdmin := api.Group("/traffic")
+		trafficAdmin.Use(middleware.AuthRequired(s.config, models.PermissionManageSystem))
+		{
+			trafficAdmin.PUT("/:name", s.UpdateTrafficPolicy)
+			trafficAdmin.DELETE("/:name", s.DeleteTrafficPolicy)
 		}
 
 		stats := api.Group("/stats")
 		stats.Use(middleware.AuthRequired(s.config, models.PermissionViewStats))
 		{
-			stats.GET("", s.GetServiceStats)
+			stats.GET("", s.ListServiceStats)
+			stats.GET("/sla", s.GetSLASummary)
 		}
 
 		// 用户相关路由
 		user := api.Group("/user")
-		user.Use(middleware.AuthRequired(s.config, models.PermissionViewServices))
+		user.Use(middleware.AllowPasswordChange(), middleware.AllowMFASetup(), middleware.AuthRequired(s.config, models.PermissionViewServices))
 		{
 			user.POST("/change-password", s.HandleChangePassword)
+			user.GET("/mfa", s.GetMFAStatus)
+			user.POST("/mfa/setup", s.SetupMFA)
+			user.POST("/mfa/enable", s.EnableMFA)
+			user.POST("/mfa/disable", s.DisableMFA)
+			user.POST("/mfa/recovery-codes", s.RegenerateRecoveryCodes)
 		}
 
 		// 用户管理路由
@@ -135,6 +288,24 @@ func (s *Server) registerRoutes() {
 			users.PUT("/:id", s.UpdateUser)
 			users.DELETE("/:id", s.DeleteUser)
 			users.POST("/:id/reset-password", s.ResetUserPassword)
+			users.POST("/:id/unlock", s.UnlockUser)
+			users.POST("/:id/reset-mfa", s.ResetUserMFA)
+			users.PUT("/:id/namespaces", s.SetUserNamespaces)
+		}
+
+		// 角色安全策略路由
+		roles := api.Group("/roles")
+		roles.Use(middleware.AuthRequired(s.config, models.PermissionManageUsers))
+		{
+			roles.GET("/policies", s.ListRolePolicies)
+			roles.PUT("/:role/policy", s.UpdateRolePolicy)
+		}
+
+		// 登录审计路由
+		loginAttempts := api.Group("/login-attempts")
+		loginAttempts.Use(middleware.AuthRequired(s.config, models.PermissionManageUsers))
+		{
+			loginAttempts.GET("", s.ListLoginAttempts)
 		}
 	}
 
@@ -153,6 +324,12 @@ func (s *Server) Shutdown() {
 	if s.cancel != nil {
 		s.cancel()
 	}
+	if s.dnsServer != nil {
+		s.dnsServer.Shutdown()
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Shutdown()
+	}
 	logger.InfoLogger.Println("服务器已关闭")
 }
 
@@ -160,21 +337,41 @@ func (s *Server) Shutdown() {
 func (s *Server) GetServiceInstance(c *gin.Context) {
 	serviceName := c.Param("name")
 	if serviceName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "服务名称不能为空",
-		})
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("服务名称不能为空"))
+		return
+	}
+
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	query, err := instanceQuery(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	service, err := s.registry.GetServiceWithLoadBalancing(serviceName)
+	// 调用方位置用于就近选择，查询参数优先于请求头
+	caller := registry.Locality{
+		Region: c.DefaultQuery("caller_region", c.GetHeader("X-Soundwave-Region")),
+		Zone:   c.DefaultQuery("caller_zone", c.GetHeader("X-Soundwave-Zone")),
+	}
+
+	// 路由键和请求头用于匹配路由规则
+	route := routing.RouteContext{
+		Key:     c.DefaultQuery("route_key", c.GetHeader("X-Soundwave-Route-Key")),
+		Headers: c.Request.Header,
+	}
+
+	service, decision, err := s.registry.SelectInstance(namespace, serviceName, query, caller, route)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"service": service,
+		"service":  service,
+		"decision": decision,
 	})
 }
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slaParams 解析 SLA 报告的公共参数：window 为 day、week 或 month，min_healthy 为服务可用要求的最少健康实例数，
+// format 为 json 或 csv
+func slaParams(c *gin.Context) (window string, minHealthy int, format string, ok bool) {
+	window = c.DefaultQuery("window", "day")
+	minHealthy = 1
+	if raw := c.Query("min_healthy"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的min_healthy参数"))
+			return "", 0, "", false
+		}
+		minHealthy = value
+	}
+	format = c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的format参数，可选 json、csv"))
+		return "", 0, "", false
+	}
+	return window, minHealthy, format, true
+}
+
+// GetServiceSLA 获取服务在时间窗口内的可用性报告，包括故障区间和各实例的可用性，可导出为 CSV
+func (s *Server) GetServiceSLA(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+	window, minHealthy, format, ok := slaParams(c)
+	if !ok {
+		return
+	}
+
+	report, err := s.eventService.SLAReport(c.Request.Context(), namespace, c.Param("name"), window, time.Now(), minHealthy)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if format == "csv" {
+		writeCSV(c, fmt.Sprintf("sla-%s-%s-%s.csv", namespace, report.Service, report.Window), serviceSLARows(report))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// GetSLASummary 获取命名空间内所有服务在时间窗口内的可用性概要，可导出为 CSV
+func (s *Server) GetSLASummary(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+	window, minHealthy, format, ok := slaParams(c)
+	if !ok {
+		return
+	}
+
+	summary, err := s.eventService.SLASummary(c.Request.Context(), namespace, window, minHealthy)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if format == "csv" {
+		rows := [][]string{{"service", "availability", "downtime_seconds", "incidents", "instances"}}
+		for _, item := range summary {
+			rows = append(rows, []string{
+				item.Service,
+				formatRatio(item.Availability),
+				formatSeconds(item.Downtime),
+				strconv.Itoa(item.Incidents),
+				strconv.Itoa(item.Instances),
+			})
+		}
+		writeCSV(c, fmt.Sprintf("sla-%s-%s.csv", namespace, window), rows)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":   namespace,
+		"window":      window,
+		"min_healthy": minHealthy,
+		"services":    summary,
+	})
+}
+
+// serviceSLARows 将可用性报告转换为 CSV 行，依次为概要、故障区间和实例可用性三部分，各部分以空行分隔
+func serviceSLARows(report *models.SLAReport) [][]string {
+	rows := [][]string{
+		{"namespace", "service", "window", "since", "until", "min_healthy", "availability", "uptime_seconds", "downtime_seconds"},
+		{
+			report.Namespace,
+			report.Service,
+			report.Window,
+			report.Since.Format(time.RFC3339),
+			report.Until.Format(time.RFC3339),
+			strconv.Itoa(report.MinHealthy),
+			formatRatio(report.Availability),
+			formatSeconds(report.Uptime),
+			formatSeconds(report.Downtime),
+		},
+		{},
+		{"incident_start", "incident_end", "duration_seconds", "lowest_healthy"},
+	}
+	for _, incident := range report.Incidents {
+		end := ""
+		if incident.End != nil {
+			end = incident.End.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			incident.Start.Format(time.RFC3339),
+			end,
+			formatSeconds(incident.Duration),
+			strconv.Itoa(incident.LowestHealthy),
+		})
+	}
+
+	rows = append(rows, []string{}, []string{"instance_id", "hostname", "address", "availability", "uptime_seconds", "downtime_seconds", "flaps"})
+	for _, instance := range report.Instances {
+		rows = append(rows, []string{
+			instance.InstanceID,
+			instance.Hostname,
+			instance.Address,
+			formatRatio(instance.Availability),
+			formatSeconds(instance.Uptime),
+			formatSeconds(instance.Downtime),
+			strconv.Itoa(instance.Flaps),
+		})
+	}
+	return rows
+}
+
+func writeCSV(c *gin.Context, filename string, rows [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	if err := w.WriteAll(rows); err != nil {
+		// 响应头已发送，只记录错误
+		logger.ErrorLogger.Printf("写入CSV失败: %v", err)
+	}
+}
+
+func formatRatio(value float64) string {
+	return strconv.FormatFloat(value, 'f', 6, 64)
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 0, 64)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/identity"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie = "soundwave_oidc_state"
+	oidcNonceCookie = "soundwave_oidc_nonce"
+	// oidcCookieMaxAge OIDC 登录流程的最长时间（秒）
+	oidcCookieMaxAge = 600
+)
+
+// ListAuthProviders 获取登录页可用的外部身份源
+func (s *Server) ListAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": s.authService.ListProviders()})
+}
+
+// HandleOIDCLogin 跳转到 OIDC 身份源授权页面
+func (s *Server) HandleOIDCLogin(c *gin.Context) {
+	provider := c.Param("provider")
+
+	state, err := identity.NewState()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	nonce, err := identity.NewState()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	authURL, err := s.authService.OIDCAuthURL(provider, state, nonce)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	secure := c.Request.TLS != nil
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, oidcCookieMaxAge, "/auth/oidc", "", secure, true)
+	c.SetCookie(oidcNonceCookie, nonce, oidcCookieMaxAge, "/auth/oidc", "", secure, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// HandleOIDCCallback 处理 OIDC 回调，登录结果通过 URL fragment 返回给前端
+func (s *Server) HandleOIDCCallback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	// 回调后立即清除一次性 cookie
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", false, true)
+	c.SetCookie(oidcNonceCookie, "", -1, "/auth/oidc", "", false, true)
+
+	if errMsg := c.Query("error"); errMsg != "" {
+		s.redirectToFrontend(c, url.Values{"error": {"身份源拒绝授权: " + errMsg}})
+		return
+	}
+
+	state, err := c.Cookie(oidcStateCookie)
+	if err != nil || state == "" || state != c.Query("state") {
+		s.redirectToFrontend(c, url.Values{"error": {"登录已过期，请重试"}})
+		return
+	}
+	nonce, err := c.Cookie(oidcNonceCookie)
+	if err != nil || nonce == "" {
+		s.redirectToFrontend(c, url.Values{"error": {"登录已过期，请重试"}})
+		return
+	}
+
+	user, err := s.authService.CompleteOIDCLogin(c.Request.Context(), provider, c.Query("code"), nonce, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		s.redirectToFrontend(c, url.Values{"error": {externalLoginError(err)}})
+		return
+	}
+
+	if user.MFAEnabled {
+		mfaToken, err := s.authService.GenerateMFAToken(user)
+		if err != nil {
+			s.redirectToFrontend(c, url.Values{"error": {"生成token失败"}})
+			return
+		}
+		s.redirectToFrontend(c, url.Values{"mfa_token": {mfaToken}})
+		return
+	}
+
+	token, mfaSetupRequired, err := s.authService.GenerateToken(user)
+	if err != nil {
+		s.redirectToFrontend(c, url.Values{"error": {"生成token失败"}})
+		return
+	}
+
+	s.redirectToFrontend(c, url.Values{
+		"token":              {token},
+		"mfa_setup_required": {strconv.FormatBool(mfaSetupRequired)},
+		"user":               {userJSON(user)},
+	})
+}
+
+// loginWithProvider 使用 LDAP 等外部身份源的用户名密码登录
+func (s *Server) loginWithProvider(c *gin.Context, req LoginRequest) (*models.User, bool) {
+	user, err := s.authService.LoginWithProvider(c.Request.Context(), req.Provider, req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+	return user, true
+}
+
+// redirectToFrontend 跳转回前端登录页，参数放在 fragment 中避免 token 出现在服务端日志
+func (s *Server) redirectToFrontend(c *gin.Context, values url.Values) {
+	c.Redirect(http.StatusFound, s.config.SSO.FrontendURL+"#"+values.Encode())
+}
+
+// externalLoginError 返回可以展示给用户的外部登录错误信息
+func externalLoginError(err error) string {
+	serviceErr := apperrors.From(err)
+	if serviceErr.Kind == apperrors.KindInternal {
+		logger.ErrorLogger.Printf("外部身份源登录失败: %v", err)
+		return service.ErrProviderAuthFailed.Message
+	}
+	return serviceErr.Message
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/identity"
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeRedirectProvider 记录授权码和 nonce 的 OIDC 身份源，Exchange 总是失败
+type fakeRedirectProvider struct {
+	identity.RoleMapper
+	exchanges []string
+}
+
+func (p *fakeRedirectProvider) Name() string        { return "mock" }
+func (p *fakeRedirectProvider) DisplayName() string { return "Mock" }
+func (p *fakeRedirectProvider) Type() string        { return "oidc" }
+
+func (p *fakeRedirectProvider) AuthCodeURL(state, nonce string) string {
+	return "https://idp.example.com/authorize?" + url.Values{"state": {state}, "nonce": {nonce}}.Encode()
+}
+
+func (p *fakeRedirectProvider) Exchange(ctx context.Context, code, nonce string) (*identity.Identity, error) {
+	p.exchanges = append(p.exchanges, code+"/"+nonce)
+	return nil, errors.New("exchange disabled")
+}
+
+// newSSOTestServer 创建只包含 OIDC 登录所需依赖的服务器，MongoDB 不可达，登录记录写入会快速失败
+func newSSOTestServer(t *testing.T) (*Server, *fakeRedirectProvider) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	client, err := mongo.Connect(context.Background(),
+		options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50&connectTimeoutMS=50"))
+	if err != nil {
+		t.Fatalf("创建MongoDB客户端失败: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	cfg := config.DefaultConfig()
+	provider := &fakeRedirectProvider{RoleMapper: identity.NewRoleMapper(nil, models.RoleUser)}
+	providers := identity.NewRegistry(context.Background(), config.SSOConfig{})
+	providers.Add(provider)
+
+	s := &Server{
+		config:      cfg,
+		authService: service.NewAuthService(client.Database("soundwave_test"), cfg, nil, providers),
+	}
+	return s, provider
+}
+
+// serveOIDC 调用 OIDC 处理函数，返回响应
+func serveOIDC(handler gin.HandlerFunc, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		c.Request.AddCookie(cookie)
+	}
+	c.Params = gin.Params{{Key: "provider", Value: "mock"}}
+	handler(c)
+	return w
+}
+
+// fragment 解析跳转回前端的地址中的 fragment 参数
+func fragment(t *testing.T, w *httptest.ResponseRecorder) url.Values {
+	t.Helper()
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("解析跳转地址失败: %v", err)
+	}
+	values, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		t.Fatalf("解析fragment失败: %v", err)
+	}
+	return values
+}
+
+func TestOIDCLoginSetsStateAndNonce(t *testing.T) {
+	s, _ := newSSOTestServer(t)
+
+	w := serveOIDC(s.HandleOIDCLogin, "/auth/oidc/mock/login")
+	if w.Code != http.StatusFound {
+		t.Fatalf("状态码 = %d, 期望 %d", w.Code, http.StatusFound)
+	}
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	state, nonce := cookies[oidcStateCookie], cookies[oidcNonceCookie]
+	if state == nil || state.Value == "" || nonce == nil || nonce.Value == "" {
+		t.Fatalf("缺少state或nonce cookie: %v", w.Result().Cookies())
+	}
+	if !state.HttpOnly || state.Path != "/auth/oidc" {
+		t.Errorf("state cookie 属性错误: %+v", state)
+	}
+	if state.Value == nonce.Value {
+		t.Error("state 和 nonce 不应相同")
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("解析跳转地址失败: %v", err)
+	}
+	if location.Query().Get("state") != state.Value || location.Query().Get("nonce") != nonce.Value {
+		t.Errorf("授权地址 %s 与 cookie 中的 state/nonce 不一致", location)
+	}
+}
+
+func TestOIDCCallbackValidatesState(t *testing.T) {
+	stateCookie := &http.Cookie{Name: oidcStateCookie, Value: "state-value"}
+	nonceCookie := &http.Cookie{Name: oidcNonceCookie, Value: "nonce-value"}
+
+	tests := []struct {
+		name         string
+		query        string
+		cookies      []*http.Cookie
+		wantError    string
+		wantExchange string
+	}{
+		{
+			name:      "缺少state cookie",
+			query:     "state=state-value&code=abc",
+			cookies:   []*http.Cookie{nonceCookie},
+			wantError: "登录已过期",
+		},
+		{
+			name:      "state不匹配",
+			query:     "state=forged&code=abc",
+			cookies:   []*http.Cookie{stateCookie, nonceCookie},
+			wantError: "登录已过期",
+		},
+		{
+			name:      "缺少state参数",
+			query:     "code=abc",
+			cookies:   []*http.Cookie{stateCookie, nonceCookie},
+			wantError: "登录已过期",
+		},
+		{
+			name:      "缺少nonce cookie",
+			query:     "state=state-value&code=abc",
+			cookies:   []*http.Cookie{stateCookie},
+			wantError: "登录已过期",
+		},
+		{
+			name:      "身份源拒绝授权",
+			query:     "error=access_denied",
+			cookies:   []*http.Cookie{stateCookie, nonceCookie},
+			wantError: "身份源拒绝授权",
+		},
+		{
+			name:         "state匹配时使用cookie中的nonce换取token",
+			query:        "state=state-value&code=abc",
+			cookies:      []*http.Cookie{stateCookie, nonceCookie},
+			wantError:    service.ErrProviderAuthFailed.Message,
+			wantExchange: "abc/nonce-value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, provider := newSSOTestServer(t)
+
+			w := serveOIDC(s.HandleOIDCCallback, "/auth/oidc/mock/callback?"+tt.query, tt.cookies...)
+			if w.Code != http.StatusFound {
+				t.Fatalf("状态码 = %d, 期望 %d", w.Code, http.StatusFound)
+			}
+			if !strings.HasPrefix(w.Header().Get("Location"), s.config.SSO.FrontendURL+"#") {
+				t.Errorf("跳转地址 = %s, 期望跳转回前端", w.Header().Get("Location"))
+			}
+
+			values := fragment(t, w)
+			if !strings.Contains(values.Get("error"), tt.wantError) {
+				t.Errorf("错误 = %q, 期望包含 %q", values.Get("error"), tt.wantError)
+			}
+			if values.Get("token") != "" {
+				t.Error("校验失败时不应返回token")
+			}
+			if got := strings.Join(provider.exchanges, ","); got != tt.wantExchange {
+				t.Errorf("Exchange 调用 = %q, 期望 %q", got, tt.wantExchange)
+			}
+
+			// 回调后一次性 cookie 被清除
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.MaxAge >= 0 {
+					t.Errorf("cookie %s 未被清除", cookie.Name)
+				}
+			}
+		})
+	}
+}
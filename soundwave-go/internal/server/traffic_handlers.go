@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/utils"
+	"soundwave-go/pkg/routing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trafficSyncInterval 从 MongoDB 重新加载路由规则的间隔
+const trafficSyncInterval = 30 * time.Second
+
+// UpdateTrafficPolicyRequest 更新路由规则请求，rules 为空时删除服务的路由规则
+type UpdateTrafficPolicyRequest struct {
+	Rules []routing.TrafficRule `json:"rules"`
+}
+
+// ListTrafficPolicies 获取命名空间内所有服务的路由规则
+func (s *Server) ListTrafficPolicies(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	policies, err := s.trafficService.ListPolicies(c.Request.Context(), namespace)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": namespace,
+		"policies":  policies,
+	})
+}
+
+// GetTrafficPolicy 获取服务的路由规则
+func (s *Server) GetTrafficPolicy(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	policy, err := s.trafficService.GetPolicy(c.Request.Context(), namespace, c.Param("name"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policy": policy,
+	})
+}
+
+// UpdateTrafficPolicy 更新服务的路由规则，保存后立即生效
+func (s *Server) UpdateTrafficPolicy(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateTrafficPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	claims := c.MustGet("user").(*utils.Claims)
+	policy, err := s.trafficService.SetPolicy(c.Request.Context(), namespace, c.Param("name"), req.Rules, claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "路由规则已更新",
+		"policy":  policy,
+	})
+}
+
+// DeleteTrafficPolicy 删除服务的路由规则
+func (s *Server) DeleteTrafficPolicy(c *gin.Context) {
+	namespace, ok := s.namespaceQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := s.trafficService.DeletePolicy(c.Request.Context(), namespace, c.Param("name")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "路由规则已删除",
+	})
+}
@@ -1,12 +1,50 @@
 package server
 
+import "soundwave-go/internal/registry"
+
+// ReportOutcomeRequest 调用方上报的实例调用结果，一次可上报多次调用的汇总。
+// 调用方需是同一命名空间内已注册的实例，并通过 X-Instance-Token 请求头携带自身的实例令牌
+type ReportOutcomeRequest struct {
+	CallerService string `json:"caller_service" binding:"required"`
+	CallerID      string `json:"caller_id" binding:"required"`
+	Successes     int    `json:"successes" binding:"gte=0"`
+	Failures      int    `json:"failures" binding:"gte=0"`
+}
+
+// HeartbeatRequest 心跳请求体，所有字段均可省略
+type HeartbeatRequest struct {
+	// Status 实例自身判断的状态：UP、DEGRADED、STARTING，为空时保持当前状态
+	Status string `json:"status"`
+	// Checks 各依赖的健康检查结果，键为依赖名称，如 db、cache
+	Checks map[string]registry.DependencyHealth `json:"checks"`
+	// InFlight 和 LatencyMs 为实例当前的负载，不上报负载时省略
+	InFlight  *int     `json:"in_flight" binding:"omitempty,gte=0"`
+	LatencyMs *float64 `json:"latency_ms" binding:"omitempty,gte=0"`
+}
+
+// UpdateInstanceRequest 部分更新实例的请求，未指定的字段保持不变
+type UpdateInstanceRequest struct {
+	// Metadata 需要修改的元数据，值为 null 时删除该键
+	Metadata map[string]*string `json:"metadata"`
+	Weight   *int               `json:"weight" binding:"omitempty,gte=0"`
+	Version  *string            `json:"version"`
+	// Revision 读取到的实例版本号，不为 0 且实例已被修改时返回 409
+	Revision uint64 `json:"revision"`
+}
+
 // ServiceRegisterRequest 服务注册请求结构
 type ServiceRegisterRequest struct {
-	Name     string            `json:"name" binding:"required"`
-	ID       string            `json:"id" binding:"required"`
-	Hostname string            `json:"hostname" binding:"required"`
-	IP       string            `json:"ip" binding:"required,ip"`
-	Port     int               `json:"port" binding:"required,gt=0,lte=65535"`
-	Version  string            `json:"version"`
-	Metadata map[string]string `json:"metadata"`
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name" binding:"required"`
+	ID        string            `json:"id" binding:"required"`
+	Hostname  string            `json:"hostname" binding:"required"`
+	IP        string            `json:"ip" binding:"required,ip"`
+	Port      int               `json:"port" binding:"required,gt=0,lte=65535"`
+	Version   string            `json:"version"`
+	Weight    int               `json:"weight" binding:"gte=0"`
+	Status    string            `json:"status"` // UP、DEGRADED、STARTING，为空时视为 UP
+	Metadata  map[string]string `json:"metadata"`
+	Tags      []string          `json:"tags"`
+	Region    string            `json:"region"`
+	Zone      string            `json:"zone"`
 }
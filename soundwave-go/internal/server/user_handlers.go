@@ -2,17 +2,41 @@ package server
 
 import (
 	"net/http"
+	apperrors "soundwave-go/internal/errors"
 	"soundwave-go/internal/models"
+	"soundwave-go/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// protectedUserFields 不允许通过用户更新接口直接修改的字段
+var protectedUserFields = []string{
+	"password",
+	"password_history",
+	"password_changed_at",
+	"must_change_password",
+	"failed_attempts",
+	"locked_until",
+	"last_login_at",
+	"mfa_enabled",
+	"mfa_secret",
+	"mfa_pending_secret",
+	"mfa_last_step",
+	"mfa_recovery_codes",
+	"mfa_enabled_at",
+	"provider",
+	"external_id",
+	"namespaces",
+}
+
 // ListUsers 获取用户列表
 func (s *Server) ListUsers(c *gin.Context) {
 	users, err := s.userService.ListUsers(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -22,7 +46,6 @@ func (s *Server) ListUsers(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
 		"users":   users,
 		"message": "获取成功",
 	})
@@ -32,45 +55,32 @@ func (s *Server) ListUsers(c *gin.Context) {
 func (s *Server) CreateUser(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的请求数据",
-		})
+		c.Error(apperrors.InvalidRequest(err))
 		return
 	}
 
 	// 验证用户输入
 	if err := s.userService.ValidateUserInput(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	// 检查是否已存在同名用户
 	existingUser, _ := s.userService.GetUserByUsername(c.Request.Context(), user.Username)
 	if existingUser != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "用户名已存在",
-		})
+		c.Error(service.ErrUsernameExists)
 		return
 	}
 
 	// 创建用户
 	if err := s.userService.CreateUser(c.Request.Context(), &user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	// 清除敏感信息
 	user.Password = ""
 	c.JSON(http.StatusCreated, gin.H{
-		"code":    0,
 		"data":    user,
 		"message": "用户创建成功",
 	})
@@ -81,44 +91,38 @@ func (s *Server) UpdateUser(c *gin.Context) {
 	id := c.Param("id")
 	var updates map[string]interface{}
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的请求数据",
-		})
+		c.Error(apperrors.InvalidRequest(err))
 		return
 	}
 
-	// 不允许直接更新密码
-	if _, exists := updates["password"]; exists {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "不能通过此接口更新密码",
-		})
-		return
+	// 不允许直接更新密码及账户安全相关字段
+	for _, field := range protectedUserFields {
+		if _, exists := updates[field]; exists {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("不能通过此接口更新字段: " + field))
+			return
+		}
 	}
 
 	// 检查是否更新用户名
 	if username, exists := updates["username"]; exists {
-		existingUser, _ := s.userService.GetUserByUsername(c.Request.Context(), username.(string))
+		name, ok := username.(string)
+		if !ok {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("用户名必须为字符串"))
+			return
+		}
+		existingUser, _ := s.userService.GetUserByUsername(c.Request.Context(), name)
 		if existingUser != nil && existingUser.ID.Hex() != id {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"code":    400,
-				"message": "用户名已存在",
-			})
+			c.Error(service.ErrUsernameExists)
 			return
 		}
 	}
 
 	if err := s.userService.UpdateUser(c.Request.Context(), id, bson.M(updates)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
 		"message": "用户信息更新成功",
 	})
 }
@@ -127,15 +131,11 @@ func (s *Server) UpdateUser(c *gin.Context) {
 func (s *Server) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
 	if err := s.userService.DeleteUser(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
 		"message": "用户删除成功",
 	})
 }
@@ -145,17 +145,13 @@ func (s *Server) GetUser(c *gin.Context) {
 	id := c.Param("id")
 	user, err := s.userService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "用户不存在",
-		})
+		c.Error(err)
 		return
 	}
 
 	// 清除敏感信息
 	user.Password = ""
 	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
 		"data":    user,
 		"message": "获取成功",
 	})
@@ -165,27 +161,147 @@ func (s *Server) GetUser(c *gin.Context) {
 func (s *Server) ResetUserPassword(c *gin.Context) {
 	id := c.Param("id")
 	var req struct {
-		NewPassword string `json:"new_password" binding:"required,min=6,max=32"`
+		NewPassword string `json:"new_password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的密码格式",
-		})
+		c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的密码格式"))
 		return
 	}
 
 	if err := s.userService.UpdateUserPassword(c.Request.Context(), id, req.NewPassword); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
 		"message": "密码重置成功",
 	})
 }
+
+// UnlockUser 解除用户账户锁定
+func (s *Server) UnlockUser(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.userService.UnlockUser(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户已解锁",
+	})
+}
+
+// SetUserNamespaces 设置用户可访问的服务命名空间
+func (s *Server) SetUserNamespaces(c *gin.Context) {
+	id := c.Param("id")
+	var req struct {
+		Namespaces []string `json:"namespaces"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	if err := s.userService.SetNamespaces(c.Request.Context(), id, req.Namespaces); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "命名空间权限更新成功，用户重新登录后生效",
+	})
+}
+
+// ListLoginAttempts 查询登录记录
+func (s *Server) ListLoginAttempts(c *gin.Context) {
+	filter := service.LoginAttemptFilter{
+		Username: c.Query("username"),
+	}
+
+	if success := c.Query("success"); success != "" {
+		value, err := strconv.ParseBool(success)
+		if err != nil {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的success参数"))
+			return
+		}
+		filter.Success = &value
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的since参数，需为RFC3339格式"))
+			return
+		}
+		filter.Since = t
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			c.Error(apperrors.ErrInvalidRequest.WithMessage("无效的limit参数"))
+			return
+		}
+		filter.Limit = value
+	}
+
+	attempts, err := s.authService.ListLoginAttempts(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attempts": attempts,
+		"message":  "获取成功",
+	})
+}
+
+// ResetUserMFA 重置用户MFA
+func (s *Server) ResetUserMFA(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.userService.ResetMFA(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "MFA重置成功",
+	})
+}
+
+// ListRolePolicies 获取角色安全策略
+func (s *Server) ListRolePolicies(c *gin.Context) {
+	policies, err := s.rolePolicyService.ListPolicies(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policies": policies,
+		"message":  "获取成功",
+	})
+}
+
+// UpdateRolePolicy 更新角色安全策略
+func (s *Server) UpdateRolePolicy(c *gin.Context) {
+	role := models.Role(c.Param("role"))
+	var req struct {
+		MFARequired *bool `json:"mfa_required" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.InvalidRequest(err))
+		return
+	}
+
+	if err := s.rolePolicyService.SetMFARequired(c.Request.Context(), role, *req.MFARequired); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色策略更新成功",
+	})
+}
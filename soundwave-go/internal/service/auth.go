@@ -2,42 +2,78 @@ package service
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"soundwave-go/internal/models"
 	"time"
 
 	"soundwave-go/internal/config"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/identity"
+	"soundwave-go/internal/logger"
 	"soundwave-go/internal/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	// ErrInvalidCredentials 用户名或密码错误，不区分具体原因以防止用户名枚举
+	ErrInvalidCredentials = apperrors.Unauthorized("INVALID_CREDENTIALS", "用户名或密码错误")
+	// ErrAccountLocked 账户因多次登录失败被锁定，仅在已确认用户身份的 MFA、SSO 登录中返回
+	ErrAccountLocked = apperrors.Locked("ACCOUNT_LOCKED", "账户已锁定，请稍后再试或联系管理员")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = apperrors.NotFound("USER_NOT_FOUND", "用户不存在")
+	// ErrInvalidUserID 用户ID格式错误
+	ErrInvalidUserID = apperrors.Validation("INVALID_USER_ID", "无效的用户ID")
+	// ErrUsernameExists 用户名已存在
+	ErrUsernameExists = apperrors.Conflict("USERNAME_EXISTS", "用户名已存在")
+	// ErrWrongPassword 修改密码或关闭 MFA 时密码校验失败
+	ErrWrongPassword = apperrors.Validation("WRONG_PASSWORD", "密码错误")
+)
+
+// dummyPasswordHash 用户不存在时参与比较的哈希，使响应耗时与密码错误时一致
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("soundwave-dummy-password"), bcrypt.DefaultCost)
+
+// permanentLock 锁定时长为0时使用的锁定截止时间，需管理员手动解锁
+var permanentLock = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
 type AuthService struct {
-	users  *mongo.Collection
-	tokens *mongo.Collection
-	config *config.Config
+	users         *mongo.Collection
+	tokens        *mongo.Collection
+	loginAttempts *mongo.Collection
+	config        *config.Config
+	policy        *PasswordPolicy
+	rolePolicies  *RolePolicyService
+	providers     *identity.Registry
 }
 
-func NewAuthService(db *mongo.Database, cfg *config.Config) *AuthService {
+func NewAuthService(db *mongo.Database, cfg *config.Config, rolePolicies *RolePolicyService, providers *identity.Registry) *AuthService {
 	return &AuthService{
-		users:  db.Collection(cfg.MongoDB.Collections.Users),
-		tokens: db.Collection(cfg.MongoDB.Collections.Tokens),
-		config: cfg,
+		users:         db.Collection(cfg.MongoDB.Collections.Users),
+		tokens:        db.Collection(cfg.MongoDB.Collections.Tokens),
+		loginAttempts: db.Collection(cfg.MongoDB.Collections.LoginAttempts),
+		config:        cfg,
+		policy:        NewPasswordPolicy(cfg.Security.PasswordPolicy),
+		rolePolicies:  rolePolicies,
+		providers:     providers,
 	}
 }
 
 func (s *AuthService) Register(user *models.User) error {
+	// 验证密码策略
+	if err := s.policy.Validate(user.Password); err != nil {
+		return err
+	}
+
 	// 检查用户名是否已存在
 	exists, err := s.users.CountDocuments(context.Background(), bson.M{"username": user.Username})
 	if err != nil {
 		return err
 	}
 	if exists > 0 {
-		return errors.New("用户名已存在")
+		return ErrUsernameExists
 	}
 
 	// 加密密码
@@ -47,58 +83,211 @@ func (s *AuthService) Register(user *models.User) error {
 	}
 
 	// 设置默认角色和权限
+	now := time.Now()
 	user.Role = models.RoleUser
 	user.Permissions = []models.Permission{models.PermissionViewServices, models.PermissionViewStats}
 	user.Password = string(hashedPassword)
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	user.PasswordChangedAt = now
+	user.CreatedAt = now
+	user.UpdatedAt = now
 
 	_, err = s.users.InsertOne(context.Background(), user)
 	return err
 }
 
-func (s *AuthService) Login(username, password string) (*models.User, error) {
+// Login 校验用户名和密码，并记录本次登录尝试
+func (s *AuthService) Login(username, password, ip, userAgent string) (*models.User, error) {
+	ctx := context.Background()
+	attempt := &models.LoginAttempt{
+		Username:  username,
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+	defer s.recordLoginAttempt(attempt)
+
 	var user models.User
-	err := s.users.FindOne(context.Background(), bson.M{"username": username}).Decode(&user)
+	err := s.users.FindOne(ctx, bson.M{"username": username}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("用户不存在")
+			bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+			attempt.Reason = models.LoginReasonUserNotFound
+			return nil, ErrInvalidCredentials
 		}
 		return nil, err
 	}
+	attempt.UserID = user.ID.Hex()
 
-	// 验证密码
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		return nil, errors.New("密码错误")
+	// 先验证密码再检查锁定状态，锁定时返回与密码错误相同的错误，
+	// 避免通过响应区分账户是否存在，锁定原因只记录在审计日志中
+	now := time.Now()
+	passwordErr := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		attempt.Reason = models.LoginReasonLocked
+		return nil, ErrInvalidCredentials
+	}
+	if passwordErr != nil {
+		attempt.Reason = models.LoginReasonWrongPassword
+		if err := s.recordLoginFailure(ctx, &user, now); err != nil {
+			logger.ErrorLogger.Printf("更新登录失败次数失败: %v", err)
+		}
+		return nil, ErrInvalidCredentials
 	}
 
+	// 密码过期或不再满足当前策略时，要求用户登录后先修改密码
+	if s.policy.Expired(&user, now) || s.policy.Validate(password) != nil {
+		user.MustChangePassword = true
+	}
+
+	set := bson.M{
+		"failed_attempts":      0,
+		"must_change_password": user.MustChangePassword,
+	}
+	// 启用 MFA 的用户需完成第二步验证才算登录成功
+	if user.MFAEnabled {
+		attempt.Reason = models.LoginReasonMFAPending
+	} else {
+		set["last_login_at"] = now
+		attempt.Success = true
+		user.LastLoginAt = &now
+	}
+
+	update := bson.M{"$set": set, "$unset": bson.M{"locked_until": ""}}
+	if _, err := s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, update); err != nil {
+		attempt.Success = false
+		return nil, err
+	}
+
+	user.FailedAttempts = 0
+	user.LockedUntil = nil
 	return &user, nil
 }
 
-func (s *AuthService) GenerateToken(user *models.User) (string, error) {
-	return utils.GenerateToken(s.config, user)
+// recordLoginFailure 增加失败次数，达到阈值后锁定账户
+func (s *AuthService) recordLoginFailure(ctx context.Context, user *models.User, now time.Time) error {
+	lockout := s.config.Security.Lockout
+
+	// 使用 $inc 原子递增，避免并发登录时计数丢失
+	var updated models.User
+	err := s.users.FindOneAndUpdate(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$inc": bson.M{"failed_attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return err
+	}
+
+	if lockout.MaxFailedAttempts <= 0 || updated.FailedAttempts < lockout.MaxFailedAttempts {
+		return nil
+	}
+
+	lockedUntil := permanentLock
+	if lockout.Duration > 0 {
+		lockedUntil = now.Add(lockout.Duration)
+	}
+	logger.WarnLogger.Printf("用户 %s 连续登录失败 %d 次，账户已锁定至 %s",
+		user.Username, updated.FailedAttempts, lockedUntil.Format(time.RFC3339))
+
+	_, err = s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{"failed_attempts": 0, "locked_until": lockedUntil},
+	})
+	return err
+}
+
+// recordLoginAttempt 保存登录尝试记录，失败只记录日志不影响登录流程
+func (s *AuthService) recordLoginAttempt(attempt *models.LoginAttempt) {
+	attempt.CreatedAt = time.Now()
+	if _, err := s.loginAttempts.InsertOne(context.Background(), attempt); err != nil {
+		logger.ErrorLogger.Printf("保存登录记录失败: %v", err)
+	}
+}
+
+// LoginAttemptFilter 登录记录查询条件
+type LoginAttemptFilter struct {
+	Username string
+	Success  *bool
+	Since    time.Time
+	Limit    int64
+}
+
+// ListLoginAttempts 按时间倒序查询登录记录
+func (s *AuthService) ListLoginAttempts(ctx context.Context, filter LoginAttemptFilter) ([]models.LoginAttempt, error) {
+	query := bson.M{}
+	if filter.Username != "" {
+		query["username"] = filter.Username
+	}
+	if filter.Success != nil {
+		query["success"] = *filter.Success
+	}
+	if !filter.Since.IsZero() {
+		query["created_at"] = bson.M{"$gte": filter.Since}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit)
+
+	cursor, err := s.loginAttempts.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	attempts := make([]models.LoginAttempt, 0)
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// GenerateToken 生成登录会话 token，角色强制 MFA 而用户尚未启用时生成仅能绑定 MFA 的受限 token
+func (s *AuthService) GenerateToken(user *models.User) (string, bool, error) {
+	setupRequired, err := s.MFASetupRequired(context.Background(), user)
+	if err != nil {
+		return "", false, err
+	}
+	if setupRequired {
+		token, err := utils.GenerateMFASetupToken(s.config, user)
+		return token, true, err
+	}
+
+	token, err := utils.GenerateToken(s.config, user)
+	return token, false, err
 }
 
 func (s *AuthService) ChangePassword(userID string, oldPassword, newPassword string) error {
 	var user models.User
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return fmt.Errorf("无效的用户ID")
+		return ErrInvalidUserID
 	}
 
 	// 查找用户
 	err = s.users.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return errors.New("用户不存在")
+			return ErrUserNotFound
 		}
 		return err
 	}
 
+	if user.Provider != "" {
+		return ErrExternalAccount
+	}
+
 	// 验证旧密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
-		return errors.New("原密码错误")
+		return ErrWrongPassword.WithMessage("原密码错误")
+	}
+
+	// 验证新密码
+	if err := s.policy.Validate(newPassword); err != nil {
+		return err
+	}
+	if err := s.policy.CheckHistory(&user, newPassword); err != nil {
+		return err
 	}
 
 	// 生成新密码的哈希值
@@ -108,10 +297,14 @@ func (s *AuthService) ChangePassword(userID string, oldPassword, newPassword str
 	}
 
 	// 更新密码
+	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"password":  string(hashedPassword),
-			"updatedAt": time.Now(),
+			"password":             string(hashedPassword),
+			"password_history":     s.policy.NextHistory(&user),
+			"password_changed_at":  now,
+			"must_change_password": false,
+			"updated_at":           now,
 		},
 	}
 
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/registry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// eventBatchSize 批量写入事件的条数
+	eventBatchSize = 100
+	// eventFlushInterval 未达到批量条数时写入事件的间隔
+	eventFlushInterval = time.Second
+)
+
+// EventService 记录服务实例的生命周期事件，并根据事件计算实例的可用性
+type EventService struct {
+	events    *mongo.Collection
+	registry  *registry.ServiceRegistry
+	retention time.Duration
+}
+
+func NewEventService(db *mongo.Database, cfg *config.Config, sr *registry.ServiceRegistry) *EventService {
+	return &EventService{
+		events:    db.Collection(cfg.MongoDB.Collections.ServiceEvents),
+		registry:  sr,
+		retention: cfg.Events.Retention,
+	}
+}
+
+// EventFilter 事件查询条件
+type EventFilter struct {
+	Namespace  string
+	Service    string
+	InstanceID string
+	Types      []string
+	Since      time.Time
+	Until      time.Time
+	Page       int64 // 从 1 开始
+	PageSize   int64 // 每页条数，最大 500
+}
+
+// Start 订阅注册中心的所有事件并批量写入 MongoDB，写入较慢时事件在内存中排队，不会丢弃
+func (s *EventService) Start(ctx context.Context) {
+	if err := syncRetention(ctx, s.events, s.retention); err != nil {
+		logger.ErrorLogger.Printf("更新事件保留时长失败: %v", err)
+	}
+
+	events, cancel := s.registry.SubscribeLossless("", "")
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(eventFlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]interface{}, 0, eventBatchSize)
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			if _, err := s.events.InsertMany(context.Background(), batch); err != nil {
+				logger.ErrorLogger.Printf("写入 %d 条实例事件失败: %v", len(batch), err)
+			}
+			batch = batch[:0]
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				flush()
+				return
+			case event, ok := <-events:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, newServiceEvent(event))
+				if len(batch) >= eventBatchSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+}
+
+// syncRetention 将集合 ttl_time 索引的过期时间更新为配置的保留时长，索引由数据库迁移创建
+func syncRetention(ctx context.Context, coll *mongo.Collection, retention time.Duration) error {
+	return coll.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll.Name()},
+		{Key: "index", Value: bson.D{
+			{Key: "name", Value: "ttl_time"},
+			{Key: "expireAfterSeconds", Value: int64(retention / time.Second)},
+		}},
+	}).Err()
+}
+
+func newServiceEvent(event registry.Event) *models.ServiceEvent {
+	return &models.ServiceEvent{
+		Namespace:     event.Service.Namespace,
+		Service:       event.Service.Name,
+		InstanceID:    event.Service.ID,
+		Hostname:      event.Service.Hostname,
+		Address:       event.Service.GetAddress(),
+		Type:          event.Type,
+		Status:        string(event.Service.Status),
+		Version:       event.Service.Version,
+		Detail:        event.Detail,
+		Ejected:       event.Service.Ejection != nil,
+		HeartbeatLost: event.Service.HeartbeatLost(),
+		Time:          event.Time,
+	}
+}
+
+// ListEvents 按时间倒序分页查询服务的事件，返回事件和总数
+func (s *EventService) ListEvents(ctx context.Context, filter EventFilter) ([]models.ServiceEvent, int64, error) {
+	query := bson.M{"namespace": filter.Namespace, "service": filter.Service}
+	if filter.InstanceID != "" {
+		query["instance_id"] = filter.InstanceID
+	}
+	if len(filter.Types) > 0 {
+		query["type"] = bson.M{"$in": filter.Types}
+	}
+	if timeRange := timeRangeQuery(filter.Since, filter.Until); timeRange != nil {
+		query["time"] = timeRange
+	}
+
+	total, err := s.events.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, pageSize := filter.Page, filter.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 500 {
+		pageSize = 50
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * pageSize).
+		SetLimit(pageSize)
+
+	cursor, err := s.events.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	events := make([]models.ServiceEvent, 0)
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func timeRangeQuery(since, until time.Time) bson.M {
+	if since.IsZero() && until.IsZero() {
+		return nil
+	}
+	timeRange := bson.M{}
+	if !since.IsZero() {
+		timeRange["$gte"] = since
+	}
+	if !until.IsZero() {
+		timeRange["$lte"] = until
+	}
+	return timeRange
+}
+
+// Availability 根据 [since, until] 内的事件计算服务各实例的可用性，按实例ID排序
+func (s *EventService) Availability(ctx context.Context, namespace, name string, since, until time.Time) ([]models.InstanceAvailability, error) {
+	until = clampUntil(until)
+	initial, events, err := s.loadEvents(ctx, bson.M{"namespace": namespace, "service": name}, since, until)
+	if err != nil {
+		return nil, err
+	}
+	return computeAvailability(initial, events, since, until), nil
+}
+
+// clampUntil 结束时间为空或晚于当前时间时使用当前时间
+func clampUntil(until time.Time) time.Time {
+	if now := time.Now(); until.IsZero() || until.After(now) {
+		return now
+	}
+	return until
+}
+
+// loadEvents 返回满足条件的实例在 since 之前的最后一个事件，以及 [since, until] 内按时间排序的事件。
+// 时间段开始时实例的状态取 since 之前的最后一个事件，事件中记录了当时的上报状态、摘除和心跳情况
+func (s *EventService) loadEvents(ctx context.Context, match bson.M, since, until time.Time) (initial, events []models.ServiceEvent, err error) {
+	before := bson.M{"time": bson.M{"$lt": since}}
+	for key, value := range match {
+		before[key] = value
+	}
+	cursor, err := s.events.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: before}},
+		{{Key: "$sort", Value: bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"service": "$service", "instance_id": "$instance_id"},
+			"event": bson.M{"$first": "$$ROOT"},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$event"}}},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	initial = make([]models.ServiceEvent, 0)
+	err = cursor.All(ctx, &initial)
+	cursor.Close(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	within := bson.M{"time": bson.M{"$gte": since, "$lte": until}}
+	for key, value := range match {
+		within[key] = value
+	}
+	cursor, err = s.events.Find(ctx, within, options.Find().SetSort(bson.D{{Key: "time", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events = make([]models.ServiceEvent, 0)
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, nil, err
+	}
+	return initial, events, nil
+}
+
+// instanceState 实例在某一时刻的可用状态
+type instanceState int
+
+const (
+	// stateGone 实例已注销或尚未注册，不计入观测时长
+	stateGone instanceState = iota
+	stateUp
+	stateDown
+)
+
+// instanceTracker 回放事件时记录实例的注册、上报状态、心跳和摘除情况，据此得出实例的可用状态
+type instanceTracker struct {
+	registered bool
+	available  bool // 实例上报的状态是否可以接收请求
+	heartbeat  bool // 心跳是否正常
+	ejected    bool
+}
+
+// apply 根据事件更新实例的情况
+func (t *instanceTracker) apply(event *models.ServiceEvent) {
+	available := registry.ServiceStatus(event.Status).Available()
+	if !t.registered && event.Type != registry.EventDeregistered {
+		// 首个事件之前的情况未知，按事件中记录的实例状态、摘除和心跳情况推断
+		*t = instanceTracker{registered: true, available: available, heartbeat: !event.HeartbeatLost, ejected: event.Ejected}
+	}
+
+	switch event.Type {
+	case registry.EventDeregistered:
+		*t = instanceTracker{}
+	case registry.EventExpired:
+		// 过期的实例已被移出注册表，重新注册前视为不可用
+		*t = instanceTracker{registered: true}
+	case registry.EventRegistered:
+		// 重新注册不解除摘除
+		t.available, t.heartbeat = available, true
+	case registry.EventHeartbeatRecovered:
+		t.available, t.heartbeat = available, true
+	case registry.EventHeartbeatLost:
+		t.heartbeat = false
+	case registry.EventStatusChanged:
+		t.available = available
+	case registry.EventEjected:
+		t.ejected = true
+	case registry.EventRestored:
+		t.ejected = false
+	}
+	// 元数据、版本和权重的变化不影响可用状态
+}
+
+// state 返回实例当前的可用状态
+func (t *instanceTracker) state() instanceState {
+	switch {
+	case !t.registered:
+		return stateGone
+	case t.available && t.heartbeat && !t.ejected:
+		return stateUp
+	default:
+		return stateDown
+	}
+}
+
+// computeAvailability 按时间顺序回放事件，统计各实例的可用和不可用时长
+func computeAvailability(initial, events []models.ServiceEvent, since, until time.Time) []models.InstanceAvailability {
+	type tracker struct {
+		instanceTracker
+		result models.InstanceAvailability
+		state  instanceState
+		from   time.Time
+	}
+	trackers := make(map[string]*tracker)
+
+	apply := func(event *models.ServiceEvent, at time.Time) {
+		t, ok := trackers[event.InstanceID]
+		if !ok {
+			t = &tracker{result: models.InstanceAvailability{InstanceID: event.InstanceID}}
+			trackers[event.InstanceID] = t
+		}
+
+		switch t.state {
+		case stateUp:
+			t.result.Uptime += at.Sub(t.from)
+		case stateDown:
+			t.result.Downtime += at.Sub(t.from)
+		}
+
+		t.apply(event)
+		state := t.instanceTracker.state()
+		if t.state == stateUp && state == stateDown {
+			t.result.Flaps++
+		}
+		t.state = state
+		t.from = at
+		t.result.Hostname = event.Hostname
+		t.result.Address = event.Address
+		t.result.LastEvent = event.Type
+		t.result.LastEventTime = event.Time
+	}
+
+	for i := range initial {
+		apply(&initial[i], since)
+	}
+	for i := range events {
+		apply(&events[i], events[i].Time)
+	}
+
+	result := make([]models.InstanceAvailability, 0, len(trackers))
+	for _, t := range trackers {
+		switch t.state {
+		case stateUp:
+			t.result.Uptime += until.Sub(t.from)
+		case stateDown:
+			t.result.Downtime += until.Sub(t.from)
+		}
+		observed := t.result.Uptime + t.result.Downtime
+		if observed == 0 {
+			// 整个时间段内都已注销的实例不返回
+			continue
+		}
+		t.result.Availability = float64(t.result.Uptime) / float64(observed)
+		result = append(result, t.result)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].InstanceID < result[j].InstanceID
+	})
+	return result
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/registry"
+)
+
+var testSince = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testEvent 构造相对 testSince 第 minute 分钟发生的事件
+func testEvent(minute int, id string, eventType registry.EventType, status registry.ServiceStatus) models.ServiceEvent {
+	return models.ServiceEvent{
+		InstanceID: id,
+		Type:       eventType,
+		Status:     string(status),
+		Time:       testSince.Add(time.Duration(minute) * time.Minute),
+	}
+}
+
+// withFlags 设置事件发生后实例的摘除和心跳情况
+func withFlags(event models.ServiceEvent, ejected, heartbeatLost bool) models.ServiceEvent {
+	event.Ejected = ejected
+	event.HeartbeatLost = heartbeatLost
+	return event
+}
+
+func TestComputeAvailability(t *testing.T) {
+	until := testSince.Add(60 * time.Minute)
+
+	tests := []struct {
+		name         string
+		initial      []models.ServiceEvent
+		events       []models.ServiceEvent
+		wantUptime   time.Duration
+		wantDowntime time.Duration
+		wantFlaps    int
+	}{
+		{
+			name: "注册为STARTING的实例在元数据变化后仍不可用",
+			events: []models.ServiceEvent{
+				testEvent(0, "a", registry.EventRegistered, registry.StatusStarting),
+				testEvent(10, "a", registry.EventMetadataChanged, registry.StatusStarting),
+				testEvent(20, "a", registry.EventStatusChanged, registry.StatusUP),
+			},
+			wantUptime:   40 * time.Minute,
+			wantDowntime: 20 * time.Minute,
+		},
+		{
+			name: "注册为OUT_OF_SERVICE的实例不可用",
+			events: []models.ServiceEvent{
+				testEvent(0, "a", registry.EventRegistered, registry.StatusOutOfService),
+			},
+			wantDowntime: 60 * time.Minute,
+		},
+		{
+			name:    "版本变化不恢复被摘除的实例",
+			initial: []models.ServiceEvent{testEvent(-5, "a", registry.EventRegistered, registry.StatusUP)},
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventEjected, registry.StatusUP),
+				testEvent(20, "a", registry.EventVersionChanged, registry.StatusUP),
+				testEvent(30, "a", registry.EventWeightChanged, registry.StatusUP),
+				testEvent(40, "a", registry.EventRestored, registry.StatusUP),
+			},
+			wantUptime:   30 * time.Minute,
+			wantDowntime: 30 * time.Minute,
+			wantFlaps:    1,
+		},
+		{
+			name:    "心跳恢复时按上报的状态",
+			initial: []models.ServiceEvent{testEvent(-5, "a", registry.EventRegistered, registry.StatusUP)},
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventHeartbeatLost, registry.StatusUP),
+				testEvent(20, "a", registry.EventHeartbeatRecovered, registry.StatusStarting),
+				testEvent(30, "a", registry.EventStatusChanged, registry.StatusUP),
+			},
+			wantUptime:   40 * time.Minute,
+			wantDowntime: 20 * time.Minute,
+			wantFlaps:    1,
+		},
+		{
+			name:    "解除摘除时心跳仍未恢复",
+			initial: []models.ServiceEvent{testEvent(-5, "a", registry.EventRegistered, registry.StatusUP)},
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventEjected, registry.StatusUP),
+				testEvent(20, "a", registry.EventHeartbeatLost, registry.StatusUP),
+				testEvent(30, "a", registry.EventRestored, registry.StatusUP),
+				testEvent(40, "a", registry.EventHeartbeatRecovered, registry.StatusUP),
+			},
+			wantUptime:   30 * time.Minute,
+			wantDowntime: 30 * time.Minute,
+			wantFlaps:    1,
+		},
+		{
+			name:    "解除摘除后回到上报的状态",
+			initial: []models.ServiceEvent{testEvent(-5, "a", registry.EventRegistered, registry.StatusUP)},
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventStatusChanged, registry.StatusOutOfService),
+				testEvent(20, "a", registry.EventEjected, registry.StatusOutOfService),
+				testEvent(30, "a", registry.EventRestored, registry.StatusOutOfService),
+				testEvent(45, "a", registry.EventStatusChanged, registry.StatusUP),
+			},
+			wantUptime:   25 * time.Minute,
+			wantDowntime: 35 * time.Minute,
+			wantFlaps:    1,
+		},
+		{
+			name:    "重新注册不解除摘除",
+			initial: []models.ServiceEvent{testEvent(-5, "a", registry.EventRegistered, registry.StatusUP)},
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventEjected, registry.StatusUP),
+				testEvent(20, "a", registry.EventRegistered, registry.StatusUP),
+				testEvent(30, "a", registry.EventRestored, registry.StatusUP),
+			},
+			wantUptime:   40 * time.Minute,
+			wantDowntime: 20 * time.Minute,
+			wantFlaps:    1,
+		},
+		{
+			name:    "开始前的最后一个事件为权重变化时按其记录的状态推断",
+			initial: []models.ServiceEvent{testEvent(-5, "a", registry.EventWeightChanged, registry.StatusDegraded)},
+			events: []models.ServiceEvent{
+				testEvent(30, "a", registry.EventDeregistered, registry.StatusDegraded),
+			},
+			wantUptime: 30 * time.Minute,
+		},
+		{
+			name:    "开始前的最后一个事件为权重变化且实例已被摘除",
+			initial: []models.ServiceEvent{withFlags(testEvent(-5, "a", registry.EventWeightChanged, registry.StatusUP), true, false)},
+			events: []models.ServiceEvent{
+				testEvent(30, "a", registry.EventRestored, registry.StatusUP),
+			},
+			wantUptime:   30 * time.Minute,
+			wantDowntime: 30 * time.Minute,
+		},
+		{
+			name:    "开始前的最后一个事件为元数据变化且心跳超时",
+			initial: []models.ServiceEvent{withFlags(testEvent(-5, "a", registry.EventMetadataChanged, registry.StatusUP), false, true)},
+			events: []models.ServiceEvent{
+				testEvent(20, "a", registry.EventHeartbeatRecovered, registry.StatusUP),
+			},
+			wantUptime:   40 * time.Minute,
+			wantDowntime: 20 * time.Minute,
+		},
+		{
+			name:    "过期后重新注册",
+			initial: []models.ServiceEvent{testEvent(-5, "a", registry.EventEjected, registry.StatusUP)},
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventExpired, registry.StatusDOWN),
+				testEvent(20, "a", registry.EventRegistered, registry.StatusUP),
+			},
+			wantUptime:   40 * time.Minute,
+			wantDowntime: 20 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := computeAvailability(tt.initial, tt.events, testSince, until)
+			if len(result) != 1 {
+				t.Fatalf("返回 %d 个实例, 期望 1 个", len(result))
+			}
+			got := result[0]
+			if got.Uptime != tt.wantUptime || got.Downtime != tt.wantDowntime {
+				t.Errorf("Uptime = %s, Downtime = %s, 期望 %s, %s", got.Uptime, got.Downtime, tt.wantUptime, tt.wantDowntime)
+			}
+			if got.Flaps != tt.wantFlaps {
+				t.Errorf("Flaps = %d, 期望 %d", got.Flaps, tt.wantFlaps)
+			}
+		})
+	}
+}
+
+func TestComputeSLA(t *testing.T) {
+	until := testSince.Add(60 * time.Minute)
+	bothUp := []models.ServiceEvent{
+		testEvent(-5, "a", registry.EventRegistered, registry.StatusUP),
+		testEvent(-5, "b", registry.EventRegistered, registry.StatusUP),
+	}
+
+	type incident struct{ start, end int }
+	tests := []struct {
+		name          string
+		initial       []models.ServiceEvent
+		events        []models.ServiceEvent
+		minHealthy    int
+		wantDowntime  time.Duration
+		wantIncidents []incident
+	}{
+		{
+			name:       "元数据变化不恢复被摘除的实例",
+			initial:    bothUp,
+			minHealthy: 2,
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventEjected, registry.StatusUP),
+				testEvent(20, "a", registry.EventMetadataChanged, registry.StatusUP),
+				testEvent(40, "a", registry.EventRestored, registry.StatusUP),
+			},
+			wantDowntime:  30 * time.Minute,
+			wantIncidents: []incident{{10, 40}},
+		},
+		{
+			name:       "注册为STARTING的实例在状态变为UP前不健康",
+			minHealthy: 1,
+			events: []models.ServiceEvent{
+				testEvent(0, "a", registry.EventRegistered, registry.StatusStarting),
+				testEvent(10, "a", registry.EventVersionChanged, registry.StatusStarting),
+				testEvent(30, "a", registry.EventStatusChanged, registry.StatusUP),
+			},
+			wantDowntime:  30 * time.Minute,
+			wantIncidents: []incident{{0, 30}},
+		},
+		{
+			name:       "心跳恢复时按上报的状态",
+			initial:    bothUp,
+			minHealthy: 2,
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventHeartbeatLost, registry.StatusUP),
+				testEvent(20, "a", registry.EventHeartbeatRecovered, registry.StatusOutOfService),
+				testEvent(50, "a", registry.EventStatusChanged, registry.StatusUP),
+			},
+			wantDowntime:  40 * time.Minute,
+			wantIncidents: []incident{{10, 50}},
+		},
+		{
+			name:       "解除摘除后回到上报的状态",
+			initial:    bothUp,
+			minHealthy: 2,
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventStatusChanged, registry.StatusOutOfService),
+				testEvent(20, "a", registry.EventEjected, registry.StatusOutOfService),
+				testEvent(30, "a", registry.EventRestored, registry.StatusOutOfService),
+				testEvent(45, "a", registry.EventStatusChanged, registry.StatusUP),
+			},
+			wantDowntime:  35 * time.Minute,
+			wantIncidents: []incident{{10, 45}},
+		},
+		{
+			name:       "权重变化不影响健康实例数",
+			initial:    bothUp,
+			minHealthy: 2,
+			events: []models.ServiceEvent{
+				testEvent(10, "a", registry.EventWeightChanged, registry.StatusUP),
+				testEvent(20, "b", registry.EventMetadataChanged, registry.StatusDegraded),
+			},
+			wantIncidents: []incident{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := computeSLA(tt.initial, tt.events, testSince, until, tt.minHealthy)
+			if report.Downtime != tt.wantDowntime || report.Uptime != 60*time.Minute-tt.wantDowntime {
+				t.Errorf("Uptime = %s, Downtime = %s, 期望 Downtime = %s", report.Uptime, report.Downtime, tt.wantDowntime)
+			}
+			if len(report.Incidents) != len(tt.wantIncidents) {
+				t.Fatalf("Incidents = %+v, 期望 %v", report.Incidents, tt.wantIncidents)
+			}
+			for i, want := range tt.wantIncidents {
+				got := report.Incidents[i]
+				start, end := testSince.Add(time.Duration(want.start)*time.Minute), testSince.Add(time.Duration(want.end)*time.Minute)
+				if !got.Start.Equal(start) || got.End == nil || !got.End.Equal(end) {
+					t.Errorf("Incidents[%d] = %s - %v, 期望 %s - %s", i, got.Start, got.End, start, end)
+				}
+			}
+		})
+	}
+}
+
+func TestNewServiceEventRecordsEjection(t *testing.T) {
+	sr := registry.NewServiceRegistry(registry.WithOutlierDetection(registry.OutlierPolicy{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    time.Minute,
+		MaxEjectionTime:     time.Minute,
+		MaxEjectionPercent:  1,
+	}))
+	events, cancel := sr.SubscribeLossless("", "")
+	defer cancel()
+
+	if err := sr.RegisterService(&registry.Service{Name: "api", ID: "a", Hostname: "h", IP: "10.0.0.1", Port: 80}); err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+	if _, err := sr.ReportOutcome(registry.DefaultNamespace, "api", "a", registry.OutlierReport{Source: registry.SourceCaller, Failures: 1}); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+
+	want := map[registry.EventType]bool{registry.EventRegistered: false, registry.EventEjected: true}
+	for i := 0; i < len(want); i++ {
+		event := newServiceEvent(<-events)
+		if ejected, ok := want[event.Type]; !ok || event.Ejected != ejected || event.HeartbeatLost {
+			t.Errorf("%s 事件 Ejected = %v, HeartbeatLost = %v", event.Type, event.Ejected, event.HeartbeatLost)
+		}
+	}
+}
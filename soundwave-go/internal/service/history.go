@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"soundwave-go/internal/config"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/registry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultHistoryPoints 未指定 step 时返回的点数
+	defaultHistoryPoints = 360
+	// maxHistoryPoints 最多返回的点数，step 过小时自动调大
+	maxHistoryPoints = 2000
+)
+
+// HistoryService 将注册中心内存中的历史数据降采样后写入 MongoDB，并提供按时间范围查询
+type HistoryService struct {
+	history  *mongo.Collection
+	registry *registry.ServiceRegistry
+	config   config.HistoryConfig
+	started  time.Time
+}
+
+func NewHistoryService(db *mongo.Database, cfg *config.Config, sr *registry.ServiceRegistry) *HistoryService {
+	return &HistoryService{
+		history:  db.Collection(cfg.MongoDB.Collections.ServiceHistory),
+		registry: sr,
+		config:   cfg.History,
+	}
+}
+
+// Start 定期将已结束的降采样周期写入 MongoDB，启动时所在的周期只包含启动后的采样点
+func (s *HistoryService) Start(ctx context.Context) {
+	if err := syncRetention(ctx, s.history, s.config.Retention); err != nil {
+		logger.ErrorLogger.Printf("更新历史数据保留时长失败: %v", err)
+	}
+
+	s.started = time.Now()
+	go func() {
+		ticker := time.NewTicker(s.config.Resolution)
+		defer ticker.Stop()
+
+		flushed := s.started.Truncate(s.config.Downsample)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				// 周期内最后一个采样点在周期结束时写入，再等待一个采样间隔
+				end := now.Add(-s.config.Resolution).Truncate(s.config.Downsample)
+				for ; flushed.Before(end); flushed = flushed.Add(s.config.Downsample) {
+					s.persist(ctx, flushed, flushed.Add(s.config.Downsample))
+				}
+			}
+		}
+	}()
+}
+
+// persist 将所有服务在 [since, until) 内的采样点降采样后写入 MongoDB
+func (s *HistoryService) persist(ctx context.Context, since, until time.Time) {
+	series := s.registry.DownsampleHistory(since, until)
+	if len(series) == 0 {
+		return
+	}
+	docs := make([]interface{}, 0, len(series))
+	for _, item := range series {
+		for _, point := range item.Points {
+			docs = append(docs, newServiceHistoryPoint(item.Namespace, item.Service, point))
+		}
+	}
+	if _, err := s.history.InsertMany(ctx, docs); err != nil {
+		logger.ErrorLogger.Printf("写入 %s 的 %d 条历史数据失败: %v", since.Format(time.RFC3339), len(docs), err)
+	}
+}
+
+func newServiceHistoryPoint(namespace, service string, point registry.HistoryPoint) *models.ServiceHistoryPoint {
+	return &models.ServiceHistoryPoint{
+		Namespace:       namespace,
+		Service:         service,
+		Time:            point.Time,
+		Healthy:         point.Healthy,
+		Unhealthy:       point.Unhealthy,
+		MinHealthy:      point.MinHealthy,
+		MaxUnhealthy:    point.MaxUnhealthy,
+		Heartbeats:      point.Heartbeats,
+		LatenessAvg:     point.LatenessAvg,
+		LatenessMax:     point.LatenessMax,
+		Registrations:   point.Registrations,
+		Deregistrations: point.Deregistrations,
+		Expirations:     point.Expirations,
+		Samples:         point.Samples,
+	}
+}
+
+func historyPoint(doc *models.ServiceHistoryPoint) registry.HistoryPoint {
+	return registry.HistoryPoint{
+		Time:            doc.Time,
+		Healthy:         doc.Healthy,
+		Unhealthy:       doc.Unhealthy,
+		MinHealthy:      doc.MinHealthy,
+		MaxUnhealthy:    doc.MaxUnhealthy,
+		Heartbeats:      doc.Heartbeats,
+		LatenessAvg:     doc.LatenessAvg,
+		LatenessMax:     doc.LatenessMax,
+		Registrations:   doc.Registrations,
+		Deregistrations: doc.Deregistrations,
+		Expirations:     doc.Expirations,
+		Samples:         doc.Samples,
+	}
+}
+
+// Range 返回服务在 [since, until) 内每 step 一个点的历史数据。
+// 内存中保留的时间段使用原始采样点，更早的时间段使用 MongoDB 中降采样后的数据，此时 step 不小于降采样间隔。
+// step 为 0 时按时间范围自动计算，并向上取整为采样间隔的整数倍
+func (s *HistoryService) Range(ctx context.Context, namespace, name string, since, until time.Time, step time.Duration) (*models.ServiceHistory, error) {
+	if s.started.IsZero() {
+		return nil, apperrors.ErrInvalidRequest.WithMessage("未启用历史数据")
+	}
+	until = clampUntil(until)
+	if !since.Before(until) {
+		return nil, apperrors.ErrInvalidRequest.WithMessage("since必须早于until")
+	}
+
+	// 内存中的采样点从启动时开始，最多保留 MemoryRetention，按降采样周期对齐后与 MongoDB 中的数据衔接
+	memoryStart := time.Now().Add(-s.config.MemoryRetention)
+	if memoryStart.Before(s.started) {
+		memoryStart = s.started
+	}
+	memoryStart = memoryStart.Truncate(s.config.Downsample).Add(s.config.Downsample)
+
+	stored := since.Before(memoryStart)
+	base := s.config.Resolution
+	if stored {
+		base = s.config.Downsample
+	}
+	if step <= 0 {
+		step = until.Sub(since) / defaultHistoryPoints
+	}
+	if minStep := until.Sub(since) / maxHistoryPoints; step < minStep {
+		step = minStep
+	}
+	step = (step + base - 1) / base * base
+	since = since.Truncate(step)
+
+	points := make([]registry.HistoryPoint, 0)
+	if stored {
+		end := memoryStart
+		if until.Before(end) {
+			end = until
+		}
+		loaded, err := s.loadPoints(ctx, namespace, name, since, end)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, loaded...)
+	}
+	if until.After(memoryStart) {
+		start := since
+		if start.Before(memoryStart) {
+			start = memoryStart
+		}
+		points = append(points, s.registry.History(namespace, name, start, until)...)
+	}
+
+	return &models.ServiceHistory{
+		Since:  since,
+		Until:  until,
+		Step:   step,
+		Points: registry.DownsampleHistoryPoints(points, step),
+	}, nil
+}
+
+// loadPoints 按时间顺序读取 MongoDB 中 [since, until) 内的降采样数据
+func (s *HistoryService) loadPoints(ctx context.Context, namespace, name string, since, until time.Time) ([]registry.HistoryPoint, error) {
+	cursor, err := s.history.Find(ctx, bson.M{
+		"namespace": namespace,
+		"service":   name,
+		"time":      bson.M{"$gte": since, "$lt": until},
+	}, options.Find().SetSort(bson.D{{Key: "time", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := make([]models.ServiceHistoryPoint, 0)
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	points := make([]registry.HistoryPoint, 0, len(docs))
+	for i := range docs {
+		points = append(points, historyPoint(&docs[i]))
+	}
+	return points, nil
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidMFACode MFA 验证码或恢复码错误
+	ErrInvalidMFACode = apperrors.Validation("INVALID_MFA_CODE", "验证码错误")
+	// ErrInvalidMFAToken MFA 登录 token 无效或已过期
+	ErrInvalidMFAToken = apperrors.Unauthorized("INVALID_MFA_TOKEN", "MFA验证已过期，请重新登录")
+	// ErrMFANotSetup 尚未生成 MFA 密钥
+	ErrMFANotSetup = apperrors.Conflict("MFA_NOT_SETUP", "请先获取MFA绑定密钥")
+	// ErrMFAAlreadyEnabled MFA 已启用
+	ErrMFAAlreadyEnabled = apperrors.Conflict("MFA_ALREADY_ENABLED", "MFA已启用")
+	// ErrMFANotEnabled MFA 未启用
+	ErrMFANotEnabled = apperrors.Conflict("MFA_NOT_ENABLED", "MFA未启用")
+	// ErrMFARequiredByRole 当前角色强制启用 MFA
+	ErrMFARequiredByRole = apperrors.Forbidden("MFA_REQUIRED_BY_ROLE", "当前角色要求必须启用MFA")
+)
+
+// MFASetup MFA 绑定信息
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAStatus 用户 MFA 状态
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// GenerateMFAToken 生成登录第二步使用的临时 token
+func (s *AuthService) GenerateMFAToken(user *models.User) (string, error) {
+	return utils.GenerateMFAToken(s.config, user, s.config.Security.MFA.PendingTokenTTL)
+}
+
+// VerifyLoginMFA 完成登录第二步，code 可以是 TOTP 验证码或恢复码
+func (s *AuthService) VerifyLoginMFA(mfaToken, code, ip, userAgent string) (*models.User, error) {
+	claims, err := utils.ParseMFAToken(s.config, mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	ctx := context.Background()
+	attempt := &models.LoginAttempt{
+		Username:  claims.Username,
+		UserID:    claims.UserID,
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+	defer s.recordLoginAttempt(attempt)
+
+	user, err := s.findUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		attempt.Reason = models.LoginReasonLocked
+		return nil, ErrAccountLocked
+	}
+	if !user.MFAEnabled {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.verifyMFACode(ctx, user, code, now); err != nil {
+		attempt.Reason = models.LoginReasonInvalidMFA
+		if err := s.recordLoginFailure(ctx, user, now); err != nil {
+			logger.ErrorLogger.Printf("更新登录失败次数失败: %v", err)
+		}
+		return nil, err
+	}
+
+	if _, err := s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{"failed_attempts": 0, "last_login_at": now},
+	}); err != nil {
+		return nil, err
+	}
+
+	attempt.Success = true
+	user.LastLoginAt = &now
+	return user, nil
+}
+
+// GetMFAStatus 查询用户 MFA 状态
+func (s *AuthService) GetMFAStatus(ctx context.Context, userID string) (*MFAStatus, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := s.rolePolicies.RequiresMFA(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAStatus{
+		Enabled:                user.MFAEnabled,
+		Required:               required,
+		EnabledAt:              user.MFAEnabledAt,
+		RecoveryCodesRemaining: len(user.MFARecoveryCodes),
+	}, nil
+}
+
+// SetupMFA 生成新的 TOTP 密钥，需调用 EnableMFA 验证后才生效
+func (s *AuthService) SetupMFA(ctx context.Context, userID string) (*MFASetup, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{"mfa_pending_secret": secret, "updated_at": time.Now()},
+	}); err != nil {
+		return nil, err
+	}
+
+	return &MFASetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.config.Security.MFA.Issuer, user.Username, secret),
+	}, nil
+}
+
+// EnableMFA 校验验证码后启用 MFA，返回一次性恢复码明文
+func (s *AuthService) EnableMFA(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFAPendingSecret == "" {
+		return nil, ErrMFANotSetup
+	}
+
+	now := time.Now()
+	step, ok := utils.ValidateTOTPCode(user.MFAPendingSecret, code, now)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"mfa_enabled":        true,
+			"mfa_secret":         user.MFAPendingSecret,
+			"mfa_last_step":      step,
+			"mfa_recovery_codes": hashes,
+			"mfa_enabled_at":     now,
+			"updated_at":         now,
+		},
+		"$unset": bson.M{"mfa_pending_secret": ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableMFA 用户自行关闭 MFA，需要验证密码和验证码
+func (s *AuthService) DisableMFA(ctx context.Context, userID, password, code string) error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+
+	required, err := s.rolePolicies.RequiresMFA(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByRole
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+	if err := s.verifyMFACode(ctx, user, code, time.Now()); err != nil {
+		return err
+	}
+
+	_, err = s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set":   bson.M{"mfa_enabled": false, "updated_at": time.Now()},
+		"$unset": mfaUnsetFields,
+	})
+	return err
+}
+
+// RegenerateRecoveryCodes 使旧恢复码失效并生成新的恢复码
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	step, ok := utils.ValidateTOTPCode(user.MFASecret, code, time.Now())
+	if !ok || step <= user.MFALastStep {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"mfa_last_step":      step,
+			"mfa_recovery_codes": hashes,
+			"updated_at":         time.Now(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// MFASetupRequired 判断用户所属角色强制 MFA 但用户尚未启用
+func (s *AuthService) MFASetupRequired(ctx context.Context, user *models.User) (bool, error) {
+	if user.MFAEnabled {
+		return false, nil
+	}
+	return s.rolePolicies.RequiresMFA(ctx, user.Role)
+}
+
+// verifyMFACode 校验 TOTP 验证码或恢复码，验证码不能重复使用，恢复码使用后作废
+func (s *AuthService) verifyMFACode(ctx context.Context, user *models.User, code string, now time.Time) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := utils.ValidateTOTPCode(user.MFASecret, code, now); ok {
+		// 仅当时间步大于上次使用的时间步时才更新，防止验证码被重放
+		result, err := s.users.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "mfa_last_step": bson.M{"$lt": step}},
+			bson.M{"$set": bson.M{"mfa_last_step": step}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	hash := hashRecoveryCode(code)
+	for _, stored := range user.MFARecoveryCodes {
+		if stored == hash {
+			result, err := s.users.UpdateOne(ctx,
+				bson.M{"_id": user.ID, "mfa_recovery_codes": hash},
+				bson.M{"$pull": bson.M{"mfa_recovery_codes": hash}},
+			)
+			if err != nil {
+				return err
+			}
+			// 并发请求已使用该恢复码
+			if result.ModifiedCount == 0 {
+				return ErrInvalidMFACode
+			}
+			return nil
+		}
+	}
+
+	return ErrInvalidMFACode
+}
+
+// generateRecoveryCodes 生成恢复码，返回明文和哈希
+func (s *AuthService) generateRecoveryCodes() ([]string, []string, error) {
+	count := s.config.Security.MFA.RecoveryCodeCount
+	codes := make([]string, 0, count)
+	hashes := make([]string, 0, count)
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < count; i++ {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))
+		code := raw[:5] + "-" + raw[5:10]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 恢复码为高熵随机串，使用 SHA-256 存储即可
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+// mfaUnsetFields 关闭或重置 MFA 时需要清除的字段
+var mfaUnsetFields = bson.M{
+	"mfa_secret":         "",
+	"mfa_pending_secret": "",
+	"mfa_last_step":      "",
+	"mfa_recovery_codes": "",
+	"mfa_enabled_at":     "",
+}
+
+func (s *AuthService) findUser(ctx context.Context, userID string) (*models.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidUserID
+	}
+
+	var user models.User
+	if err := s.users.FindOne(ctx, bson.M{"_id": objID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"soundwave-go/internal/config"
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrPasswordPolicy 密码不满足密码策略
+var ErrPasswordPolicy = apperrors.Validation("PASSWORD_POLICY_VIOLATION", "密码不满足安全策略")
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	config config.PasswordPolicyConfig
+}
+
+func NewPasswordPolicy(cfg config.PasswordPolicyConfig) *PasswordPolicy {
+	return &PasswordPolicy{config: cfg}
+}
+
+// Validate 检查密码是否满足长度和复杂度要求
+func (p *PasswordPolicy) Validate(password string) error {
+	length := len([]rune(password))
+	if length < p.config.MinLength || length > p.config.MaxLength {
+		return ErrPasswordPolicy.WithMessage(fmt.Sprintf("密码长度必须在%d-%d个字符之间", p.config.MinLength, p.config.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+
+	var missing []string
+	if p.config.RequireUpper && !hasUpper {
+		missing = append(missing, "大写字母")
+	}
+	if p.config.RequireLower && !hasLower {
+		missing = append(missing, "小写字母")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		missing = append(missing, "数字")
+	}
+	if p.config.RequireSpecial && !hasSpecial {
+		missing = append(missing, "特殊字符")
+	}
+	if len(missing) > 0 {
+		return ErrPasswordPolicy.WithMessage(fmt.Sprintf("密码必须包含%s", strings.Join(missing, "、")))
+	}
+
+	return nil
+}
+
+// CheckHistory 检查新密码是否与当前密码或最近使用过的密码重复
+func (p *PasswordPolicy) CheckHistory(user *models.User, password string) error {
+	if p.config.HistorySize <= 0 {
+		return nil
+	}
+
+	hashes := append([]string{user.Password}, user.PasswordHistory...)
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return ErrPasswordPolicy.WithMessage(fmt.Sprintf("不能使用最近%d次使用过的密码", p.config.HistorySize))
+		}
+	}
+	return nil
+}
+
+// NextHistory 返回更换密码后应保存的历史密码列表
+func (p *PasswordPolicy) NextHistory(user *models.User) []string {
+	if p.config.HistorySize <= 0 {
+		return nil
+	}
+
+	// 新密码本身占一个名额，历史列表只需保留 HistorySize-1 个
+	limit := p.config.HistorySize - 1
+	history := make([]string, 0, limit)
+	if user.Password != "" && limit > 0 {
+		history = append(history, user.Password)
+	}
+	for _, hash := range user.PasswordHistory {
+		if len(history) >= limit {
+			break
+		}
+		history = append(history, hash)
+	}
+	return history
+}
+
+// Expired 判断用户密码是否已超过有效期
+func (p *PasswordPolicy) Expired(user *models.User, now time.Time) bool {
+	if p.config.MaxAge <= 0 || user.PasswordChangedAt.IsZero() {
+		return false
+	}
+	return now.Sub(user.PasswordChangedAt) > p.config.MaxAge
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RolePolicyService 管理按角色生效的安全策略
+type RolePolicyService struct {
+	policies *mongo.Collection
+}
+
+func NewRolePolicyService(db *mongo.Database, cfg *config.Config) *RolePolicyService {
+	return &RolePolicyService{
+		policies: db.Collection(cfg.MongoDB.Collections.RolePolicies),
+	}
+}
+
+// ListPolicies 返回所有角色的策略，未配置的角色返回默认策略
+func (s *RolePolicyService) ListPolicies(ctx context.Context) ([]models.RolePolicy, error) {
+	cursor, err := s.policies.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stored []models.RolePolicy
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+
+	byRole := make(map[models.Role]models.RolePolicy, len(stored))
+	for _, p := range stored {
+		byRole[p.Role] = p
+	}
+
+	policies := make([]models.RolePolicy, 0, len(validRoles))
+	for _, role := range validRoles {
+		if p, ok := byRole[role]; ok {
+			policies = append(policies, p)
+		} else {
+			policies = append(policies, models.RolePolicy{Role: role})
+		}
+	}
+	return policies, nil
+}
+
+// RequiresMFA 判断指定角色是否强制启用 MFA
+func (s *RolePolicyService) RequiresMFA(ctx context.Context, role models.Role) (bool, error) {
+	var policy models.RolePolicy
+	err := s.policies.FindOne(ctx, bson.M{"role": role}).Decode(&policy)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, err
+	}
+	return policy.MFARequired, nil
+}
+
+// SetMFARequired 设置指定角色是否强制启用 MFA
+func (s *RolePolicyService) SetMFARequired(ctx context.Context, role models.Role, required bool) error {
+	if !isValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	_, err := s.policies.UpdateOne(ctx,
+		bson.M{"role": role},
+		bson.M{"$set": bson.M{"role": role, "mfa_required": required}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SLA 报告的时间窗口
+const (
+	SLAWindowDay   = "day"
+	SLAWindowWeek  = "week"
+	SLAWindowMonth = "month"
+)
+
+var slaWindows = map[string]time.Duration{
+	SLAWindowDay:   24 * time.Hour,
+	SLAWindowWeek:  7 * 24 * time.Hour,
+	SLAWindowMonth: 30 * 24 * time.Hour,
+}
+
+// SLAWindowDuration 返回时间窗口的长度，空值视为 day
+func SLAWindowDuration(window string) (string, time.Duration, error) {
+	if window == "" {
+		window = SLAWindowDay
+	}
+	duration, ok := slaWindows[window]
+	if !ok {
+		return "", 0, apperrors.ErrInvalidRequest.WithMessage(fmt.Sprintf("无效的时间窗口: %s，可选 day、week、month", window))
+	}
+	return window, duration, nil
+}
+
+// SLAReport 生成服务截至 until 的时间窗口内的可用性报告
+func (s *EventService) SLAReport(ctx context.Context, namespace, name, window string, until time.Time, minHealthy int) (*models.SLAReport, error) {
+	window, duration, err := SLAWindowDuration(window)
+	if err != nil {
+		return nil, err
+	}
+	until = clampUntil(until)
+	since := until.Add(-duration)
+
+	initial, events, err := s.loadEvents(ctx, bson.M{"namespace": namespace, "service": name}, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	report := computeSLA(initial, events, since, until, minHealthy)
+	report.Namespace = namespace
+	report.Service = name
+	report.Window = window
+	report.Instances = computeAvailability(initial, events, since, until)
+	return report, nil
+}
+
+// SLASummary 统计命名空间内所有有事件记录的服务在时间窗口内的可用性，按服务名称排序
+func (s *EventService) SLASummary(ctx context.Context, namespace, window string, minHealthy int) ([]models.SLASummary, error) {
+	_, duration, err := SLAWindowDuration(window)
+	if err != nil {
+		return nil, err
+	}
+	until := time.Now()
+	since := until.Add(-duration)
+
+	initial, events, err := s.loadEvents(ctx, bson.M{"namespace": namespace}, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	initialByService := make(map[string][]models.ServiceEvent)
+	for _, event := range initial {
+		initialByService[event.Service] = append(initialByService[event.Service], event)
+	}
+	eventsByService := make(map[string][]models.ServiceEvent)
+	for _, event := range events {
+		eventsByService[event.Service] = append(eventsByService[event.Service], event)
+	}
+
+	names := make(map[string]bool)
+	for name := range initialByService {
+		names[name] = true
+	}
+	for name := range eventsByService {
+		names[name] = true
+	}
+
+	result := make([]models.SLASummary, 0, len(names))
+	for name := range names {
+		report := computeSLA(initialByService[name], eventsByService[name], since, until, minHealthy)
+		instances := computeAvailability(initialByService[name], eventsByService[name], since, until)
+		if report.Uptime+report.Downtime == 0 {
+			// 整个时间窗口内都已下线的服务不返回
+			continue
+		}
+		result = append(result, models.SLASummary{
+			Service:      name,
+			Availability: report.Availability,
+			Downtime:     report.Downtime,
+			Incidents:    len(report.Incidents),
+			Instances:    len(instances),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Service < result[j].Service
+	})
+	return result, nil
+}
+
+// computeSLA 按时间顺序回放服务的事件，统计健康实例数不低于 minHealthy 的时长和故障区间
+func computeSLA(initial, events []models.ServiceEvent, since, until time.Time, minHealthy int) *models.SLAReport {
+	if minHealthy <= 0 {
+		minHealthy = 1
+	}
+	report := &models.SLAReport{
+		Since:      since,
+		Until:      until,
+		MinHealthy: minHealthy,
+		Incidents:  make([]models.SLAIncident, 0),
+	}
+
+	trackers := make(map[string]*instanceTracker)
+	var incident *models.SLAIncident
+	last := since
+
+	// advance 统计 [last, at) 内的可用性
+	advance := func(at time.Time) {
+		healthy, observed := 0, false
+		for _, t := range trackers {
+			switch t.state() {
+			case stateUp:
+				healthy++
+				observed = true
+			case stateDown:
+				observed = true
+			}
+		}
+
+		elapsed := at.Sub(last)
+		switch {
+		case !observed:
+			// 所有实例都已注销，不计入观测时长，故障视为结束
+			if incident != nil {
+				report.Incidents = append(report.Incidents, closeIncident(incident, last))
+				incident = nil
+			}
+		case healthy >= minHealthy:
+			report.Uptime += elapsed
+			if incident != nil {
+				report.Incidents = append(report.Incidents, closeIncident(incident, last))
+				incident = nil
+			}
+		case elapsed == 0:
+			// 同一时刻的多个事件，以最后的状态为准
+		default:
+			report.Downtime += elapsed
+			if incident == nil {
+				incident = &models.SLAIncident{Start: last, LowestHealthy: healthy}
+			} else if healthy < incident.LowestHealthy {
+				incident.LowestHealthy = healthy
+			}
+		}
+		last = at
+	}
+
+	apply := func(event *models.ServiceEvent) {
+		t, ok := trackers[event.InstanceID]
+		if !ok {
+			t = &instanceTracker{}
+			trackers[event.InstanceID] = t
+		}
+		t.apply(event)
+	}
+	for i := range initial {
+		apply(&initial[i])
+	}
+	for i := range events {
+		advance(events[i].Time)
+		apply(&events[i])
+	}
+	advance(until)
+
+	if incident != nil {
+		incident.Duration = until.Sub(incident.Start)
+		report.Incidents = append(report.Incidents, *incident)
+	}
+	if observed := report.Uptime + report.Downtime; observed > 0 {
+		report.Availability = float64(report.Uptime) / float64(observed)
+	}
+	return report
+}
+
+func closeIncident(incident *models.SLAIncident, end time.Time) models.SLAIncident {
+	closed := *incident
+	closed.End = &end
+	closed.Duration = end.Sub(incident.Start)
+	return closed
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	apperrors "soundwave-go/internal/errors"
+	"soundwave-go/internal/identity"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrProviderNotFound 身份源不存在或类型不匹配
+	ErrProviderNotFound = apperrors.NotFound("PROVIDER_NOT_FOUND", "身份源不存在")
+	// ErrProviderAuthFailed 外部身份源认证失败，底层原因只记录日志
+	ErrProviderAuthFailed = apperrors.Unauthorized("PROVIDER_AUTH_FAILED", "身份源认证失败")
+	// ErrUsernameTaken 外部用户的用户名已被本地账户占用
+	ErrUsernameTaken = apperrors.Conflict("USERNAME_TAKEN", "用户名已被其他账户使用，请联系管理员")
+	// ErrExternalAccount 外部身份源用户不能修改本地密码
+	ErrExternalAccount = apperrors.Forbidden("EXTERNAL_ACCOUNT", "外部身份源账户请在身份源中修改密码")
+)
+
+// ListProviders 返回已启用的外部身份源
+func (s *AuthService) ListProviders() []identity.ProviderInfo {
+	return s.providers.List()
+}
+
+// LoginWithProvider 使用 LDAP 等用户名密码类身份源登录，首次登录时自动创建用户
+func (s *AuthService) LoginWithProvider(ctx context.Context, providerName, username, password, ip, userAgent string) (*models.User, error) {
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	passwordProvider, ok := provider.(identity.PasswordProvider)
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+
+	attempt := &models.LoginAttempt{
+		Username:  username,
+		Provider:  providerName,
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+	defer s.recordLoginAttempt(attempt)
+
+	ident, err := passwordProvider.Authenticate(ctx, username, password)
+	if err != nil {
+		if err == identity.ErrInvalidCredentials {
+			attempt.Reason = models.LoginReasonWrongPassword
+			return nil, ErrInvalidCredentials
+		}
+		logger.ErrorLogger.Printf("身份源 %s 认证失败: %v", providerName, err)
+		attempt.Reason = models.LoginReasonProviderError
+		return nil, ErrProviderAuthFailed.Wrap(err)
+	}
+
+	return s.completeExternalLogin(ctx, provider, ident, attempt)
+}
+
+// OIDCAuthURL 返回 OIDC 身份源的授权跳转地址
+func (s *AuthService) OIDCAuthURL(providerName, state, nonce string) (string, error) {
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return "", ErrProviderNotFound
+	}
+	redirectProvider, ok := provider.(identity.RedirectProvider)
+	if !ok {
+		return "", ErrProviderNotFound
+	}
+	return redirectProvider.AuthCodeURL(state, nonce), nil
+}
+
+// CompleteOIDCLogin 处理 OIDC 回调，使用授权码换取身份并登录
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, providerName, code, nonce, ip, userAgent string) (*models.User, error) {
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	redirectProvider, ok := provider.(identity.RedirectProvider)
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+
+	attempt := &models.LoginAttempt{
+		Provider:  providerName,
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+	defer s.recordLoginAttempt(attempt)
+
+	ident, err := redirectProvider.Exchange(ctx, code, nonce)
+	if err != nil {
+		logger.ErrorLogger.Printf("身份源 %s 认证失败: %v", providerName, err)
+		attempt.Reason = models.LoginReasonProviderError
+		return nil, ErrProviderAuthFailed.Wrap(err)
+	}
+	attempt.Username = ident.Username
+
+	return s.completeExternalLogin(ctx, provider, ident, attempt)
+}
+
+// completeExternalLogin 映射角色并同步本地用户，返回登录用户
+func (s *AuthService) completeExternalLogin(ctx context.Context, provider identity.Provider, ident *identity.Identity, attempt *models.LoginAttempt) (*models.User, error) {
+	role, err := provider.MapRole(ident.Groups)
+	if err != nil {
+		attempt.Reason = models.LoginReasonNoRoleMapped
+		return nil, err
+	}
+
+	user, err := s.provisionUser(ctx, ident, role)
+	if err != nil {
+		attempt.Reason = models.LoginReasonProviderError
+		return nil, err
+	}
+	attempt.UserID = user.ID.Hex()
+
+	now := time.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		attempt.Reason = models.LoginReasonLocked
+		return nil, ErrAccountLocked
+	}
+
+	// 启用 MFA 的用户需完成第二步验证才算登录成功
+	if user.MFAEnabled {
+		attempt.Reason = models.LoginReasonMFAPending
+		return user, nil
+	}
+
+	if _, err := s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{"last_login_at": now},
+	}); err != nil {
+		return nil, err
+	}
+
+	attempt.Success = true
+	user.LastLoginAt = &now
+	return user, nil
+}
+
+// provisionUser 按身份源和外部ID查找用户，不存在时即时创建，存在时同步角色和权限
+func (s *AuthService) provisionUser(ctx context.Context, ident *identity.Identity, role models.Role) (*models.User, error) {
+	now := time.Now()
+	permissions := models.RolePermissions[role]
+
+	var user models.User
+	err := s.users.FindOne(ctx, bson.M{"provider": ident.Provider, "external_id": ident.Subject}).Decode(&user)
+	if err == nil {
+		if _, err := s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+			"$set": bson.M{
+				"role":        role,
+				"permissions": permissions,
+				"email":       ident.Email,
+				"updated_at":  now,
+			},
+		}); err != nil {
+			return nil, err
+		}
+		user.Role = role
+		user.Permissions = permissions
+		user.Email = ident.Email
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// 不自动关联同名的本地账户，避免外部身份接管本地管理员
+	count, err := s.users.CountDocuments(ctx, bson.M{"username": ident.Username})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrUsernameTaken
+	}
+
+	user = models.User{
+		ID:          primitive.NewObjectID(),
+		Username:    ident.Username,
+		Role:        role,
+		Permissions: permissions,
+		Email:       ident.Email,
+		Provider:    ident.Provider,
+		ExternalID:  ident.Subject,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := s.users.InsertOne(ctx, user); err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("通过身份源 %s 创建用户: %s, 角色: %s", ident.Provider, user.Username, role)
+	return &user, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"soundwave-go/internal/config"
+	"soundwave-go/internal/logger"
+	"soundwave-go/internal/models"
+	"soundwave-go/internal/registry"
+	"soundwave-go/pkg/routing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TrafficService 管理服务的流量路由规则。规则持久化在 MongoDB 中，并同步到注册中心供实例选择使用
+type TrafficService struct {
+	rules    *mongo.Collection
+	registry *registry.ServiceRegistry
+}
+
+func NewTrafficService(db *mongo.Database, cfg *config.Config, sr *registry.ServiceRegistry) *TrafficService {
+	return &TrafficService{
+		rules:    db.Collection(cfg.MongoDB.Collections.TrafficRules),
+		registry: sr,
+	}
+}
+
+// ListPolicies 获取命名空间内所有服务的路由规则
+func (s *TrafficService) ListPolicies(ctx context.Context, namespace string) ([]models.TrafficPolicy, error) {
+	cursor, err := s.rules.Find(ctx, bson.M{"namespace": namespace}, options.Find().SetSort(bson.M{"service": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	policies := make([]models.TrafficPolicy, 0)
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetPolicy 获取服务的路由规则，未配置时返回没有规则的策略
+func (s *TrafficService) GetPolicy(ctx context.Context, namespace, name string) (*models.TrafficPolicy, error) {
+	var policy models.TrafficPolicy
+	err := s.rules.FindOne(ctx, bson.M{"namespace": namespace, "service": name}).Decode(&policy)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &models.TrafficPolicy{Namespace: namespace, Service: name, Rules: []routing.TrafficRule{}}, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetPolicy 校验并保存服务的路由规则，规则为空时删除
+func (s *TrafficService) SetPolicy(ctx context.Context, namespace, name string, rules []routing.TrafficRule, updatedBy string) (*models.TrafficPolicy, error) {
+	compiled, err := routing.CompileTrafficRules(rules)
+	if err != nil {
+		return nil, registry.ErrInvalidTrafficRule.WithMessage(err.Error())
+	}
+	if compiled == nil {
+		if err := s.DeletePolicy(ctx, namespace, name); err != nil {
+			return nil, err
+		}
+		return &models.TrafficPolicy{Namespace: namespace, Service: name, Rules: []routing.TrafficRule{}}, nil
+	}
+
+	policy := &models.TrafficPolicy{
+		Namespace: namespace,
+		Service:   name,
+		Rules:     rules,
+		UpdatedAt: time.Now(),
+		UpdatedBy: updatedBy,
+	}
+	_, err = s.rules.ReplaceOne(ctx,
+		bson.M{"namespace": namespace, "service": name},
+		policy,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.registry.SetTrafficPolicy(namespace, name, compiled)
+	logger.InfoLogger.Printf("更新服务 %s/%s 的路由规则，共 %d 条，操作人：%s", namespace, name, len(rules), updatedBy)
+	return policy, nil
+}
+
+// DeletePolicy 删除服务的路由规则
+func (s *TrafficService) DeletePolicy(ctx context.Context, namespace, name string) error {
+	if _, err := s.rules.DeleteOne(ctx, bson.M{"namespace": namespace, "service": name}); err != nil {
+		return err
+	}
+	s.registry.SetTrafficPolicy(namespace, name, nil)
+	logger.InfoLogger.Printf("删除服务 %s/%s 的路由规则", namespace, name)
+	return nil
+}
+
+// Load 从 MongoDB 加载全部路由规则并替换注册中心中的规则，无法编译的规则会被跳过
+func (s *TrafficService) Load(ctx context.Context) error {
+	cursor, err := s.rules.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var stored []models.TrafficPolicy
+	if err := cursor.All(ctx, &stored); err != nil {
+		return err
+	}
+
+	policies := make(map[string]*routing.TrafficPolicy, len(stored))
+	for _, p := range stored {
+		compiled, err := routing.CompileTrafficRules(p.Rules)
+		if err != nil {
+			logger.WarnLogger.Printf("跳过服务 %s/%s 的路由规则: %v", p.Namespace, p.Service, err)
+			continue
+		}
+		if compiled != nil {
+			policies[p.Namespace+"/"+p.Service] = compiled
+		}
+	}
+	s.registry.ReplaceTrafficPolicies(policies)
+	return nil
+}
+
+// StartSync 定期从 MongoDB 重新加载路由规则，使多个注册中心节点的规则保持一致
+func (s *TrafficService) StartSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Load(ctx); err != nil {
+					logger.ErrorLogger.Printf("同步路由规则失败: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
-	"errors"
 	"soundwave-go/internal/models"
 	"time"
 
+	"soundwave-go/internal/config"
+	apperrors "soundwave-go/internal/errors"
 	"soundwave-go/internal/logger"
+	"soundwave-go/internal/registry"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,13 +17,22 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	// ErrInvalidUser 用户信息校验失败
+	ErrInvalidUser = apperrors.Validation("INVALID_USER", "无效的用户信息")
+	// ErrCannotDeleteAdmin 不允许删除管理员账户
+	ErrCannotDeleteAdmin = apperrors.Forbidden("CANNOT_DELETE_ADMIN", "不能删除管理员账户")
+)
+
 type UserService struct {
-	users *mongo.Collection
+	users  *mongo.Collection
+	policy *PasswordPolicy
 }
 
-func NewUserService(db *mongo.Database) *UserService {
+func NewUserService(db *mongo.Database, cfg *config.Config) *UserService {
 	return &UserService{
-		users: db.Collection("users"),
+		users:  db.Collection(cfg.MongoDB.Collections.Users),
+		policy: NewPasswordPolicy(cfg.Security.PasswordPolicy),
 	}
 }
 
@@ -55,7 +66,7 @@ func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
 		return err
 	}
 	if count > 0 {
-		return errors.New("用户名已存在")
+		return ErrUsernameExists
 	}
 
 	// 加密密码
@@ -64,11 +75,15 @@ func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
 		return err
 	}
 
+	now := time.Now()
 	user.Password = string(hashedPassword)
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	user.PasswordChangedAt = now
+	user.CreatedAt = now
+	user.UpdatedAt = now
 
-	_, err = s.users.InsertOne(ctx, user)
+	if _, err = s.users.InsertOne(ctx, user); mongo.IsDuplicateKeyError(err) {
+		return ErrUsernameExists
+	}
 	return err
 }
 
@@ -76,36 +91,40 @@ func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
 func (s *UserService) UpdateUser(ctx context.Context, id string, updates bson.M) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return ErrInvalidUserID
 	}
 
 	updates["updated_at"] = time.Now()
-	_, err = s.users.UpdateOne(
+	result, err := s.users.UpdateOne(
 		ctx,
 		bson.M{"_id": objectID},
 		bson.M{"$set": updates},
 	)
-	return err
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrUsernameExists
+		}
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
 
 // DeleteUser 删除用户
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	user, err := s.GetUserByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	// 不允许删除管理员账户
-	var user models.User
-	err = s.users.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
-	if err != nil {
-		return err
-	}
 	if user.Role == models.RoleAdmin {
-		return errors.New("不能删除管理员账户")
+		return ErrCannotDeleteAdmin
 	}
 
-	_, err = s.users.DeleteOne(ctx, bson.M{"_id": objectID})
+	_, err = s.users.DeleteOne(ctx, bson.M{"_id": user.ID})
 	return err
 }
 
@@ -113,12 +132,15 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 func (s *UserService) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidUserID
 	}
 
 	var user models.User
 	err = s.users.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
 		return nil, err
 	}
 
@@ -128,26 +150,47 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*models.User,
 // ValidateUserInput 验证用户输入
 func (s *UserService) ValidateUserInput(user *models.User) error {
 	if user.Username == "" {
-		return errors.New("用户名不能为空")
+		return ErrInvalidUser.WithMessage("用户名不能为空")
 	}
 	if len(user.Username) < 3 || len(user.Username) > 32 {
-		return errors.New("用户名长度必须在3-32个字符之间")
+		return ErrInvalidUser.WithMessage("用户名长度必须在3-32个字符之间")
+	}
+	if user.Password == "" {
+		return ErrInvalidUser.WithMessage("密码不能为空")
 	}
-	if user.Password != "" && (len(user.Password) < 6 || len(user.Password) > 32) {
-		return errors.New("密码长度必须在6-32个字符之间")
+	if err := s.policy.Validate(user.Password); err != nil {
+		return err
 	}
 	if user.Role == "" {
-		return errors.New("用户角色不能为空")
+		return ErrInvalidUser.WithMessage("用户角色不能为空")
 	}
 	if !isValidRole(user.Role) {
-		return errors.New("无效的用户角色")
+		return ErrInvalidRole
+	}
+	return validateNamespaces(user.Namespaces)
+}
+
+// validateNamespaces 校验用户可访问的命名空间名称
+func validateNamespaces(namespaces []string) error {
+	for _, namespace := range namespaces {
+		if namespace == "" {
+			return ErrInvalidUser.WithMessage("命名空间不能为空")
+		}
+		if _, err := registry.NormalizeNamespace(namespace); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// validRoles 系统支持的用户角色
+var validRoles = []models.Role{models.RoleAdmin, models.RoleUser, models.RoleTester}
+
+// ErrInvalidRole 无效的用户角色
+var ErrInvalidRole = apperrors.Validation("INVALID_ROLE", "无效的用户角色")
+
 // isValidRole 检查角色是否有效
 func isValidRole(role models.Role) bool {
-	validRoles := []models.Role{models.RoleAdmin, models.RoleUser, models.RoleTester}
 	for _, r := range validRoles {
 		if r == role {
 			return true
@@ -156,33 +199,116 @@ func isValidRole(role models.Role) bool {
 	return false
 }
 
-// UpdateUserPassword 更新用户密码
+// UpdateUserPassword 管理员重置用户密码，用户下次登录后必须修改密码
 func (s *UserService) UpdateUserPassword(ctx context.Context, id string, newPassword string) error {
-	if len(newPassword) < 6 || len(newPassword) > 32 {
-		return errors.New("密码长度必须在6-32个字符之间")
+	if err := s.policy.Validate(newPassword); err != nil {
+		return err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	user, err := s.GetUserByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if user.Provider != "" {
+		return ErrExternalAccount
+	}
 
-	objectID, err := primitive.ObjectIDFromHex(id)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
+	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"password":   string(hashedPassword),
-			"updated_at": time.Now(),
+			"password":             string(hashedPassword),
+			"password_history":     s.policy.NextHistory(user),
+			"password_changed_at":  now,
+			"must_change_password": true,
+			"updated_at":           now,
 		},
 	}
 
-	_, err = s.users.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	_, err = s.users.UpdateOne(ctx, bson.M{"_id": user.ID}, update)
 	return err
 }
 
+// UnlockUser 解除因登录失败导致的账户锁定
+func (s *UserService) UnlockUser(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	result, err := s.users.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set":   bson.M{"failed_attempts": 0, "updated_at": time.Now()},
+		"$unset": bson.M{"locked_until": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	logger.InfoLogger.Printf("用户 %s 已解锁", id)
+	return nil
+}
+
+// SetNamespaces 设置用户可访问的服务命名空间，为空时不限制。权限写在登录 token 中，
+// 用户重新登录后生效，已签发的 token 在过期前仍按原有权限访问
+func (s *UserService) SetNamespaces(ctx context.Context, id string, namespaces []string) error {
+	if err := validateNamespaces(namespaces); err != nil {
+		return err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	update := bson.M{"$set": bson.M{"namespaces": namespaces, "updated_at": time.Now()}}
+	if len(namespaces) == 0 {
+		update = bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"namespaces": ""},
+		}
+	}
+
+	result, err := s.users.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	logger.InfoLogger.Printf("用户 %s 的命名空间权限已更新: %v", id, namespaces)
+	return nil
+}
+
+// ResetMFA 管理员重置用户 MFA，用户需重新绑定
+func (s *UserService) ResetMFA(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	result, err := s.users.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set":   bson.M{"mfa_enabled": false, "updated_at": time.Now()},
+		"$unset": mfaUnsetFields,
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	logger.InfoLogger.Printf("用户 %s 的MFA已重置", id)
+	return nil
+}
+
 // GetUserByUsername 根据用户名获取用户
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	logger.InfoLogger.Printf("查询用户名: %s", username)
@@ -8,31 +8,90 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenPurposeMFA 登录第二步（MFA 验证）使用的临时 token
+const TokenPurposeMFA = "mfa"
+
 type Claims struct {
 	UserID      string              `json:"user_id"`
 	Username    string              `json:"username"`
 	Role        models.Role         `json:"role"`
 	Permissions []models.Permission `json:"permissions"`
+	// Namespaces 可访问的服务命名空间，为空时不限制。签发时从用户读取，撤销的权限在 token 过期前仍然有效
+	Namespaces []string `json:"namespaces,omitempty"`
+	// MustChangePassword 为 true 时该 token 只能用于修改密码
+	MustChangePassword bool `json:"must_change_password,omitempty"`
+	// MFASetupRequired 为 true 时该 token 只能用于绑定 MFA
+	MFASetupRequired bool `json:"mfa_setup_required,omitempty"`
+	// Purpose 非空表示该 token 不是登录会话 token
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func GenerateToken(cfg *config.Config, user *models.User) (string, error) {
-	claims := Claims{
-		UserID:      user.ID.Hex(),
-		Username:    user.Username,
-		Role:        user.Role,
-		Permissions: user.Permissions,
+	claims := newClaims(user, time.Duration(cfg.JWT.ExpireHours)*time.Hour)
+	return signClaims(cfg, claims)
+}
+
+// GenerateMFASetupToken 生成仅允许修改密码和绑定 MFA 的受限 token
+func GenerateMFASetupToken(cfg *config.Config, user *models.User) (string, error) {
+	claims := newClaims(user, time.Duration(cfg.JWT.ExpireHours)*time.Hour)
+	claims.MFASetupRequired = true
+	return signClaims(cfg, claims)
+}
+
+// GenerateMFAToken 生成密码验证通过、等待 MFA 验证的临时 token
+func GenerateMFAToken(cfg *config.Config, user *models.User, ttl time.Duration) (string, error) {
+	claims := newClaims(user, ttl)
+	claims.Permissions = nil
+	claims.Purpose = TokenPurposeMFA
+	return signClaims(cfg, claims)
+}
+
+func newClaims(user *models.User, ttl time.Duration) *Claims {
+	return &Claims{
+		UserID:             user.ID.Hex(),
+		Username:           user.Username,
+		Role:               user.Role,
+		Permissions:        user.Permissions,
+		Namespaces:         user.Namespaces,
+		MustChangePassword: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWT.ExpireHours) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+}
 
+func signClaims(cfg *config.Config, claims *Claims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(cfg.JWT.Secret))
 }
 
+// ParseToken 解析登录会话 token，拒绝 MFA 等特殊用途的 token
 func ParseToken(cfg *config.Config, tokenString string) (*Claims, error) {
+	claims, err := parseClaims(cfg, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// ParseMFAToken 解析登录第二步使用的 MFA token
+func ParseMFAToken(cfg *config.Config, tokenString string) (*Claims, error) {
+	claims, err := parseClaims(cfg, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != TokenPurposeMFA {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+func parseClaims(cfg *config.Config, tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(cfg.JWT.Secret), nil
 	})
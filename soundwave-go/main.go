@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
 	"soundwave-go/internal/config"
+	"soundwave-go/internal/db"
 	"soundwave-go/internal/logger"
 	"soundwave-go/internal/server"
 	"syscall"
@@ -12,6 +14,7 @@ import (
 
 var (
 	configPath = flag.String("config", "configs/config.yaml", "配置文件路径")
+	migrate    = flag.Bool("migrate", false, "执行数据库迁移后退出，不启动服务器")
 )
 
 func main() {
@@ -37,6 +40,11 @@ func main() {
 
 	logger.InfoLogger.Printf("当前配置: %+v", cfg)
 
+	if *migrate {
+		runMigrations(cfg)
+		return
+	}
+
 	// 初始化服务器
 	app := server.NewServer(cfg)
 
@@ -58,3 +66,16 @@ func main() {
 	// 执行清理操作
 	app.Shutdown()
 }
+
+// runMigrations 仅执行数据库迁移，用于发布前单独升级数据库
+func runMigrations(cfg *config.Config) {
+	mongodb, err := db.NewMongoDB(cfg.MongoDB.URI, cfg.MongoDB.Database)
+	if err != nil {
+		logger.ErrorLogger.Fatalf("连接MongoDB失败: %v", err)
+	}
+	defer mongodb.Close()
+
+	if err := db.Migrate(context.Background(), mongodb.Database(), cfg); err != nil {
+		logger.ErrorLogger.Fatalf("数据库迁移失败: %v", err)
+	}
+}
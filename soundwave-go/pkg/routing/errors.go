@@ -0,0 +1,35 @@
+// Package routing 实现注册中心和客户端共用的实例筛选规则：元数据选择器、语义化版本范围和路由规则。
+// 注册中心按这些规则筛选返回的实例，客户端使用同样的实现在本地按注册中心下发的规则选择实例
+package routing
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidSelector 元数据选择器语法错误
+	ErrInvalidSelector = errors.New("无效的元数据选择器")
+	// ErrInvalidVersionConstraint 版本范围语法错误
+	ErrInvalidVersionConstraint = errors.New("无效的版本范围")
+	// ErrInvalidTrafficRule 路由规则不合法
+	ErrInvalidTrafficRule = errors.New("无效的路由规则")
+)
+
+// invalidError 附带具体原因的校验错误，可用 errors.Is 判断错误类别
+type invalidError struct {
+	kind    error
+	message string
+}
+
+func (e *invalidError) Error() string {
+	return e.message
+}
+
+func (e *invalidError) Unwrap() error {
+	return e.kind
+}
+
+func invalidf(kind error, format string, args ...interface{}) error {
+	return &invalidError{kind: kind, message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,169 @@
+package routing
+
+import "strings"
+
+// SelectorOperator 元数据选择条件的运算符
+type SelectorOperator string
+
+const (
+	// SelectorEquals key=value 或 key==value
+	SelectorEquals SelectorOperator = "="
+	// SelectorNotEquals key!=value，不存在该键的实例也满足条件
+	SelectorNotEquals SelectorOperator = "!="
+	// SelectorIn key in (v1,v2)
+	SelectorIn SelectorOperator = "in"
+	// SelectorNotIn key notin (v1,v2)，不存在该键的实例也满足条件
+	SelectorNotIn SelectorOperator = "notin"
+	// SelectorExists key
+	SelectorExists SelectorOperator = "exists"
+	// SelectorDoesNotExist !key
+	SelectorDoesNotExist SelectorOperator = "!"
+)
+
+// Requirement 单个元数据选择条件
+type Requirement struct {
+	Key      string
+	Operator SelectorOperator
+	Values   []string
+}
+
+// Selector 元数据选择器，所有条件需同时满足
+type Selector []Requirement
+
+// ParseSelector 解析与 Kubernetes 标签选择器一致的语法，条件之间以逗号分隔，例如
+// env=prod,tier!=cache,region in (cn-east,cn-north),canary,!deprecated
+func ParseSelector(s string) (Selector, error) {
+	selector := make(Selector, 0)
+	for _, item := range splitSelector(s) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		requirement, err := parseRequirement(item)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, requirement)
+	}
+	return selector, nil
+}
+
+// splitSelector 按括号外的逗号拆分条件
+func splitSelector(s string) []string {
+	items := make([]string, 0)
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(items, s[start:])
+}
+
+func parseRequirement(item string) (Requirement, error) {
+	invalid := invalidf(ErrInvalidSelector, "无效的元数据选择条件: %s", item)
+
+	if strings.HasPrefix(item, "!") && !strings.Contains(item, "=") {
+		key := strings.TrimSpace(item[1:])
+		if key == "" {
+			return Requirement{}, invalid
+		}
+		return Requirement{Key: key, Operator: SelectorDoesNotExist}, nil
+	}
+
+	if key, values, ok := cutSetOperator(item, " notin "); ok {
+		return newSetRequirement(key, SelectorNotIn, values, invalid)
+	}
+	if key, values, ok := cutSetOperator(item, " in "); ok {
+		return newSetRequirement(key, SelectorIn, values, invalid)
+	}
+
+	for _, op := range []struct {
+		token    string
+		operator SelectorOperator
+	}{
+		{"!=", SelectorNotEquals},
+		{"==", SelectorEquals},
+		{"=", SelectorEquals},
+	} {
+		if key, value, ok := strings.Cut(item, op.token); ok {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				return Requirement{}, invalid
+			}
+			return Requirement{Key: key, Operator: op.operator, Values: []string{strings.TrimSpace(value)}}, nil
+		}
+	}
+
+	if strings.ContainsAny(item, " ()") {
+		return Requirement{}, invalid
+	}
+	return Requirement{Key: item, Operator: SelectorExists}, nil
+}
+
+func cutSetOperator(item, token string) (string, string, bool) {
+	key, values, ok := strings.Cut(item, token)
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(values), true
+}
+
+func newSetRequirement(key string, operator SelectorOperator, values string, invalid error) (Requirement, error) {
+	if key == "" || !strings.HasPrefix(values, "(") || !strings.HasSuffix(values, ")") {
+		return Requirement{}, invalid
+	}
+	list := make([]string, 0)
+	for _, value := range strings.Split(values[1:len(values)-1], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	if len(list) == 0 {
+		return Requirement{}, invalid
+	}
+	return Requirement{Key: key, Operator: operator, Values: list}, nil
+}
+
+// Matches 判断元数据是否满足条件
+func (r Requirement) Matches(metadata map[string]string) bool {
+	value, exists := metadata[r.Key]
+	switch r.Operator {
+	case SelectorEquals, SelectorIn:
+		return exists && containsString(r.Values, value)
+	case SelectorNotEquals, SelectorNotIn:
+		return !exists || !containsString(r.Values, value)
+	case SelectorExists:
+		return exists
+	case SelectorDoesNotExist:
+		return !exists
+	}
+	return false
+}
+
+// Matches 判断元数据是否满足全部条件
+func (s Selector) Matches(metadata map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
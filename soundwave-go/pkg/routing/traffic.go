@@ -0,0 +1,212 @@
+package routing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strings"
+)
+
+// TrafficRule 单条路由规则。规则按顺序匹配，命中第一条后按权重选择目标实例组
+type TrafficRule struct {
+	Name         string               `bson:"name" json:"name"`
+	Match        *TrafficMatch        `bson:"match,omitempty" json:"match,omitempty"`
+	Destinations []TrafficDestination `bson:"destinations" json:"destinations"`
+}
+
+// TrafficMatch 规则的匹配条件，为空时匹配所有请求
+type TrafficMatch struct {
+	// Headers 请求头需全部相等，键不区分大小写
+	Headers map[string]string `bson:"headers,omitempty" json:"headers,omitempty"`
+	// Keys 路由键（如调用方服务名或用户ID）命中其中之一
+	Keys []string `bson:"keys,omitempty" json:"keys,omitempty"`
+}
+
+// TrafficDestination 目标实例组，Version 为语义化版本范围，Selector 为元数据选择器，均为空时表示所有实例
+type TrafficDestination struct {
+	Version  string `bson:"version,omitempty" json:"version,omitempty"`
+	Selector string `bson:"selector,omitempty" json:"selector,omitempty"`
+	Weight   int    `bson:"weight" json:"weight"`
+}
+
+// RouteContext 匹配路由规则使用的请求信息
+type RouteContext struct {
+	// Key 路由键，如调用方服务名或用户ID。指定时同一路由键总是落到同一目标实例组
+	Key string
+	// Headers 请求头
+	Headers http.Header
+}
+
+// TrafficPolicy 编译后的服务路由规则
+type TrafficPolicy struct {
+	// Rules 原始规则，用于下发给客户端
+	Rules []TrafficRule
+	rules []trafficRule
+}
+
+type trafficRule struct {
+	name         string
+	headers      map[string]string // 规范化后的请求头 -> 值
+	keys         []string
+	destinations []trafficDestination
+	totalWeight  int
+}
+
+type trafficDestination struct {
+	label    string
+	version  *VersionConstraint
+	selector Selector
+	weight   int
+}
+
+// TrafficRoute 请求命中的路由规则和目标实例组
+type TrafficRoute struct {
+	Rule        string `json:"rule"`
+	Destination string `json:"destination"`
+	// Fallback 目标实例组没有可用实例，退回到所有实例
+	Fallback bool `json:"fallback,omitempty"`
+
+	destination *trafficDestination
+}
+
+// CompileTrafficRules 校验并编译路由规则，没有规则时返回 nil
+func CompileTrafficRules(rules []TrafficRule) (*TrafficPolicy, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	policy := &TrafficPolicy{Rules: rules, rules: make([]trafficRule, 0, len(rules))}
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if names[name] {
+			return nil, invalidf(ErrInvalidTrafficRule, "路由规则名称重复: %s", name)
+		}
+		names[name] = true
+
+		if len(rule.Destinations) == 0 {
+			return nil, invalidf(ErrInvalidTrafficRule, "路由规则 %s 没有目标实例组", name)
+		}
+
+		compiled := trafficRule{name: name}
+		if rule.Match != nil {
+			if len(rule.Match.Headers) > 0 {
+				compiled.headers = make(map[string]string, len(rule.Match.Headers))
+				for key, value := range rule.Match.Headers {
+					compiled.headers[http.CanonicalHeaderKey(key)] = value
+				}
+			}
+			compiled.keys = rule.Match.Keys
+		}
+
+		for _, destination := range rule.Destinations {
+			if destination.Weight < 0 {
+				return nil, invalidf(ErrInvalidTrafficRule, "路由规则 %s 的权重不能为负数", name)
+			}
+			d := trafficDestination{label: destinationLabel(destination), weight: destination.Weight}
+			if destination.Version != "" {
+				constraint, err := ParseVersionConstraint(destination.Version)
+				if err != nil {
+					return nil, invalidf(ErrInvalidTrafficRule, "路由规则 %s 的版本范围无效: %s", name, destination.Version)
+				}
+				d.version = constraint
+			}
+			if destination.Selector != "" {
+				selector, err := ParseSelector(destination.Selector)
+				if err != nil {
+					return nil, invalidf(ErrInvalidTrafficRule, "路由规则 %s 的元数据选择器无效: %s", name, destination.Selector)
+				}
+				d.selector = selector
+			}
+			compiled.destinations = append(compiled.destinations, d)
+			compiled.totalWeight += destination.Weight
+		}
+		if compiled.totalWeight == 0 {
+			return nil, invalidf(ErrInvalidTrafficRule, "路由规则 %s 的权重之和必须大于0", name)
+		}
+
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy, nil
+}
+
+// destinationLabel 目标实例组的描述，如 version=^2.1.0,selector=canary
+func destinationLabel(destination TrafficDestination) string {
+	parts := make([]string, 0, 2)
+	if destination.Version != "" {
+		parts = append(parts, "version="+destination.Version)
+	}
+	if destination.Selector != "" {
+		parts = append(parts, "selector="+destination.Selector)
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, ",")
+}
+
+// Route 按顺序匹配规则并按权重选择目标实例组，没有命中任何规则时返回 nil
+func (p *TrafficPolicy) Route(route RouteContext) *TrafficRoute {
+	if p == nil {
+		return nil
+	}
+	for i := range p.rules {
+		rule := &p.rules[i]
+		if !rule.matches(route) {
+			continue
+		}
+		destination := rule.pick(route.Key)
+		return &TrafficRoute{
+			Rule:        rule.name,
+			Destination: destination.label,
+			destination: destination,
+		}
+	}
+	return nil
+}
+
+func (r *trafficRule) matches(route RouteContext) bool {
+	for key, value := range r.headers {
+		if route.Headers.Get(key) != value {
+			return false
+		}
+	}
+	if len(r.keys) > 0 && (route.Key == "" || !containsString(r.keys, route.Key)) {
+		return false
+	}
+	return true
+}
+
+// pick 按权重选择目标实例组，指定路由键时按其哈希选择，保证同一调用方的请求落到同一版本
+func (r *trafficRule) pick(key string) *trafficDestination {
+	var n int
+	if key != "" {
+		h := fnv.New32a()
+		h.Write([]byte(r.name + "/" + key))
+		n = int(h.Sum32() % uint32(r.totalWeight))
+	} else {
+		n = rand.Intn(r.totalWeight)
+	}
+	for i := range r.destinations {
+		if n < r.destinations[i].weight {
+			return &r.destinations[i]
+		}
+		n -= r.destinations[i].weight
+	}
+	return &r.destinations[len(r.destinations)-1]
+}
+
+// Matches 判断实例是否属于目标实例组
+func (r *TrafficRoute) Matches(version string, metadata map[string]string) bool {
+	if r == nil || r.destination == nil {
+		return true
+	}
+	if r.destination.version != nil && !r.destination.version.Matches(version) {
+		return false
+	}
+	return r.destination.selector.Matches(metadata)
+}
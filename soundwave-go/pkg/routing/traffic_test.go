@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCompileTrafficRulesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []TrafficRule
+		wantMsg string
+	}{
+		{
+			name:    "名称重复",
+			rules:   []TrafficRule{{Name: "a", Destinations: []TrafficDestination{{Weight: 1}}}, {Name: "a", Destinations: []TrafficDestination{{Weight: 1}}}},
+			wantMsg: "路由规则名称重复: a",
+		},
+		{
+			name:    "没有目标实例组",
+			rules:   []TrafficRule{{Name: "a"}},
+			wantMsg: "路由规则 a 没有目标实例组",
+		},
+		{
+			name:    "版本范围无效",
+			rules:   []TrafficRule{{Destinations: []TrafficDestination{{Version: ">>1", Weight: 1}}}},
+			wantMsg: "路由规则 rule-1 的版本范围无效: >>1",
+		},
+		{
+			name:    "权重之和为0",
+			rules:   []TrafficRule{{Name: "a", Destinations: []TrafficDestination{{Version: "1.x"}}}},
+			wantMsg: "路由规则 a 的权重之和必须大于0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileTrafficRules(tt.rules)
+			if !errors.Is(err, ErrInvalidTrafficRule) {
+				t.Fatalf("错误 = %v, 期望 %v", err, ErrInvalidTrafficRule)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("错误信息 = %q, 期望 %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+
+	if _, err := ParseSelector("region in cn"); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("ParseSelector 错误 = %v, 期望 %v", err, ErrInvalidSelector)
+	}
+	if _, err := ParseVersionConstraint("1.0 ||"); !errors.Is(err, ErrInvalidVersionConstraint) {
+		t.Errorf("ParseVersionConstraint 错误 = %v, 期望 %v", err, ErrInvalidVersionConstraint)
+	}
+}
+
+func TestTrafficPolicyRoute(t *testing.T) {
+	policy, err := CompileTrafficRules([]TrafficRule{
+		{
+			Name:         "canary",
+			Match:        &TrafficMatch{Headers: map[string]string{"x-canary": "true"}},
+			Destinations: []TrafficDestination{{Version: "^2.0.0", Selector: "track=canary", Weight: 1}},
+		},
+		{
+			Name:         "default",
+			Destinations: []TrafficDestination{{Version: "1.x", Weight: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("编译失败: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		headers  http.Header
+		wantRule string
+		version  string
+		metadata map[string]string
+		want     bool
+	}{
+		{name: "命中灰度规则", headers: http.Header{"X-Canary": {"true"}}, wantRule: "canary", version: "2.1.0", metadata: map[string]string{"track": "canary"}, want: true},
+		{name: "灰度规则要求元数据", headers: http.Header{"X-Canary": {"true"}}, wantRule: "canary", version: "2.1.0", want: false},
+		{name: "默认规则", wantRule: "default", version: "1.4.2", want: true},
+		{name: "默认规则不匹配新版本", wantRule: "default", version: "2.0.0", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := policy.Route(RouteContext{Headers: tt.headers})
+			if route == nil || route.Rule != tt.wantRule {
+				t.Fatalf("Route = %+v, 期望命中 %s", route, tt.wantRule)
+			}
+			if got := route.Matches(tt.version, tt.metadata); got != tt.want {
+				t.Errorf("Matches(%s, %v) = %v, 期望 %v", tt.version, tt.metadata, got, tt.want)
+			}
+		})
+	}
+
+	var empty *TrafficPolicy
+	if route := empty.Route(RouteContext{}); route != nil {
+		t.Errorf("没有规则时 Route = %+v, 期望 nil", route)
+	}
+}
@@ -0,0 +1,239 @@
+package routing
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver 语义化版本号，忽略构建元数据
+type semver struct {
+	major, minor, patch int
+	prerelease          string
+}
+
+// parseSemver 解析 1.2.3、v1.2.3、1.2.3-beta.1 形式的版本号，缺省的次版本号和修订号视为 0
+func parseSemver(s string) (semver, bool) {
+	v, _, ok := parseVersionPrefix(s)
+	return v, ok
+}
+
+// parseVersionPrefix 解析版本号，同时返回显式给出的数字段数，x 和 * 视为未给出
+func parseVersionPrefix(s string) (semver, int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if s == "" {
+		return semver{}, 0, false
+	}
+	s, _, _ = strings.Cut(s, "+")
+	s, prerelease, _ := strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return semver{}, 0, false
+	}
+	numbers := make([]int, 3)
+	given := 0
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semver{}, 0, false
+		}
+		numbers[i] = n
+		given++
+	}
+	return semver{major: numbers[0], minor: numbers[1], patch: numbers[2], prerelease: prerelease}, given, true
+}
+
+// compare 按语义化版本规则比较，预发布版本低于对应的正式版本
+func (v semver) compare(o semver) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			if d < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.prerelease == o.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case o.prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.prerelease, o.prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// versionBound 版本范围的一个边界
+type versionBound struct {
+	operator string
+	version  semver
+}
+
+func (b versionBound) matches(v semver) bool {
+	c := v.compare(b.version)
+	switch b.operator {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case "!=":
+		return c != 0
+	}
+	return c == 0
+}
+
+// VersionConstraint 语义化版本范围，由 || 分隔的多组条件组成，满足任意一组即可，
+// 每组内以空格或逗号分隔的条件需同时满足。支持的写法：
+//   - 比较：=1.2.3、!=1.2.3、>1.2、>=1.2.0、<2、<=1.9.9
+//   - 通配：1.x、1.2.*、*
+//   - 波浪号：~1.2.3 等价于 >=1.2.3 <1.3.0
+//   - 插入号：^1.2.3 等价于 >=1.2.3 <2.0.0，^0.2.3 等价于 >=0.2.3 <0.3.0
+type VersionConstraint struct {
+	raw    string
+	groups [][]versionBound
+}
+
+// ParseVersionConstraint 解析版本范围
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	constraint := &VersionConstraint{raw: s}
+	groups := strings.Split(s, "||")
+	for _, group := range groups {
+		if len(groups) > 1 && strings.TrimSpace(group) == "" {
+			return nil, invalidf(ErrInvalidVersionConstraint, "无效的版本范围: %s", s)
+		}
+		bounds := make([]versionBound, 0)
+		for _, term := range strings.FieldsFunc(group, func(r rune) bool { return r == ' ' || r == ',' }) {
+			expanded, ok := expandVersionTerm(term)
+			if !ok {
+				return nil, invalidf(ErrInvalidVersionConstraint, "无效的版本范围: %s", s)
+			}
+			bounds = append(bounds, expanded...)
+		}
+		constraint.groups = append(constraint.groups, bounds)
+	}
+	return constraint, nil
+}
+
+// expandVersionTerm 将单个条件展开为边界
+func expandVersionTerm(term string) ([]versionBound, bool) {
+	if term == "*" || term == "x" || term == "X" {
+		return nil, true
+	}
+
+	operator := ""
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(term, op) {
+			operator = op
+			term = term[len(op):]
+			break
+		}
+	}
+	version, given, ok := parseVersionPrefix(term)
+	if !ok {
+		return nil, false
+	}
+
+	switch operator {
+	case "~":
+		if given == 0 {
+			return nil, true
+		}
+		upper := semver{major: version.major + 1}
+		if given >= 2 {
+			upper = semver{major: version.major, minor: version.minor + 1}
+		}
+		return []versionBound{{">=", version}, {"<", upper}}, true
+	case "^":
+		if given == 0 {
+			return nil, true
+		}
+		var upper semver
+		switch {
+		case version.major > 0 || given == 1:
+			upper = semver{major: version.major + 1}
+		case version.minor > 0 || given == 2:
+			upper = semver{minor: version.minor + 1}
+		default:
+			upper = semver{patch: version.patch + 1}
+		}
+		return []versionBound{{">=", version}, {"<", upper}}, true
+	case "", "=":
+		// 省略的数字段表示通配，如 1.2 匹配 1.2.x
+		switch given {
+		case 0:
+			return nil, true
+		case 1:
+			return []versionBound{{">=", version}, {"<", semver{major: version.major + 1}}}, true
+		case 2:
+			return []versionBound{{">=", version}, {"<", semver{major: version.major, minor: version.minor + 1}}}, true
+		}
+		return []versionBound{{"=", version}}, true
+	}
+	return []versionBound{{operator, version}}, true
+}
+
+// Matches 判断版本号是否在范围内，无法解析的版本号不满足任何范围
+func (c *VersionConstraint) Matches(version string) bool {
+	v, ok := parseSemver(version)
+	if !ok {
+		return false
+	}
+	for _, group := range c.groups {
+		matched := true
+		for _, bound := range group {
+			if !bound.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// String 返回原始的版本范围
+func (c *VersionConstraint) String() string {
+	return c.raw
+}
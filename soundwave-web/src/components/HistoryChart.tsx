@@ -0,0 +1,88 @@
+import React from 'react';
+import { Empty, Space, Typography } from 'antd';
+
+const { Text } = Typography;
+
+export interface ChartSeries<T> {
+  name: string;
+  color: string;
+  value: (point: T) => number;
+}
+
+interface HistoryChartProps<T extends { time: string }> {
+  points: T[];
+  series: ChartSeries<T>[];
+  height?: number;
+  // 纵轴刻度的格式化
+  format?: (value: number) => string;
+}
+
+const width = 880;
+const padding = { top: 8, right: 8, bottom: 20, left: 48 };
+
+// 简单的折线图，横轴为时间，用于展示服务的历史数据
+function HistoryChart<T extends { time: string }>({ points, series, height = 160, format = String }: HistoryChartProps<T>) {
+  if (points.length === 0) {
+    return <Empty image={Empty.PRESENTED_IMAGE_SIMPLE} description="暂无数据" />;
+  }
+
+  const times = points.map((point) => new Date(point.time).getTime());
+  const minTime = times[0];
+  const maxTime = Math.max(times[times.length - 1], minTime + 1);
+  const maxValue = Math.max(1, ...points.flatMap((point) => series.map((s) => s.value(point))));
+  const innerWidth = width - padding.left - padding.right;
+  const innerHeight = height - padding.top - padding.bottom;
+  const x = (time: number) => padding.left + ((time - minTime) / (maxTime - minTime)) * innerWidth;
+  const y = (value: number) => padding.top + innerHeight - (value / maxValue) * innerHeight;
+
+  return (
+    <div>
+      <Space size="large">
+        {series.map((s) => (
+          <Text key={s.name} style={{ color: s.color }}>
+            ● {s.name}
+          </Text>
+        ))}
+      </Space>
+      <svg viewBox={`0 0 ${width} ${height}`} style={{ width: '100%', height }}>
+        {[0, 0.5, 1].map((ratio) => (
+          <g key={ratio}>
+            <line
+              x1={padding.left}
+              x2={width - padding.right}
+              y1={y(maxValue * ratio)}
+              y2={y(maxValue * ratio)}
+              stroke="#f0f0f0"
+            />
+            <text x={padding.left - 6} y={y(maxValue * ratio) + 4} fontSize={11} textAnchor="end" fill="#8c8c8c">
+              {format(maxValue * ratio)}
+            </text>
+          </g>
+        ))}
+        {[minTime, maxTime].map((time, index) => (
+          <text
+            key={time}
+            x={x(time)}
+            y={height - 4}
+            fontSize={11}
+            textAnchor={index === 0 ? 'start' : 'end'}
+            fill="#8c8c8c"
+          >
+            {new Date(time).toLocaleString()}
+          </text>
+        ))}
+        {series.map((s) => (
+          <polyline
+            key={s.name}
+            fill="none"
+            stroke={s.color}
+            strokeWidth={1.5}
+            points={points.map((point, i) => `${x(times[i])},${y(s.value(point))}`).join(' ')}
+          />
+        ))}
+      </svg>
+    </div>
+  );
+}
+
+export default HistoryChart;
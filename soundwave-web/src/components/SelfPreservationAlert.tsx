@@ -0,0 +1,41 @@
+import React from 'react';
+import { Alert } from 'antd';
+
+export interface SelfPreservationStatus {
+  enabled: boolean;
+  active: boolean;
+  since?: string;
+  threshold: number;
+  expected_renewals: number;
+  actual_renewals: number;
+  renewal_rate: number;
+  skipped_evictions: number;
+}
+
+interface SelfPreservationAlertProps {
+  status?: SelfPreservationStatus;
+}
+
+const SelfPreservationAlert: React.FC<SelfPreservationAlertProps> = ({ status }) => {
+  if (!status?.active) {
+    return null;
+  }
+
+  const since = status.since ? new Date(status.since).toLocaleString() : '-';
+  return (
+    <Alert
+      type="warning"
+      showIcon
+      style={{ marginBottom: 16 }}
+      message="注册中心处于自我保护模式"
+      description={
+        `自 ${since} 起心跳续约率为 ${(status.renewal_rate * 100).toFixed(1)}%` +
+        `（${status.actual_renewals}/${Math.round(status.expected_renewals)}），` +
+        `低于阈值 ${(status.threshold * 100).toFixed(0)}%，暂停剔除心跳超时的实例，` +
+        `已保留 ${status.skipped_evictions} 个实例。续约率恢复后自动退出。`
+      }
+    />
+  );
+};
+
+export default SelfPreservationAlert;
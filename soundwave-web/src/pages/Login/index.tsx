@@ -1,25 +1,62 @@
 import React from 'react';
-import { Form, Input, Button, Card, message } from 'antd';
-import { UserOutlined, LockOutlined, CloudServerOutlined } from '@ant-design/icons';
+import { Form, Input, Button, Card, Select, Divider, Space } from 'antd';
+import { UserOutlined, LockOutlined, CloudServerOutlined, SafetyOutlined } from '@ant-design/icons';
 import { useNavigate } from 'react-router-dom';
 import { useUserStore } from '../../store/userStore';
+import axios from '../../utils/axios';
 import styles from './Login.module.css';
 
 interface LoginForm {
   username: string;
   password: string;
+  provider?: string;
+  code?: string;
+}
+
+interface AuthProvider {
+  name: string;
+  display_name: string;
+  type: 'oidc' | 'ldap';
 }
 
 const Login: React.FC = () => {
   const navigate = useNavigate();
   const login = useUserStore((state) => state.login);
+  const verifyMFA = useUserStore((state) => state.verifyMFA);
+  const mfaToken = useUserStore((state) => state.mfaToken);
+  const completeRedirectLogin = useUserStore((state) => state.completeRedirectLogin);
   const [loading, setLoading] = React.useState(false);
+  const [providers, setProviders] = React.useState<AuthProvider[]>([]);
+
+  React.useEffect(() => {
+    axios.get<{ providers: AuthProvider[] }>('/auth/providers')
+      .then((response) => setProviders(response.data.providers ?? []))
+      .catch(() => setProviders([]));
+
+    // 处理 OIDC 登录回调
+    if (window.location.hash.length > 1) {
+      completeRedirectLogin(new URLSearchParams(window.location.hash.slice(1)));
+      window.history.replaceState(null, '', window.location.pathname);
+      if (useUserStore.getState().token) {
+        navigate('/');
+      }
+    }
+  }, [completeRedirectLogin, navigate]);
+
+  const passwordProviders = providers.filter((p) => p.type === 'ldap');
+  const redirectProviders = providers.filter((p) => p.type === 'oidc');
 
   const onFinish = async (values: LoginForm) => {
     setLoading(true);
     try {
-      await login(values.username, values.password);
-      navigate('/');
+      if (mfaToken) {
+        await verifyMFA(values.code ?? '');
+      } else {
+        await login(values.username, values.password, values.provider);
+      }
+      if (useUserStore.getState().token) {
+        navigate('/');
+      }
     } catch (error) {
       // 错误处理已在 store 中完成
     } finally {
@@ -42,29 +79,59 @@ const Login: React.FC = () => {
           className={styles.form}
           size="large"
         >
-          <Form.Item
-            name="username"
-            rules={[{ required: true, message: '请输入用户名' }]}
-          >
-            <Input
-              prefix={<UserOutlined style={{ color: '#1890ff' }} />}
-              placeholder="用户名"
-              className={styles.input}
-              disabled={loading}
-            />
-          </Form.Item>
+          {!mfaToken && (
+            <>
+              <Form.Item
+                name="username"
+                rules={[{ required: true, message: '请输入用户名' }]}
+              >
+                <Input
+                  prefix={<UserOutlined style={{ color: '#1890ff' }} />}
+                  placeholder="用户名"
+                  className={styles.input}
+                  disabled={loading}
+                />
+              </Form.Item>
 
-          <Form.Item
-            name="password"
-            rules={[{ required: true, message: '请输入密码' }]}
-          >
-            <Input.Password
-              prefix={<LockOutlined style={{ color: '#1890ff' }} />}
-              placeholder="密码"
-              className={styles.input}
-              disabled={loading}
-            />
-          </Form.Item>
+              <Form.Item
+                name="password"
+                rules={[{ required: true, message: '请输入密码' }]}
+              >
+                <Input.Password
+                  prefix={<LockOutlined style={{ color: '#1890ff' }} />}
+                  placeholder="密码"
+                  className={styles.input}
+                  disabled={loading}
+                />
+              </Form.Item>
+            </>
+          )}
+
+          {passwordProviders.length > 0 && !mfaToken && (
+            <Form.Item name="provider" initialValue="local">
+              <Select disabled={loading}>
+                <Select.Option value="local">本地账户</Select.Option>
+                {passwordProviders.map((p) => (
+                  <Select.Option key={p.name} value={p.name}>{p.display_name}</Select.Option>
+                ))}
+              </Select>
+            </Form.Item>
+          )}
+
+          {mfaToken && (
+            <Form.Item
+              name="code"
+              rules={[{ required: true, message: '请输入验证码或恢复码' }]}
+            >
+              <Input
+                prefix={<SafetyOutlined style={{ color: '#1890ff' }} />}
+                placeholder="双因素验证码或恢复码"
+                className={styles.input}
+                disabled={loading}
+                autoFocus
+              />
+            </Form.Item>
+          )}
 
           <Form.Item>
             <Button
@@ -78,6 +145,22 @@ const Login: React.FC = () => {
             </Button>
           </Form.Item>
         </Form>
+        {redirectProviders.length > 0 && !mfaToken && (
+          <>
+            <Divider plain>其他登录方式</Divider>
+            <Space direction="vertical" style={{ width: '100%' }}>
+              {redirectProviders.map((p) => (
+                <Button
+                  key={p.name}
+                  block
+                  href={`${axios.defaults.baseURL}/auth/oidc/${p.name}/login`}
+                >
+                  {p.display_name}
+                </Button>
+              ))}
+            </Space>
+          </>
+        )}
         <div className={styles.footer}>
           <p>© 2024 Soundwave. All rights reserved.</p>
           <p>
@@ -0,0 +1,229 @@
+import React, { useState } from 'react';
+import { Drawer, Table, Tag, Select, Space, Progress, Typography } from 'antd';
+import type { ColumnsType } from 'antd/es/table';
+import { useQuery } from '@tanstack/react-query';
+import axios from '../../utils/axios';
+import HistoryChart, { type ChartSeries } from '../../components/HistoryChart';
+
+const { Text } = Typography;
+
+interface ServiceEvent {
+  id: string;
+  instance_id: string;
+  hostname: string;
+  address: string;
+  type: string;
+  status: string;
+  version?: string;
+  detail?: string;
+  time: string;
+}
+
+interface InstanceAvailability {
+  instance_id: string;
+  hostname: string;
+  address: string;
+  uptime: number; // 纳秒
+  downtime: number; // 纳秒
+  availability: number;
+  flaps: number;
+  last_event: string;
+  last_event_time: string;
+}
+
+interface HistoryPoint {
+  time: string;
+  healthy: number;
+  unhealthy: number;
+  min_healthy: number;
+  max_unhealthy: number;
+  heartbeats: number;
+  lateness_avg: number; // 秒
+  lateness_max: number; // 秒
+  registrations: number;
+  deregistrations: number;
+  expirations: number;
+}
+
+const historyRanges = [
+  { label: '最近1小时', value: 1 },
+  { label: '最近6小时', value: 6 },
+  { label: '最近24小时', value: 24 },
+  { label: '最近7天', value: 24 * 7 },
+];
+
+const instanceSeries: ChartSeries<HistoryPoint>[] = [
+  { name: '健康实例', color: '#52c41a', value: (point) => point.healthy },
+  { name: '异常实例', color: '#ff4d4f', value: (point) => point.unhealthy },
+];
+
+const latenessSeries: ChartSeries<HistoryPoint>[] = [
+  { name: '平均心跳延迟', color: '#1677ff', value: (point) => point.lateness_avg },
+  { name: '最大心跳延迟', color: '#faad14', value: (point) => point.lateness_max },
+];
+
+const churnSeries: ChartSeries<HistoryPoint>[] = [
+  { name: '注册', color: '#52c41a', value: (point) => point.registrations },
+  { name: '注销', color: '#8c8c8c', value: (point) => point.deregistrations },
+  { name: '过期剔除', color: '#ff4d4f', value: (point) => point.expirations },
+];
+
+interface EventsDrawerProps {
+  namespace: string;
+  service?: string;
+  onClose: () => void;
+}
+
+const eventColors: Record<string, string> = {
+  REGISTERED: 'success',
+  DEREGISTERED: 'default',
+  EXPIRED: 'error',
+  HEARTBEAT_LOST: 'error',
+  HEARTBEAT_RECOVERED: 'success',
+  EJECTED: 'warning',
+  RESTORED: 'success',
+  STATUS_CHANGED: 'processing',
+  METADATA_CHANGED: 'blue',
+  VERSION_CHANGED: 'purple',
+  WEIGHT_CHANGED: 'cyan',
+};
+
+const formatDuration = (ns: number) => {
+  const minutes = Math.floor(ns / 6e10);
+  const hours = Math.floor(minutes / 60);
+  return hours > 0 ? `${hours}小时${minutes % 60}分钟` : `${minutes}分钟`;
+};
+
+const EventsDrawer: React.FC<EventsDrawerProps> = ({ namespace, service, onClose }) => {
+  const [page, setPage] = useState(1);
+  const [pageSize, setPageSize] = useState(20);
+  const [types, setTypes] = useState<string[]>([]);
+  const [historyHours, setHistoryHours] = useState(1);
+
+  const { data: events, isLoading: eventsLoading } = useQuery({
+    queryKey: ['serviceEvents', namespace, service, page, pageSize, types],
+    enabled: !!service,
+    queryFn: async () => {
+      const response = await axios.get<{ events: ServiceEvent[]; total: number }>(
+        `/api/services/${encodeURIComponent(service!)}/events`,
+        { params: { namespace, page, page_size: pageSize, type: types.join(',') || undefined } },
+      );
+      return response.data;
+    },
+  });
+
+  const { data: availability, isLoading: availabilityLoading } = useQuery({
+    queryKey: ['serviceAvailability', namespace, service],
+    enabled: !!service,
+    queryFn: async () => {
+      const response = await axios.get<{ instances: InstanceAvailability[] }>(
+        `/api/services/${encodeURIComponent(service!)}/availability`,
+        { params: { namespace } },
+      );
+      return response.data.instances ?? [];
+    },
+  });
+
+  const { data: history } = useQuery({
+    queryKey: ['serviceHistory', namespace, service, historyHours],
+    enabled: !!service,
+    queryFn: async () => {
+      const since = new Date(Date.now() - historyHours * 3600 * 1000).toISOString();
+      const response = await axios.get<{ points: HistoryPoint[] }>(
+        `/api/services/${encodeURIComponent(service!)}/history`,
+        { params: { namespace, since } },
+      );
+      return response.data.points ?? [];
+    },
+  });
+
+  const availabilityColumns: ColumnsType<InstanceAvailability> = [
+    { title: '实例ID', dataIndex: 'instance_id', key: 'instance_id', ellipsis: true },
+    { title: '地址', dataIndex: 'address', key: 'address' },
+    {
+      title: '可用性',
+      dataIndex: 'availability',
+      key: 'availability',
+      width: 180,
+      render: (value: number) => (
+        <Progress
+          percent={Number((value * 100).toFixed(2))}
+          size="small"
+          status={value < 0.99 ? 'exception' : 'success'}
+        />
+      ),
+    },
+    { title: '不可用时长', dataIndex: 'downtime', key: 'downtime', render: formatDuration },
+    { title: '抖动次数', dataIndex: 'flaps', key: 'flaps' },
+  ];
+
+  const eventColumns: ColumnsType<ServiceEvent> = [
+    { title: '时间', dataIndex: 'time', key: 'time', width: 180, render: (time: string) => new Date(time).toLocaleString() },
+    {
+      title: '事件',
+      dataIndex: 'type',
+      key: 'type',
+      render: (type: string) => <Tag color={eventColors[type] ?? 'default'}>{type}</Tag>,
+    },
+    { title: '实例ID', dataIndex: 'instance_id', key: 'instance_id', ellipsis: true },
+    { title: '地址', dataIndex: 'address', key: 'address' },
+    { title: '说明', dataIndex: 'detail', key: 'detail', render: (detail?: string) => detail || '-' },
+  ];
+
+  return (
+    <Drawer title={`${service ?? ''} 实例事件`} width={960} open={!!service} onClose={onClose} destroyOnClose>
+      <Text strong>最近24小时可用性</Text>
+      <Table
+        style={{ marginTop: 8, marginBottom: 24 }}
+        size="small"
+        columns={availabilityColumns}
+        dataSource={availability}
+        rowKey="instance_id"
+        loading={availabilityLoading}
+        pagination={false}
+      />
+      <Space style={{ marginBottom: 8 }}>
+        <Text strong>历史趋势</Text>
+        <Select style={{ width: 140 }} value={historyHours} onChange={setHistoryHours} options={historyRanges} />
+      </Space>
+      <HistoryChart points={history ?? []} series={instanceSeries} format={(value) => value.toFixed(1)} />
+      <HistoryChart points={history ?? []} series={latenessSeries} format={(value) => `${value.toFixed(1)}s`} />
+      <HistoryChart points={history ?? []} series={churnSeries} height={120} format={(value) => value.toFixed(0)} />
+      <Space style={{ marginTop: 24, marginBottom: 8 }}>
+        <Text strong>事件记录</Text>
+        <Select
+          mode="multiple"
+          allowClear
+          placeholder="全部事件类型"
+          style={{ minWidth: 320 }}
+          value={types}
+          onChange={(value) => {
+            setTypes(value);
+            setPage(1);
+          }}
+          options={Object.keys(eventColors).map((type) => ({ label: type, value: type }))}
+        />
+      </Space>
+      <Table
+        size="small"
+        columns={eventColumns}
+        dataSource={events?.events}
+        rowKey="id"
+        loading={eventsLoading}
+        pagination={{
+          current: page,
+          pageSize,
+          total: events?.total ?? 0,
+          showSizeChanger: true,
+          showTotal: (total) => `共 ${total} 条`,
+          onChange: (current, size) => {
+            setPage(current);
+            setPageSize(size);
+          },
+        }}
+      />
+    </Drawer>
+  );
+};
+
+export default EventsDrawer;
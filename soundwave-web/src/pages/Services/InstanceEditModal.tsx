@@ -0,0 +1,130 @@
+import React, { useEffect, useState } from 'react';
+import { Button, Form, Input, InputNumber, Modal, Space, message } from 'antd';
+import { MinusCircleOutlined, PlusOutlined } from '@ant-design/icons';
+import axios from '../../utils/axios';
+
+export interface EditableInstance {
+  namespace: string;
+  name: string;
+  id: string;
+  metadata: Record<string, string>;
+  weight: number;
+  version: string;
+  revision: number;
+}
+
+interface InstanceEditModalProps {
+  instance?: EditableInstance;
+  onClose: () => void;
+  onUpdated: () => void;
+}
+
+interface FormValues {
+  weight: number;
+  version: string;
+  metadata: { key: string; value: string }[];
+}
+
+const InstanceEditModal: React.FC<InstanceEditModalProps> = ({ instance, onClose, onUpdated }) => {
+  const [form] = Form.useForm<FormValues>();
+  const [saving, setSaving] = useState(false);
+
+  useEffect(() => {
+    if (instance) {
+      form.setFieldsValue({
+        weight: instance.weight,
+        version: instance.version,
+        metadata: Object.entries(instance.metadata ?? {}).map(([key, value]) => ({ key, value })),
+      });
+    }
+  }, [instance, form]);
+
+  const handleSubmit = async (values: FormValues) => {
+    if (!instance) {
+      return;
+    }
+    // 只提交变化的字段，删除的元数据键提交为 null
+    const metadata: Record<string, string | null> = {};
+    const current = instance.metadata ?? {};
+    const next = Object.fromEntries((values.metadata ?? []).map((item) => [item.key, item.value ?? '']));
+    Object.keys(current).forEach((key) => {
+      if (!(key in next)) {
+        metadata[key] = null;
+      }
+    });
+    Object.entries(next).forEach(([key, value]) => {
+      if (current[key] !== value) {
+        metadata[key] = value;
+      }
+    });
+
+    try {
+      setSaving(true);
+      await axios.patch(
+        `/api/services/${encodeURIComponent(instance.name)}/${encodeURIComponent(instance.id)}`,
+        {
+          metadata: Object.keys(metadata).length > 0 ? metadata : undefined,
+          weight: values.weight !== instance.weight ? values.weight : undefined,
+          version: values.version !== instance.version ? values.version : undefined,
+          revision: instance.revision,
+        },
+        { params: { namespace: instance.namespace } },
+      );
+      message.success('实例更新成功');
+      onUpdated();
+      onClose();
+    } catch (error: any) {
+      if (error.response?.status === 409) {
+        message.error('实例已被修改，请刷新后重试');
+      } else {
+        message.error(error.response?.data?.message || '实例更新失败');
+      }
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  return (
+    <Modal
+      title={`编辑实例 ${instance?.id ?? ''}`}
+      open={!!instance}
+      onCancel={onClose}
+      onOk={() => form.submit()}
+      confirmLoading={saving}
+      destroyOnClose
+    >
+      <Form form={form} layout="vertical" onFinish={handleSubmit} preserve={false}>
+        <Form.Item name="weight" label="权重" rules={[{ required: true, message: '请输入权重' }]}>
+          <InputNumber min={0} precision={0} style={{ width: '100%' }} />
+        </Form.Item>
+        <Form.Item name="version" label="版本">
+          <Input />
+        </Form.Item>
+        <Form.Item label="元数据">
+          <Form.List name="metadata">
+            {(fields, { add, remove }) => (
+              <>
+                {fields.map(({ key, name }) => (
+                  <Space key={key} align="baseline" style={{ display: 'flex' }}>
+                    <Form.Item name={[name, 'key']} rules={[{ required: true, message: '请输入键' }]}>
+                      <Input placeholder="键" />
+                    </Form.Item>
+                    <Form.Item name={[name, 'value']}>
+                      <Input placeholder="值" />
+                    </Form.Item>
+                    <MinusCircleOutlined onClick={() => remove(name)} />
+                  </Space>
+                ))}
+                <Button type="dashed" onClick={() => add()} block icon={<PlusOutlined />}>
+                  添加元数据
+                </Button>
+              </>
+            )}
+          </Form.List>
+        </Form.Item>
+      </Form>
+    </Modal>
+  );
+};
+
+export default InstanceEditModal;
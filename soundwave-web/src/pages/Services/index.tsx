@@ -1,12 +1,17 @@
 import React, { useEffect, useState } from 'react';
-import { Table, Tag, Card, Row, Col, Statistic, Button, Space, Typography } from 'antd';
+import { Table, Tag, Card, Row, Col, Statistic, Button, Space, Typography, Select, Tooltip } from 'antd';
 import type { ColumnsType } from 'antd/es/table';
 import { ReloadOutlined, ApiOutlined, CloudServerOutlined, CheckCircleOutlined } from '@ant-design/icons';
-import axios from 'axios';
+import axios from '../../utils/axios';
+import EventsDrawer from './EventsDrawer';
+import InstanceEditModal, { type EditableInstance } from './InstanceEditModal';
+import PermissionGuard from '../../components/PermissionGuard';
+import SelfPreservationAlert, { type SelfPreservationStatus } from '../../components/SelfPreservationAlert';
 
 const { Title } = Typography;
 
 interface Service {
+  namespace: string;
   name: string;
   id: string;
   hostname: string;
@@ -16,11 +21,69 @@ interface Service {
   metadata: Record<string, string>;
   last_heartbeat: string;
   version: string;
+  weight: number;
+  revision: number;
+  region?: string;
+  zone?: string;
+  load?: {
+    in_flight: number;
+    latency_ms: number;
+    reported_at: string;
+  };
+  health?: {
+    checks: Record<string, DependencyHealth>;
+    reported_at: string;
+  };
+  ejection?: {
+    reason: string;
+    source: string;
+    ejected_at: string;
+    until: string;
+    count: number;
+  };
+}
+
+interface DependencyHealth {
+  status: string;
+  message?: string;
+  latency_ms?: number;
+}
+
+const statusColors: Record<string, string> = {
+  UP: 'success',
+  DEGRADED: 'warning',
+  STARTING: 'processing',
+};
+
+// 依赖健康情况的提示，如 db: DOWN（连接超时）
+const healthTooltip = (checks: Record<string, DependencyHealth>) => (
+  <>
+    {Object.entries(checks)
+      .sort(([a], [b]) => a.localeCompare(b))
+      .map(([name, check]) => (
+        <div key={name}>
+          {`${name}: ${check.status}`}
+          {check.latency_ms ? ` ${check.latency_ms.toFixed(1)} ms` : ''}
+          {check.message ? `（${check.message}）` : ''}
+        </div>
+      ))}
+  </>
+);
+
+interface NamespaceInfo {
+  name: string;
+  services: number;
+  instances: number;
 }
 
 const ServicesPage: React.FC = () => {
   const [services, setServices] = useState<Service[]>([]);
   const [loading, setLoading] = useState(false);
+  const [namespaces, setNamespaces] = useState<NamespaceInfo[]>([]);
+  const [namespace, setNamespace] = useState('default');
+  const [selfPreservation, setSelfPreservation] = useState<SelfPreservationStatus>();
+  const [eventsService, setEventsService] = useState<string>();
+  const [editing, setEditing] = useState<EditableInstance>();
 
   const columns: ColumnsType<Service> = [
     {
@@ -30,7 +93,9 @@ const ServicesPage: React.FC = () => {
       render: (text: string) => (
         <Space>
           <ApiOutlined style={{ color: '#1890ff' }} />
-          <span>{text}</span>
+          <Tooltip title="查看实例事件和可用性">
+            <a onClick={() => setEventsService(text)}>{text}</a>
+          </Tooltip>
         </Space>
       ),
     },
@@ -58,12 +123,43 @@ const ServicesPage: React.FC = () => {
       title: '状态',
       dataIndex: 'status',
       key: 'status',
-      render: (status: string) => (
-        <Tag color={status === 'UP' ? 'success' : 'error'}>
-          {status === 'UP' ? <CheckCircleOutlined /> : null} {status}
-        </Tag>
+      render: (status: string, record: Service) => (
+        <Space size={4}>
+          {record.health ? (
+            <Tooltip title={healthTooltip(record.health.checks)}>
+              <Tag color={statusColors[status] ?? 'error'}>
+                {status === 'UP' ? <CheckCircleOutlined /> : null} {status}
+              </Tag>
+            </Tooltip>
+          ) : (
+            <Tag color={statusColors[status] ?? 'error'}>
+              {status === 'UP' ? <CheckCircleOutlined /> : null} {status}
+            </Tag>
+          )}
+          {record.ejection && (
+            <Tooltip title={`${record.ejection.reason}，${new Date(record.ejection.until).toLocaleString()} 恢复`}>
+              <Tag color="warning">已摘除</Tag>
+            </Tooltip>
+          )}
+        </Space>
       ),
     },
+    {
+      title: '地域/可用区',
+      key: 'locality',
+      render: (_: unknown, record: Service) =>
+        record.region || record.zone ? (
+          <Tag color="cyan">{[record.region, record.zone].filter(Boolean).join(' / ')}</Tag>
+        ) : '-',
+    },
+    {
+      title: '负载',
+      key: 'load',
+      render: (_: unknown, record: Service) =>
+        record.load ? (
+          <Tag color="orange">{`${record.load.in_flight} 并发 / ${record.load.latency_ms.toFixed(1)} ms`}</Tag>
+        ) : '-',
+    },
     {
       title: '版本',
       dataIndex: 'version',
@@ -78,14 +174,29 @@ const ServicesPage: React.FC = () => {
       key: 'last_heartbeat',
       render: (time: string) => new Date(time).toLocaleString(),
     },
+    {
+      title: '操作',
+      key: 'action',
+      render: (_: unknown, record: Service) => (
+        <PermissionGuard permission="manage_system">
+          <a onClick={() => setEditing(record)}>编辑</a>
+        </PermissionGuard>
+      ),
+    },
   ];
 
   const fetchServices = async () => {
     try {
       setLoading(true);
-      const response = await axios.get<{ services: Record<string, Service[]> }>('http://localhost:7777/services');
+      const response = await axios.get<{
+        services: Record<string, Service[]>;
+        self_preservation: SelfPreservationStatus;
+      }>('/api/services', {
+        params: { namespace },
+      });
       const servicesList = Object.values(response.data.services).flat();
       setServices(servicesList);
+      setSelfPreservation(response.data.self_preservation);
     } catch (error) {
       console.error('获取服务列表失败:', error);
     } finally {
@@ -93,16 +204,29 @@ const ServicesPage: React.FC = () => {
     }
   };
 
+  const fetchNamespaces = async () => {
+    try {
+      const response = await axios.get<{ namespaces: NamespaceInfo[] }>('/api/namespaces');
+      setNamespaces(response.data.namespaces ?? []);
+    } catch (error) {
+      console.error('获取命名空间列表失败:', error);
+    }
+  };
+
+  useEffect(() => {
+    fetchNamespaces();
+  }, []);
+
   useEffect(() => {
     fetchServices();
     const interval = setInterval(fetchServices, 10000);
     return () => clearInterval(interval);
-  }, []);
+  }, [namespace]);
 
   const getStatistics = () => {
     const totalServices = new Set(services.map(s => s.name)).size;
     const totalInstances = services.length;
-    const healthyInstances = services.filter(s => s.status === 'UP').length;
+    const healthyInstances = services.filter(s => s.status === 'UP' || s.status === 'DEGRADED').length;
     
     return { totalServices, totalInstances, healthyInstances };
   };
@@ -111,6 +235,7 @@ const ServicesPage: React.FC = () => {
 
   return (
     <div>
+      <SelfPreservationAlert status={selfPreservation} />
       <Row gutter={[16, 16]}>
         <Col xs={24} sm={8}>
           <Card bordered={false} className="overview-card">
@@ -153,14 +278,22 @@ const ServicesPage: React.FC = () => {
           </Space>
         }
         extra={
-          <Button
-            type="primary"
-            icon={<ReloadOutlined />}
-            onClick={fetchServices}
-            loading={loading}
-          >
-            刷新
-          </Button>
+          <Space>
+            <Select
+              style={{ width: 200 }}
+              value={namespace}
+              onChange={setNamespace}
+              options={namespaces.map((ns) => ({ label: ns.name, value: ns.name }))}
+            />
+            <Button
+              type="primary"
+              icon={<ReloadOutlined />}
+              onClick={fetchServices}
+              loading={loading}
+            >
+              刷新
+            </Button>
+          </Space>
         }
       >
         <Table
@@ -175,6 +308,16 @@ const ServicesPage: React.FC = () => {
           }}
         />
       </Card>
+      <EventsDrawer
+        namespace={namespace}
+        service={eventsService}
+        onClose={() => setEventsService(undefined)}
+      />
+      <InstanceEditModal
+        instance={editing}
+        onClose={() => setEditing(undefined)}
+        onUpdated={fetchServices}
+      />
     </div>
   );
 };
@@ -1,28 +1,148 @@
-import React from 'react';
-import { Card, Table, Typography } from 'antd';
+import React, { useState } from 'react';
+import { Button, Card, Progress, Select, Space, Table, Typography, message } from 'antd';
+import { DownloadOutlined } from '@ant-design/icons';
 import { useQuery } from '@tanstack/react-query';
 import axios from '../utils/axios';
+import SelfPreservationAlert, { type SelfPreservationStatus } from '../components/SelfPreservationAlert';
 import type { ColumnsType } from 'antd/es/table';
 
 const { Title } = Typography;
 
 interface ServiceStats {
   name: string;
-  instanceCount: number;
-  averageResponseTime: number;
-  successRate: number;
-  lastUpdated: string;
+  total_instances: number;
+  healthy_instances: number;
+  unhealthy_instances: number;
+  ejected_instances: number;
+  average_uptime: number; // 纳秒
+  last_update_time: string;
 }
 
+interface SLASummary {
+  service: string;
+  availability: number;
+  downtime: number; // 纳秒
+  incidents: number;
+  instances: number;
+}
+
+const slaWindows = [
+  { label: '最近一天', value: 'day' },
+  { label: '最近一周', value: 'week' },
+  { label: '最近一月', value: 'month' },
+];
+
+interface NamespaceInfo {
+  name: string;
+  services: number;
+  instances: number;
+}
+
+const formatUptime = (nanoseconds: number) => {
+  const minutes = Math.floor(nanoseconds / 1e9 / 60);
+  if (minutes < 60) {
+    return `${minutes} 分钟`;
+  }
+  const hours = Math.floor(minutes / 60);
+  if (hours < 24) {
+    return `${hours} 小时 ${minutes % 60} 分钟`;
+  }
+  return `${Math.floor(hours / 24)} 天 ${hours % 24} 小时`;
+};
+
 const Stats: React.FC = () => {
-  const { data: stats, isLoading } = useQuery<ServiceStats[]>({
-    queryKey: ['serviceStats'],
+  const [namespace, setNamespace] = useState('default');
+  const [slaWindow, setSlaWindow] = useState('day');
+
+  const { data: namespaces } = useQuery<NamespaceInfo[]>({
+    queryKey: ['namespaces'],
+    queryFn: async () => {
+      const response = await axios.get<{ namespaces: NamespaceInfo[] }>('/api/namespaces');
+      return response.data.namespaces ?? [];
+    },
+  });
+
+  const { data, isLoading } = useQuery({
+    queryKey: ['serviceStats', namespace],
+    queryFn: async () => {
+      const response = await axios.get<{
+        stats: ServiceStats[];
+        self_preservation: SelfPreservationStatus;
+      }>('/api/stats', {
+        params: { namespace },
+      });
+      return response.data;
+    },
+  });
+  const stats = data?.stats ?? [];
+
+  const { data: sla, isLoading: slaLoading } = useQuery<SLASummary[]>({
+    queryKey: ['slaSummary', namespace, slaWindow],
     queryFn: async () => {
-      const response = await axios.get<{ stats: ServiceStats[] }>('/api/services/stats');
-      return response.data.stats ?? [];
+      const response = await axios.get<{ services: SLASummary[] }>('/api/stats/sla', {
+        params: { namespace, window: slaWindow },
+      });
+      return response.data.services ?? [];
     },
   });
 
+  const exportSLA = async () => {
+    try {
+      const response = await axios.get<Blob>('/api/stats/sla', {
+        params: { namespace, window: slaWindow, format: 'csv' },
+        responseType: 'blob',
+      });
+      const url = URL.createObjectURL(response.data);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = `sla-${namespace}-${slaWindow}.csv`;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (error) {
+      message.error('导出可用性报告失败');
+    }
+  };
+
+  const slaColumns: ColumnsType<SLASummary> = [
+    {
+      title: '服务名称',
+      dataIndex: 'service',
+      key: 'service',
+    },
+    {
+      title: '可用性',
+      dataIndex: 'availability',
+      key: 'availability',
+      width: 240,
+      render: (value: number) => (
+        <Progress
+          percent={Number((value * 100).toFixed(3))}
+          size="small"
+          status={value < 0.999 ? 'exception' : 'success'}
+        />
+      ),
+      sorter: (a: SLASummary, b: SLASummary) => a.availability - b.availability,
+    },
+    {
+      title: '不可用时长',
+      dataIndex: 'downtime',
+      key: 'downtime',
+      render: (downtime: number) => formatUptime(downtime),
+      sorter: (a: SLASummary, b: SLASummary) => a.downtime - b.downtime,
+    },
+    {
+      title: '故障次数',
+      dataIndex: 'incidents',
+      key: 'incidents',
+      sorter: (a: SLASummary, b: SLASummary) => a.incidents - b.incidents,
+    },
+    {
+      title: '实例数',
+      dataIndex: 'instances',
+      key: 'instances',
+    },
+  ];
+
   const columns: ColumnsType<ServiceStats> = [
     {
       title: '服务名称',
@@ -31,27 +151,39 @@ const Stats: React.FC = () => {
     },
     {
       title: '实例数量',
-      dataIndex: 'instanceCount',
-      key: 'instanceCount',
-      sorter: (a: ServiceStats, b: ServiceStats) => a.instanceCount - b.instanceCount,
+      dataIndex: 'total_instances',
+      key: 'total_instances',
+      sorter: (a: ServiceStats, b: ServiceStats) => a.total_instances - b.total_instances,
     },
     {
-      title: '平均响应时间(ms)',
-      dataIndex: 'averageResponseTime',
-      key: 'averageResponseTime',
-      sorter: (a: ServiceStats, b: ServiceStats) => a.averageResponseTime - b.averageResponseTime,
+      title: '健康实例',
+      dataIndex: 'healthy_instances',
+      key: 'healthy_instances',
+      sorter: (a: ServiceStats, b: ServiceStats) => a.healthy_instances - b.healthy_instances,
     },
     {
-      title: '成功率',
-      dataIndex: 'successRate',
-      key: 'successRate',
-      render: (rate: number) => `${(rate * 100).toFixed(2)}%`,
-      sorter: (a: ServiceStats, b: ServiceStats) => a.successRate - b.successRate,
+      title: '异常实例',
+      dataIndex: 'unhealthy_instances',
+      key: 'unhealthy_instances',
+      sorter: (a: ServiceStats, b: ServiceStats) => a.unhealthy_instances - b.unhealthy_instances,
+    },
+    {
+      title: '被摘除实例',
+      dataIndex: 'ejected_instances',
+      key: 'ejected_instances',
+      sorter: (a: ServiceStats, b: ServiceStats) => a.ejected_instances - b.ejected_instances,
+    },
+    {
+      title: '平均运行时长',
+      dataIndex: 'average_uptime',
+      key: 'average_uptime',
+      render: (uptime: number) => formatUptime(uptime),
+      sorter: (a: ServiceStats, b: ServiceStats) => a.average_uptime - b.average_uptime,
     },
     {
       title: '最后更新时间',
-      dataIndex: 'lastUpdated',
-      key: 'lastUpdated',
+      dataIndex: 'last_update_time',
+      key: 'last_update_time',
       render: (time: string) => new Date(time).toLocaleString(),
     },
   ];
@@ -59,7 +191,17 @@ const Stats: React.FC = () => {
   return (
     <div>
       <Title level={2}>服务统计</Title>
-      <Card>
+      <SelfPreservationAlert status={data?.self_preservation} />
+      <Card
+        extra={
+          <Select
+            style={{ width: 200 }}
+            value={namespace}
+            onChange={setNamespace}
+            options={(namespaces ?? []).map((ns) => ({ label: ns.name, value: ns.name }))}
+          />
+        }
+      >
         <Table<ServiceStats>
           columns={columns}
           dataSource={stats ?? []}
@@ -68,8 +210,28 @@ const Stats: React.FC = () => {
           pagination={false}
         />
       </Card>
+      <Card
+        title="可用性报告"
+        style={{ marginTop: 16 }}
+        extra={
+          <Space>
+            <Select style={{ width: 120 }} value={slaWindow} onChange={setSlaWindow} options={slaWindows} />
+            <Button icon={<DownloadOutlined />} onClick={exportSLA}>
+              导出CSV
+            </Button>
+          </Space>
+        }
+      >
+        <Table<SLASummary>
+          columns={slaColumns}
+          dataSource={sla ?? []}
+          loading={slaLoading}
+          rowKey="service"
+          pagination={false}
+        />
+      </Card>
     </div>
   );
 };
 
-export default Stats; 
\ No newline at end of file
+export default Stats;
@@ -6,7 +6,9 @@ interface User {
   id: string;
   username: string;
   role: string;
+  namespaces?: string[];
   permissions: string[];
+  provider?: string;
 }
 
 interface UserState {
@@ -14,32 +16,87 @@ interface UserState {
   token: string | null;
   setUser: (user: User | null) => void;
   setToken: (token: string | null) => void;
-  login: (username: string, password: string) => Promise<void>;
+  mfaToken: string | null;
+  login: (username: string, password: string, provider?: string) => Promise<void>;
+  completeRedirectLogin: (params: URLSearchParams) => void;
+  verifyMFA: (code: string) => Promise<void>;
   logout: () => void;
 }
 
-export const useUserStore = create<UserState>((set) => ({
+const completeLogin = (
+  set: (state: Partial<UserState>) => void,
+  data: { token: string; user: User; must_change_password?: boolean; mfa_setup_required?: boolean },
+) => {
+  const { token, user, must_change_password, mfa_setup_required } = data;
+
+  localStorage.setItem('token', token);
+  localStorage.setItem('user', JSON.stringify(user));
+
+  set({ user, token, mfaToken: null });
+  message.success('登录成功');
+  if (must_change_password) {
+    message.warning('密码已过期或不符合安全策略，请先修改密码');
+  }
+  if (mfa_setup_required) {
+    message.warning('当前角色要求启用双因素认证，请先完成绑定');
+  }
+};
+
+export const useUserStore = create<UserState>((set, get) => ({
   user: null,
   token: localStorage.getItem('token'),
+  mfaToken: null,
   
   setUser: (user) => set({ user }),
   setToken: (token) => set({ token }),
   
-  login: async (username: string, password: string) => {
+  login: async (username: string, password: string, provider?: string) => {
     try {
-      const response = await axios.post('/auth/login', { username, password });
-      const { token, user } = response.data;
-      
-      localStorage.setItem('token', token);
-      localStorage.setItem('user', JSON.stringify(user));
-      
-      set({ user, token });
-      message.success('登录成功');
+      const response = await axios.post('/auth/login', { username, password, provider });
+      // 启用 MFA 的账户需要输入验证码完成第二步登录
+      if (response.data.mfa_required) {
+        set({ mfaToken: response.data.mfa_token });
+        return;
+      }
+      completeLogin(set, response.data);
     } catch (error) {
       message.error('登录失败');
       throw error;
     }
   },
+
+  // OIDC 登录回调后，登录结果通过 URL fragment 传回
+  completeRedirectLogin: (params: URLSearchParams) => {
+    const error = params.get('error');
+    if (error) {
+      message.error(error);
+      return;
+    }
+    const mfaToken = params.get('mfa_token');
+    if (mfaToken) {
+      set({ mfaToken });
+      return;
+    }
+    const token = params.get('token');
+    const user = params.get('user');
+    if (token && user) {
+      completeLogin(set, {
+        token,
+        user: JSON.parse(user),
+        mfa_setup_required: params.get('mfa_setup_required') === 'true',
+      });
+    }
+  },
+
+  verifyMFA: async (code: string) => {
+    try {
+      const response = await axios.post('/auth/login/mfa', { mfa_token: get().mfaToken, code });
+      completeLogin(set, response.data);
+    } catch (error) {
+      message.error('验证码错误');
+      throw error;
+    }
+  },
   
   logout: () => {
     localStorage.removeItem('token');
@@ -32,10 +32,15 @@ axios.interceptors.response.use(
           message.error('登录已过期，请重新登录');
           break;
         case 403:
-          message.error('没有权限访问');
+          message.error(error.response.data?.error || '没有权限访问');
           break;
         case 500:
-          message.error('服务器错误');
+          // 附带请求ID，便于在服务端日志中定位
+          message.error(
+            error.response.data?.request_id
+              ? `服务器错误（请求ID: ${error.response.data.request_id}）`
+              : '服务器错误'
+          );
           break;
         default:
           message.error(error.response.data?.error || '请求失败');